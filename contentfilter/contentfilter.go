@@ -0,0 +1,298 @@
+// Package contentfilter screens what members write before it is published.
+// Many members are children, so besides the organization's word and pattern
+// lists it catches contact details and links to unknown sites.
+package contentfilter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rule kinds
+const (
+	KindWord  = "word"
+	KindRegex = "regex"
+)
+
+// Rule actions. Blocked content is rejected; flagged content is held for a
+// moderator to approve.
+const (
+	ActionBlock = "block"
+	ActionFlag  = "flag"
+)
+
+// Codes tell clients why content was blocked or flagged
+const (
+	CodeBlockedWord  = "blocked_word"
+	CodeEmailAddress = "email_address"
+	CodePhoneNumber  = "phone_number"
+	CodeFlaggedWord  = "flagged_word"
+	CodeLink         = "link"
+)
+
+// Rule is a word or regular expression to block or flag
+type Rule struct {
+	Kind    string
+	Pattern string
+	Action  string
+}
+
+// Compile checks the rule and returns the expression it matches with. Words
+// match whole words regardless of case; expressions are case insensitive.
+func (r Rule) Compile() (*regexp.Regexp, error) {
+	switch r.Kind {
+	case KindWord:
+		word := strings.TrimSpace(r.Pattern)
+		if word == "" {
+			return nil, fmt.Errorf("contentfilter: empty word")
+		}
+		return regexp.MustCompile(`(?i)(?:^|[^\pL\pN_])(` + regexp.QuoteMeta(word) + `)(?:$|[^\pL\pN_])`), nil
+	case KindRegex:
+		re, err := regexp.Compile(`(?i)` + r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("contentfilter: invalid pattern: %w", err)
+		}
+		return re, nil
+	default:
+		return nil, fmt.Errorf("contentfilter: unknown rule kind %q", r.Kind)
+	}
+}
+
+// Match is a reason content was blocked or flagged
+type Match struct {
+	Code string `json:"code"`
+	Text string `json:"match"`
+}
+
+// Result is the outcome of checking a text. Block is set when the content
+// must be rejected; otherwise Flags lists why it should be held, if at all.
+type Result struct {
+	Block *Match
+	Flags []Match
+}
+
+// Filter is an organization's rules with its allowed link domains
+type Filter struct {
+	rules          []compiledRule
+	allowedDomains []string
+}
+
+type compiledRule struct {
+	re     *regexp.Regexp
+	action string
+}
+
+// New compiles the rules. Rules that don't compile are skipped, as they are
+// validated when they are saved.
+func New(rules []Rule, allowedDomains []string) *Filter {
+	f := &Filter{}
+	for _, rule := range rules {
+		re, err := rule.Compile()
+		if err != nil {
+			continue
+		}
+		f.rules = append(f.rules, compiledRule{re: re, action: rule.Action})
+	}
+	for _, domain := range allowedDomains {
+		if domain = NormalizeDomain(domain); domain != "" {
+			f.allowedDomains = append(f.allowedDomains, domain)
+		}
+	}
+	return f
+}
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}`)
+	// phonePattern finds runs of digits with the separators people type in
+	// phone numbers, dates and times; findPhoneNumber decides whether a run
+	// holds a phone number
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d \t().:/-]{5,}\d`)
+	// numberPattern splits a run into numbers, dates and times
+	numberPattern    = regexp.MustCompile(`\d+(?:[./:-]\d+)*`)
+	timePattern      = regexp.MustCompile(`^(\d{1,2})[.:](\d{2})$`)
+	thousandsPattern = regexp.MustCompile(`^\d{1,3}(?:[ .]\d{3})+$`)
+	// linkPattern finds URLs with a scheme or www., and bare domains with a
+	// common top-level domain
+	linkPattern = regexp.MustCompile(`(?i)\b(?:https?://[^\s<>"]+|www\.[^\s<>"]+|[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)*\.(?:com|net|org|info|biz|io|co|me|tv|gg|ly|app|dev|xyz|site|online|link|ee|eu|fi|lv|lt|ru|uk|de|se|no|us)\b(?:/[^\s<>"]*)?)`)
+)
+
+// Check runs the filter over the texts
+func (f *Filter) Check(texts ...string) Result {
+	var result Result
+
+	for _, text := range texts {
+		for _, rule := range f.rules {
+			match := rule.re.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			found := match[0]
+			if len(match) > 1 && match[1] != "" {
+				found = match[1]
+			}
+			if rule.action == ActionBlock {
+				return Result{Block: &Match{Code: CodeBlockedWord, Text: found}}
+			}
+			result.Flags = append(result.Flags, Match{Code: CodeFlaggedWord, Text: found})
+		}
+
+		if email := emailPattern.FindString(text); email != "" {
+			return Result{Block: &Match{Code: CodeEmailAddress, Text: email}}
+		}
+		for _, candidate := range phonePattern.FindAllString(text, -1) {
+			if phone := findPhoneNumber(candidate); phone != "" {
+				return Result{Block: &Match{Code: CodePhoneNumber, Text: phone}}
+			}
+		}
+
+		for _, link := range linkPattern.FindAllString(text, -1) {
+			link = strings.TrimRight(link, ".,;:!?)")
+			if !f.isAllowedLink(link) {
+				result.Flags = append(result.Flags, Match{Code: CodeLink, Text: link})
+			}
+		}
+	}
+
+	return result
+}
+
+// findPhoneNumber returns the phone number in a run of digits, if any.
+// Dates, times and ranges of them split the run, and amounts written in
+// thousands aren't phone numbers.
+func findPhoneNumber(candidate string) string {
+	candidate = strings.TrimSpace(candidate)
+	if thousandsPattern.MatchString(candidate) {
+		return ""
+	}
+
+	start, end := -1, -1
+	for _, loc := range numberPattern.FindAllStringIndex(candidate, -1) {
+		if isSchedule(candidate[loc[0]:loc[1]]) {
+			if phone := phoneNumber(candidate, start, end); phone != "" {
+				return phone
+			}
+			start = -1
+			continue
+		}
+		if start < 0 {
+			start = loc[0]
+		}
+		end = loc[1]
+	}
+	return phoneNumber(candidate, start, end)
+}
+
+// phoneNumber returns candidate[start:end] when it is a phone number: 7 to
+// 15 digits that start with + or 00 or are grouped like a local number
+func phoneNumber(candidate string, start, end int) string {
+	if start < 0 {
+		return ""
+	}
+	for start > 0 && strings.ContainsRune("+(", rune(candidate[start-1])) {
+		start--
+	}
+	run := candidate[start:end]
+
+	digits := 0
+	for _, r := range run {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if digits < 7 || digits > 15 {
+		return ""
+	}
+	if strings.HasPrefix(run, "+") || strings.HasPrefix(run, "00") || isPhoneGrouping(run) {
+		return run
+	}
+	return ""
+}
+
+// isPhoneGrouping tells whether digits are grouped like a local phone
+// number: in one block, or in blocks of at least two digits after the first
+// separated by spaces, dots, dashes or parentheses
+func isPhoneGrouping(run string) bool {
+	if strings.ContainsAny(run, ":/") {
+		return false
+	}
+	groups := strings.FieldsFunc(run, func(r rune) bool { return !unicode.IsDigit(r) })
+	for _, group := range groups[1:] {
+		if len(group) < 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// isSchedule tells dates, times and ranges of them, like 2025-03-15, 18:00,
+// 10.00-12.00 or 15.03-20.03.2025, from other numbers
+func isSchedule(number string) bool {
+	if isDate(number) {
+		return true
+	}
+	for _, part := range strings.Split(number, "-") {
+		if !isDate(part) && !isTime(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDate tells dates written day.month.year or year-month-day, with the same
+// separator between the parts
+func isDate(number string) bool {
+	for _, sep := range []string{".", "/", "-"} {
+		parts := strings.Split(number, sep)
+		if len(parts) != 3 {
+			continue
+		}
+		day, month, year := parts[0], parts[1], parts[2]
+		if len(day) == 4 {
+			day, year = year, day
+		}
+		if (len(year) == 2 || len(year) == 4) && len(day) <= 2 && len(month) <= 2 &&
+			inRange(day, 1, 31) && inRange(month, 1, 12) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTime tells times and day.month dates, which share their shape
+func isTime(number string) bool {
+	match := timePattern.FindStringSubmatch(number)
+	return match != nil && inRange(match[1], 0, 31) && inRange(match[2], 0, 59)
+}
+
+func inRange(number string, min, max int) bool {
+	n, err := strconv.Atoi(number)
+	return err == nil && n >= min && n <= max
+}
+
+func (f *Filter) isAllowedLink(link string) bool {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := NormalizeDomain(u.Hostname())
+	for _, domain := range f.allowedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDomain lowercases a domain and drops a leading www.
+func NormalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimSuffix(domain, ".")
+	return strings.TrimPrefix(domain, "www.")
+}
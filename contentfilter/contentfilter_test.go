@@ -0,0 +1,72 @@
+package contentfilter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	filter := New([]Rule{
+		{Kind: KindWord, Pattern: "darn", Action: ActionBlock},
+		{Kind: KindWord, Pattern: "meet up", Action: ActionFlag},
+		{Kind: KindRegex, Pattern: `snap(?:chat)?`, Action: ActionFlag},
+	}, []string{"unicorn.example"})
+
+	tests := []struct {
+		name  string
+		text  string
+		block *Match
+		flags []Match
+	}{
+		{name: "plain text", text: "See you at practice!"},
+		{name: "blocked word", text: "Darn it", block: &Match{Code: CodeBlockedWord, Text: "Darn"}},
+		{name: "blocked word inside another word", text: "darning socks"},
+		{name: "flagged word", text: "Let's meet up later", flags: []Match{{Code: CodeFlaggedWord, Text: "meet up"}}},
+		{name: "flagged pattern", text: "add me on snapchat", flags: []Match{{Code: CodeFlaggedWord, Text: "snapchat"}}},
+		{name: "email address", text: "write to kid@mail.example.com", block: &Match{Code: CodeEmailAddress, Text: "kid@mail.example.com"}},
+
+		{name: "international phone", text: "call +372 5123 4567", block: &Match{Code: CodePhoneNumber, Text: "+372 5123 4567"}},
+		{name: "international phone with 00", text: "call 00372 51234567", block: &Match{Code: CodePhoneNumber, Text: "00372 51234567"}},
+		{name: "phone with area code", text: "call (555) 123-4567", block: &Match{Code: CodePhoneNumber, Text: "(555) 123-4567"}},
+		{name: "dashed phone", text: "call 555-123-4567 now", block: &Match{Code: CodePhoneNumber, Text: "555-123-4567"}},
+		{name: "dotted phone", text: "06.12.34.56.78", block: &Match{Code: CodePhoneNumber, Text: "06.12.34.56.78"}},
+		{name: "phone with trunk prefix", text: "8 912 345-67-89", block: &Match{Code: CodePhoneNumber, Text: "8 912 345-67-89"}},
+		{name: "phone in one block", text: "my number is 51234567", block: &Match{Code: CodePhoneNumber, Text: "51234567"}},
+		{name: "phone after a date", text: "2025-03-15 call 5123 4567", block: &Match{Code: CodePhoneNumber, Text: "5123 4567"}},
+
+		{name: "time range", text: "Practice 10.00-12.00 on Saturday"},
+		{name: "time range with colons", text: "Practice 10:00 - 12:00"},
+		{name: "date and time", text: "Starts 2025-03-15 18:00"},
+		{name: "date range", text: "Camp 15.03-20.03.2025"},
+		{name: "dates with slashes", text: "From 15/03/2025 to 20/03/2025"},
+		{name: "date", text: "Due 15.03.2025"},
+		{name: "thousands", text: "We raised 1 000 000 coins"},
+		{name: "short number", text: "Room 123 45"},
+		{name: "single digits", text: "1 2 3 4 5 6 7 8"},
+
+		{name: "allowed link", text: "see https://unicorn.example/events"},
+		{name: "allowed subdomain", text: "see www.app.unicorn.example"},
+		{name: "unknown link", text: "see example.com/page.", flags: []Match{{Code: CodeLink, Text: "example.com/page"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := filter.Check(tt.text)
+			if !reflect.DeepEqual(result.Block, tt.block) {
+				t.Errorf("Check(%q).Block = %+v, want %+v", tt.text, result.Block, tt.block)
+			}
+			if !reflect.DeepEqual(result.Flags, tt.flags) {
+				t.Errorf("Check(%q).Flags = %+v, want %+v", tt.text, result.Flags, tt.flags)
+			}
+		})
+	}
+}
+
+func TestCheckMultipleTexts(t *testing.T) {
+	filter := New([]Rule{{Kind: KindWord, Pattern: "darn", Action: ActionBlock}}, nil)
+
+	result := filter.Check("A fine title", "darn content")
+	if result.Block == nil || result.Block.Code != CodeBlockedWord {
+		t.Errorf("Check blocked %+v, want %s", result.Block, CodeBlockedWord)
+	}
+}
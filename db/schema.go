@@ -274,6 +274,598 @@ CREATE TABLE IF NOT EXISTS chatboard_tests (
     FOREIGN KEY (test_id) REFERENCES tests(id) ON DELETE CASCADE,
     UNIQUE(chatboard_id, test_id)
 );
+
+-- Link squads to a country and allow archiving
+ALTER TABLE squads ADD COLUMN IF NOT EXISTS country_id INTEGER REFERENCES countries(id) ON DELETE SET NULL;
+ALTER TABLE squads ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+ALTER TABLE squads ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Squad membership workflow: Pending -> Approved / Rejected / Removed
+ALTER TABLE user_squads ADD COLUMN IF NOT EXISTS reason TEXT;
+ALTER TABLE user_squads ADD COLUMN IF NOT EXISTS decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE user_squads ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP;
+
+-- Normalize free-text statuses written before the workflow existed
+UPDATE user_squads SET status = 'Approved' WHERE LOWER(status) IN ('approved', 'active');
+UPDATE user_squads SET status = 'Rejected' WHERE LOWER(status) = 'rejected';
+UPDATE user_squads SET status = 'Removed' WHERE LOWER(status) = 'removed';
+UPDATE user_squads SET status = 'Pending' WHERE status NOT IN ('Pending', 'Approved', 'Rejected', 'Removed');
+
+-- The squad role an applicant asked for, granted when they are approved
+ALTER TABLE user_squads ADD COLUMN IF NOT EXISTS requested_role_id INTEGER REFERENCES roles(id) ON DELETE SET NULL;
+
+DO $$
+BEGIN
+    ALTER TABLE user_squads ADD CONSTRAINT user_squads_status_check
+        CHECK (status IN ('Pending', 'Approved', 'Rejected', 'Removed'));
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+-- Create user_squad_status_history table
+CREATE TABLE IF NOT EXISTS user_squad_status_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    squad_id INTEGER NOT NULL,
+    from_status VARCHAR(50) NOT NULL DEFAULT '',
+    to_status VARCHAR(50) NOT NULL,
+    reason TEXT,
+    changed_by INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (squad_id) REFERENCES squads(id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Create notifications table
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    data JSONB,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+
+-- Squad roles used to be written when applying, before approval
+DELETE FROM user_squad_roles usr
+WHERE NOT EXISTS (
+    SELECT 1 FROM user_squads us
+    WHERE us.user_id = usr.user_id AND us.squad_id = usr.squad_id AND us.status = 'Approved'
+);
+
+-- Create organizations table (partner organizations sharing the deployment)
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO organizations (name, slug) VALUES ('Unicorn Squads', 'default') ON CONFLICT (slug) DO NOTHING;
+
+-- Create organization_members table
+CREATE TABLE IF NOT EXISTS organization_members (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(organization_id, user_id)
+);
+
+-- Super admins manage every organization
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_super_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Users that predate organizations belong to the default one
+INSERT INTO organization_members (organization_id, user_id)
+SELECT o.id, u.id
+FROM users u, organizations o
+WHERE o.slug = 'default'
+AND NOT EXISTS (SELECT 1 FROM organization_members om WHERE om.user_id = u.id);
+
+-- Scope organization-owned data. Existing rows belong to the default organization.
+ALTER TABLE squads ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE countries ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE chatboards ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE tests ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE rewards_catalog ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+
+UPDATE squads SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+UPDATE countries SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+UPDATE chatboards SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+UPDATE courses SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+UPDATE tests SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+UPDATE rewards_catalog SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+UPDATE user_roles SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+
+ALTER TABLE squads ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE countries ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE chatboards ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE courses ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE tests ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE rewards_catalog ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE user_roles ALTER COLUMN organization_id SET NOT NULL;
+
+-- Global roles are held per organization
+ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS user_roles_user_id_role_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_roles_org_unique ON user_roles (organization_id, user_id, role_id);
+
+CREATE INDEX IF NOT EXISTS idx_squads_organization ON squads(organization_id);
+CREATE INDEX IF NOT EXISTS idx_chatboards_organization ON chatboards(organization_id);
+
+-- Notifications belong to the organization they were created in
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE notifications SET organization_id = (SELECT id FROM organizations WHERE slug = 'default') WHERE organization_id IS NULL;
+ALTER TABLE notifications ALTER COLUMN organization_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_org ON notifications(user_id, organization_id, created_at DESC);
+
+-- Country ISO codes, timezone and locale
+ALTER TABLE countries ADD COLUMN IF NOT EXISTS iso_code CHAR(2);
+ALTER TABLE countries ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE countries ADD COLUMN IF NOT EXISTS locale VARCHAR(16) NOT NULL DEFAULT 'en';
+
+UPDATE countries
+SET iso_code = 'EE', timezone = 'Europe/Tallinn', locale = 'et'
+WHERE LOWER(TRIM(name)) = 'estonia' AND iso_code IS NULL;
+
+-- Merge case-insensitive duplicate countries within an organization into the
+-- oldest row so the unique indexes below can be created
+DO $$
+BEGIN
+    CREATE TEMP TABLE country_merge AS
+        SELECT id, keep_id FROM (
+            SELECT id, MIN(id) OVER (PARTITION BY organization_id, LOWER(TRIM(name))) AS keep_id
+            FROM countries
+        ) grouped
+        WHERE id <> keep_id;
+
+    DELETE FROM user_countries uc USING country_merge m
+    WHERE uc.country_id = m.id
+    AND EXISTS (SELECT 1 FROM user_countries x WHERE x.user_id = uc.user_id AND x.country_id = m.keep_id);
+    UPDATE user_countries uc SET country_id = m.keep_id FROM country_merge m WHERE uc.country_id = m.id;
+
+    DELETE FROM chatboard_countries cc USING country_merge m
+    WHERE cc.country_id = m.id
+    AND EXISTS (SELECT 1 FROM chatboard_countries x WHERE x.chatboard_id = cc.chatboard_id AND x.country_id = m.keep_id);
+    UPDATE chatboard_countries cc SET country_id = m.keep_id FROM country_merge m WHERE cc.country_id = m.id;
+
+    UPDATE squads s SET country_id = m.keep_id FROM country_merge m WHERE s.country_id = m.id;
+
+    DELETE FROM countries c USING country_merge m WHERE c.id = m.id;
+    DROP TABLE country_merge;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_countries_name_unique ON countries (organization_id, LOWER(TRIM(name)));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_countries_iso_code_unique ON countries (organization_id, iso_code);
+
+-- Create country_admins table (country-scoped admin role)
+CREATE TABLE IF NOT EXISTS country_admins (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    country_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (country_id) REFERENCES countries(id) ON DELETE CASCADE,
+    UNIQUE(user_id, country_id)
+);
+
+-- Courses can belong to a country so country admins can manage them
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS country_id INTEGER REFERENCES countries(id) ON DELETE SET NULL;
+
+-- Chatboards remember their creator (the board's owner) and can be archived
+ALTER TABLE chatboards ADD COLUMN IF NOT EXISTS creator_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE chatboards ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+ALTER TABLE chatboards ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+-- Chatboards can replace the default rule, access through any of their squads,
+-- roles or countries, with an access policy of nested AND/OR/NOT rules
+ALTER TABLE chatboards ADD COLUMN IF NOT EXISTS access_policy JSONB;
+
+-- Read markers: posts newer than the chatboard marker are unread, comments
+-- newer than both the chatboard and the post marker are new
+CREATE TABLE IF NOT EXISTS chatboard_reads (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chatboard_id INTEGER NOT NULL REFERENCES chatboards(id) ON DELETE CASCADE,
+    last_read_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, chatboard_id)
+);
+
+CREATE TABLE IF NOT EXISTS post_reads (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    last_read_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_chatboard_created ON posts(chatboard_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_post_created ON comments(post_id, created_at);
+
+-- Post edits keep the previous version; deleted posts are only hidden so
+-- Admins can restore them
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    edited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post ON post_revisions(post_id, created_at);
+
+-- Threaded comments: replies point at their parent, and depth is stored so
+-- nesting can be bounded. Comments are edited and removed like posts.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments(parent_comment_id);
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id SERIAL PRIMARY KEY,
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    comment TEXT NOT NULL,
+    edited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reactions on posts and comments, one row per user, target and emoji
+CREATE TABLE IF NOT EXISTS reactions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_post_unique ON reactions(post_id, user_id, emoji) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_comment_unique ON reactions(comment_id, user_id, emoji) WHERE comment_id IS NOT NULL;
+
+-- Files attached to posts. The content lives in the storage backend under
+-- storage_key.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_post ON attachments(post_id);
+
+-- Stored objects of deleted attachments and variants, whether deleted
+-- directly or along with their post, chatboard or organization, are queued
+-- here and removed from storage by a background job
+CREATE TABLE IF NOT EXISTS storage_deletions (
+    storage_key TEXT PRIMARY KEY,
+    queued_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION queue_storage_deletion() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO storage_deletions (storage_key) VALUES (OLD.storage_key)
+    ON CONFLICT DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS attachments_queue_storage_deletion ON attachments;
+CREATE TRIGGER attachments_queue_storage_deletion
+    AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_storage_deletion();
+
+-- Images are stored without metadata, with their dimensions, a blurhash
+-- placeholder and resized variants
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS width INTEGER;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS height INTEGER;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS blurhash VARCHAR(64);
+
+CREATE TABLE IF NOT EXISTS attachment_variants (
+    attachment_id INTEGER NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    variant VARCHAR(32) NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    content_type VARCHAR(100) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    PRIMARY KEY (attachment_id, variant)
+);
+
+DROP TRIGGER IF EXISTS attachment_variants_queue_storage_deletion ON attachment_variants;
+CREATE TRIGGER attachment_variants_queue_storage_deletion
+    AFTER DELETE ON attachment_variants
+    FOR EACH ROW EXECUTE FUNCTION queue_storage_deletion();
+
+-- Profile pictures: an uploaded image in storage, or one of the built-in
+-- unicorn avatars
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_image_key TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_preset VARCHAR(50);
+
+-- @username mentions in post content and comment text. Offsets are in
+-- UTF-16 code units.
+CREATE TABLE IF NOT EXISTS mentions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL,
+    length INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_post ON mentions(post_id) WHERE post_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_mentions_comment ON mentions(comment_id) WHERE comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users(LOWER(username));
+
+-- Full-text search. Postgres ships no Estonian configuration, so estonian
+-- starts as a copy of simple (lowercasing, no stemming); swap in a hunspell
+-- dictionary with ALTER TEXT SEARCH CONFIGURATION when one is installed.
+DO $$
+BEGIN
+    CREATE TEXT SEARCH CONFIGURATION estonian (COPY = simple);
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english'::regconfig, title), 'A') ||
+    setweight(to_tsvector('estonian'::regconfig, title), 'A') ||
+    setweight(to_tsvector('english'::regconfig, content), 'B') ||
+    setweight(to_tsvector('estonian'::regconfig, content), 'B')
+) STORED;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('english'::regconfig, comment) ||
+    to_tsvector('estonian'::regconfig, comment)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_comments_search ON comments USING GIN (search_vector);
+
+-- Moderation. Every moderator decision is logged in moderation_actions;
+-- reports point at the action that closed them. Suspended members can read
+-- but not post until suspended_until.
+ALTER TABLE organization_members ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('dismiss', 'hide', 'warn', 'suspend')),
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+    post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE SET NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT NOT NULL DEFAULT '',
+    suspended_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_org ON moderation_actions(organization_id, created_at);
+
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+    post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+    action_id INTEGER REFERENCES moderation_actions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_open ON reports(organization_id, target_type, post_id, comment_id, user_id) WHERE status = 'open';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_per_reporter ON reports(
+    reporter_id, target_type, COALESCE(post_id, 0), COALESCE(comment_id, 0), user_id
+) WHERE status = 'open';
+
+-- Content filter. Rules without a locale apply everywhere; others apply when
+-- the chatboard or the author belongs to a country with that locale. Content
+-- matching a flag rule or linking outside the allowed domains is held until
+-- a moderator approves it.
+CREATE TABLE IF NOT EXISTS content_filter_rules (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    locale VARCHAR(16) NOT NULL DEFAULT '',
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('word', 'regex')),
+    pattern TEXT NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('block', 'flag')),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(organization_id, locale, kind, pattern)
+);
+
+CREATE TABLE IF NOT EXISTS content_filter_domains (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    domain VARCHAR(253) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(organization_id, domain)
+);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS held_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS held_reasons JSONB;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS held_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS held_reasons JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_posts_held ON posts(chatboard_id, held_at) WHERE held_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_held ON comments(post_id, held_at) WHERE held_at IS NOT NULL;
+
+-- Approving and rejecting held content is logged with the other actions
+ALTER TABLE moderation_actions DROP CONSTRAINT IF EXISTS moderation_actions_action_check;
+ALTER TABLE moderation_actions ADD CONSTRAINT moderation_actions_action_check
+    CHECK (action IN ('dismiss', 'hide', 'warn', 'suspend', 'approve', 'reject'));
+
+-- Chatboard restrictions. Banned members lose access to the chatboard; muted
+-- members can read but not post or comment. Restrictions without expires_at
+-- last until a moderator lifts them. Slow mode makes members wait
+-- slow_mode_seconds between their posts and comments on the board.
+CREATE TABLE IF NOT EXISTS chatboard_restrictions (
+    id SERIAL PRIMARY KEY,
+    chatboard_id INTEGER NOT NULL REFERENCES chatboards(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('ban', 'mute')),
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chatboard_id, user_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chatboard_restrictions_user ON chatboard_restrictions(user_id);
+
+ALTER TABLE chatboards ADD COLUMN IF NOT EXISTS slow_mode_seconds INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_posts_chatboard_user ON posts(chatboard_id, user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_user ON comments(user_id, created_at);
+
+-- Bans and mutes are logged with the chatboard they apply to; their expiry is
+-- kept in suspended_until
+ALTER TABLE moderation_actions ADD COLUMN IF NOT EXISTS chatboard_id INTEGER REFERENCES chatboards(id) ON DELETE SET NULL;
+ALTER TABLE moderation_actions DROP CONSTRAINT IF EXISTS moderation_actions_action_check;
+ALTER TABLE moderation_actions ADD CONSTRAINT moderation_actions_action_check
+    CHECK (action IN ('dismiss', 'hide', 'warn', 'suspend', 'approve', 'reject', 'ban', 'unban', 'mute', 'unmute'));
+
+-- Pinned posts are shown in pin_order, lowest first; unpinned posts keep 0.
+-- Pins with pin_expires_at are taken off by a background job.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS pin_order INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS pinned_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS pin_expires_at TIMESTAMP;
+
+-- Posts pinned before pins were ordered keep their newest-first order
+UPDATE posts p SET pin_order = o.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY chatboard_id ORDER BY created_at DESC, id DESC) AS position
+    FROM posts WHERE pinned
+) o
+WHERE p.id = o.id AND p.pinned AND p.pin_order = 0;
+
+CREATE INDEX IF NOT EXISTS idx_posts_pinned ON posts(chatboard_id, pin_order) WHERE pinned;
+CREATE INDEX IF NOT EXISTS idx_posts_pin_expiry ON posts(pin_expires_at) WHERE pinned AND pin_expires_at IS NOT NULL;
+
+-- Announcements reach the members of their chatboards and squads, fixed in
+-- announcement_recipients when sent so leaders can see who has acknowledged
+-- them. Each targeted chatboard gets a post linked to the announcement.
+CREATE TABLE IF NOT EXISTS announcements (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS announcement_chatboards (
+    announcement_id INTEGER NOT NULL REFERENCES announcements(id) ON DELETE CASCADE,
+    chatboard_id INTEGER NOT NULL REFERENCES chatboards(id) ON DELETE CASCADE,
+    PRIMARY KEY (announcement_id, chatboard_id)
+);
+
+CREATE TABLE IF NOT EXISTS announcement_squads (
+    announcement_id INTEGER NOT NULL REFERENCES announcements(id) ON DELETE CASCADE,
+    squad_id INTEGER NOT NULL REFERENCES squads(id) ON DELETE CASCADE,
+    PRIMARY KEY (announcement_id, squad_id)
+);
+
+CREATE TABLE IF NOT EXISTS announcement_recipients (
+    announcement_id INTEGER NOT NULL REFERENCES announcements(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    acknowledged_at TIMESTAMP,
+    reminded_at TIMESTAMP,
+    PRIMARY KEY (announcement_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcement_recipients_user ON announcement_recipients(user_id, announcement_id);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS announcement_id INTEGER REFERENCES announcements(id) ON DELETE SET NULL;
+
+-- Polls. poll_ballots allows one vote per member; the options they picked are
+-- in poll_votes, or in poll_anonymous_votes for anonymous polls.
+CREATE TABLE IF NOT EXISTS polls (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL UNIQUE REFERENCES posts(id) ON DELETE CASCADE,
+    multiple_choice BOOLEAN NOT NULL DEFAULT FALSE,
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    closes_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    text VARCHAR(200) NOT NULL,
+    UNIQUE(poll_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    poll_id INTEGER NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (poll_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    option_id INTEGER NOT NULL REFERENCES poll_options(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_option ON poll_votes(option_id);
+CREATE INDEX IF NOT EXISTS idx_poll_votes_poll ON poll_votes(poll_id);
+
+-- Votes on anonymous polls have a random key and no time, so they can't be
+-- matched to ballots by order. voter_hash is a keyed hash of the poll and
+-- the voter that only the server can compute, to show voters their choice.
+CREATE TABLE IF NOT EXISTS poll_anonymous_votes (
+    id UUID PRIMARY KEY,
+    poll_id INTEGER NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    option_id INTEGER NOT NULL REFERENCES poll_options(id) ON DELETE CASCADE,
+    voter_hash VARCHAR(64)
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_anonymous_votes_option ON poll_anonymous_votes(option_id);
+CREATE INDEX IF NOT EXISTS idx_poll_anonymous_votes_voter ON poll_anonymous_votes(poll_id, voter_hash);
+
+INSERT INTO poll_anonymous_votes (id, poll_id, option_id)
+SELECT md5(random()::text || v.id::text)::uuid, v.poll_id, v.option_id
+FROM poll_votes v
+JOIN polls pl ON pl.id = v.poll_id
+WHERE pl.anonymous;
+DELETE FROM poll_votes v USING polls pl WHERE pl.id = v.poll_id AND pl.anonymous;
+
+ALTER TABLE poll_ballots DROP COLUMN IF EXISTS created_at;
+
+-- Drafts and scheduled posts are only visible to their author. Scheduled
+-- posts are published by a background job once publish_at has passed.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published'));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts(publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_posts_unpublished ON posts(user_id, created_at DESC) WHERE status <> 'published';
 `
 
 // InitSchema initializes the database schema
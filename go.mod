@@ -10,6 +10,8 @@ require (
 	golang.org/x/crypto v0.36.0
 )
 
+require golang.org/x/image v0.25.0
+
 require (
 	github.com/bytedance/sonic v1.12.6 // indirect
 	github.com/bytedance/sonic/loader v0.2.1 // indirect
@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicorn_app_backend/models"
+
+	"github.com/lib/pq"
+)
+
+// maxAccessRuleDepth limits how deeply access rules can be nested
+const maxAccessRuleDepth = 8
+
+// accessSubject holds everything that can grant a user access to a chatboard
+// in one organization: squad memberships with their status, global roles,
+// roles in squads they are approved in, and countries.
+type accessSubject struct {
+	squads    map[int]string
+	roles     map[int]bool
+	countries map[int]bool
+}
+
+func newAccessSubject() *accessSubject {
+	return &accessSubject{
+		squads:    make(map[int]string),
+		roles:     make(map[int]bool),
+		countries: make(map[int]bool),
+	}
+}
+
+// loadAccessSubject loads the user's squads, roles and countries within the
+// organization.
+func loadAccessSubject(db querier, userID, orgID int) (*accessSubject, error) {
+	subjects, err := loadAccessSubjects(db, orgID, []int{userID})
+	if err != nil {
+		return nil, err
+	}
+	return subjects[userID], nil
+}
+
+// loadAccessSubjects loads the squads, roles and countries of each of the
+// users within the organization with one query. Every user gets a subject,
+// empty when they have no memberships.
+func loadAccessSubjects(db querier, orgID int, userIDs []int) (map[int]*accessSubject, error) {
+	subjects := make(map[int]*accessSubject, len(userIDs))
+	for _, userID := range userIDs {
+		subjects[userID] = newAccessSubject()
+	}
+	if len(userIDs) == 0 {
+		return subjects, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT us.user_id, 'squad', us.squad_id, us.status
+		FROM user_squads us
+		JOIN squads s ON s.id = us.squad_id
+		WHERE us.user_id = ANY($1) AND s.organization_id = $2
+		UNION ALL
+		SELECT ur.user_id, 'role', ur.role_id, ''
+		FROM user_roles ur
+		WHERE ur.user_id = ANY($1) AND ur.organization_id = $2
+		UNION ALL
+		SELECT usr.user_id, 'role', usr.role_id, ''
+		FROM user_squad_roles usr
+		JOIN squads s ON s.id = usr.squad_id
+		JOIN user_squads us ON us.user_id = usr.user_id AND us.squad_id = usr.squad_id
+			AND us.status = 'Approved'
+		WHERE usr.user_id = ANY($1) AND s.organization_id = $2
+		UNION ALL
+		SELECT uc.user_id, 'country', uc.country_id, ''
+		FROM user_countries uc
+		JOIN countries co ON co.id = uc.country_id
+		WHERE uc.user_id = ANY($1) AND co.organization_id = $2
+	`, pq.Array(userIDs), orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, id int
+		var kind, status string
+		if err := rows.Scan(&userID, &kind, &id, &status); err != nil {
+			return nil, err
+		}
+		subject, ok := subjects[userID]
+		if !ok {
+			continue
+		}
+		switch kind {
+		case "squad":
+			subject.squads[id] = status
+		case "role":
+			subject.roles[id] = true
+		case "country":
+			subject.countries[id] = true
+		}
+	}
+
+	return subjects, rows.Err()
+}
+
+// matches evaluates an access rule against the subject
+func (s *accessSubject) matches(rule models.AccessRule) bool {
+	for _, child := range rule.All {
+		if !s.matches(child) {
+			return false
+		}
+	}
+
+	if len(rule.Any) > 0 {
+		matched := false
+		for _, child := range rule.Any {
+			if s.matches(child) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if rule.Not != nil && s.matches(*rule.Not) {
+		return false
+	}
+
+	if len(rule.SquadIDs) > 0 || rule.SquadStatus != "" {
+		if !s.inSquad(rule.SquadIDs, rule.SquadStatus) {
+			return false
+		}
+	}
+
+	if len(rule.RoleIDs) > 0 && !containsAny(s.roles, rule.RoleIDs) {
+		return false
+	}
+
+	if len(rule.CountryIDs) > 0 && !containsAny(s.countries, rule.CountryIDs) {
+		return false
+	}
+
+	return true
+}
+
+// inSquad reports whether the subject belongs to one of the squads, or to any
+// squad when none are given, with the given membership status. Without a
+// status only Approved memberships count, so pending, rejected and removed
+// members never get access through a squad by accident.
+func (s *accessSubject) inSquad(squadIDs []int, status string) bool {
+	if status == "" {
+		status = models.SquadStatusApproved
+	}
+
+	if len(squadIDs) == 0 {
+		for _, memberStatus := range s.squads {
+			if memberStatus == status {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, id := range squadIDs {
+		memberStatus, ok := s.squads[id]
+		if ok && memberStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(set map[int]bool, ids []int) bool {
+	for _, id := range ids {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// withSquadStatus returns a copy of the subject with its membership in the
+// squad set to the status
+func (s *accessSubject) withSquadStatus(squadID int, status string) *accessSubject {
+	squads := make(map[int]string, len(s.squads)+1)
+	for id, memberStatus := range s.squads {
+		squads[id] = memberStatus
+	}
+	squads[squadID] = status
+
+	return &accessSubject{squads: squads, roles: s.roles, countries: s.countries}
+}
+
+// chatboardsUnlockedByApproval returns the IDs of the chatboards the subject
+// cannot access now but could once its membership in the squad is approved.
+func chatboardsUnlockedByApproval(rules map[int]models.AccessRule, subject *accessSubject, squadID int) []int {
+	approved := subject.withSquadStatus(squadID, models.SquadStatusApproved)
+
+	unlocked := make([]int, 0)
+	for id, rule := range rules {
+		if !subject.matches(rule) && approved.matches(rule) {
+			unlocked = append(unlocked, id)
+		}
+	}
+	sort.Ints(unlocked)
+	return unlocked
+}
+
+// defaultAccessRule is the rule of chatboards without a policy: membership in
+// any of the board's squads, roles or countries grants access.
+func defaultAccessRule(squadIDs, roleIDs, countryIDs []int) models.AccessRule {
+	var rule models.AccessRule
+	if len(squadIDs) > 0 {
+		rule.Any = append(rule.Any, models.AccessRule{SquadIDs: squadIDs})
+	}
+	if len(roleIDs) > 0 {
+		rule.Any = append(rule.Any, models.AccessRule{RoleIDs: roleIDs})
+	}
+	if len(countryIDs) > 0 {
+		rule.Any = append(rule.Any, models.AccessRule{CountryIDs: countryIDs})
+	}
+	if len(rule.Any) == 0 {
+		// Boards without any access lists are not visible to anyone
+		rule.Not = &models.AccessRule{}
+	}
+	return rule
+}
+
+// loadChatboardRules returns the access rule of each chatboard of the
+// organization, or of the given chatboards only.
+func loadChatboardRules(db querier, orgID int, chatboardIDs ...int) (map[int]models.AccessRule, error) {
+	query := `
+		SELECT
+			cb.id,
+			cb.access_policy,
+			ARRAY(SELECT squad_id FROM chatboard_squads WHERE chatboard_id = cb.id),
+			ARRAY(SELECT role_id FROM chatboard_roles WHERE chatboard_id = cb.id),
+			ARRAY(SELECT country_id FROM chatboard_countries WHERE chatboard_id = cb.id)
+		FROM chatboards cb
+		WHERE cb.organization_id = $1`
+	args := []interface{}{orgID}
+	if len(chatboardIDs) > 0 {
+		query += " AND cb.id = ANY($2)"
+		args = append(args, pq.Array(chatboardIDs))
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make(map[int]models.AccessRule)
+	for rows.Next() {
+		var id int
+		var policy []byte
+		var squadIDs, roleIDs, countryIDs pq.Int64Array
+		if err := rows.Scan(&id, &policy, &squadIDs, &roleIDs, &countryIDs); err != nil {
+			return nil, err
+		}
+
+		if policy != nil {
+			var rule models.AccessRule
+			if err := json.Unmarshal(policy, &rule); err != nil {
+				return nil, fmt.Errorf("invalid access policy of chatboard %d: %w", id, err)
+			}
+			rules[id] = rule
+			continue
+		}
+
+		rules[id] = defaultAccessRule(toInts(squadIDs), toInts(roleIDs), toInts(countryIDs))
+	}
+
+	return rules, rows.Err()
+}
+
+func toInts(values pq.Int64Array) []int {
+	ints := make([]int, len(values))
+	for i, v := range values {
+		ints[i] = int(v)
+	}
+	return ints
+}
+
+// bannedChatboardIDs returns the chatboards the user is currently banned from
+func bannedChatboardIDs(db querier, userID int) (map[int]bool, error) {
+	rows, err := db.Query(`
+		SELECT chatboard_id FROM chatboard_restrictions
+		WHERE user_id = $1 AND kind = 'ban'
+		AND (expires_at IS NULL OR expires_at > NOW())
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	banned := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		banned[id] = true
+	}
+	return banned, rows.Err()
+}
+
+// chatboardBans returns, for each user, the chatboards among the given ones
+// they are currently banned from
+func chatboardBans(db querier, chatboardIDs []int) (map[int]map[int]bool, error) {
+	rows, err := db.Query(`
+		SELECT user_id, chatboard_id FROM chatboard_restrictions
+		WHERE chatboard_id = ANY($1) AND kind = 'ban'
+		AND (expires_at IS NULL OR expires_at > NOW())
+	`, pq.Array(chatboardIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := make(map[int]map[int]bool)
+	for rows.Next() {
+		var userID, chatboardID int
+		if err := rows.Scan(&userID, &chatboardID); err != nil {
+			return nil, err
+		}
+		if bans[userID] == nil {
+			bans[userID] = make(map[int]bool)
+		}
+		bans[userID][chatboardID] = true
+	}
+	return bans, rows.Err()
+}
+
+// canAccessChatboard reports whether the user may read and post on the
+// chatboard. Chatboards outside the organization are never accessible, and
+// neither are chatboards the user is banned from.
+func canAccessChatboard(db querier, userID, orgID, chatboardID int) (bool, error) {
+	rules, err := loadChatboardRules(db, orgID, chatboardID)
+	if err != nil {
+		return false, err
+	}
+	rule, ok := rules[chatboardID]
+	if !ok {
+		return false, nil
+	}
+
+	subject, err := loadAccessSubject(db, userID, orgID)
+	if err != nil {
+		return false, err
+	}
+	if !subject.matches(rule) {
+		return false, nil
+	}
+
+	banned, err := bannedChatboardIDs(db, userID)
+	if err != nil {
+		return false, err
+	}
+	return !banned[chatboardID], nil
+}
+
+// accessibleChatboardIDs returns the IDs of every chatboard of the
+// organization the user may access.
+func accessibleChatboardIDs(db querier, userID, orgID int) ([]int, error) {
+	rules, err := loadChatboardRules(db, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := loadAccessSubject(db, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	banned, err := bannedChatboardIDs(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(rules))
+	for id, rule := range rules {
+		if subject.matches(rule) && !banned[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// chatboardAudience returns the organization's members who can access any of
+// the chatboards
+func chatboardAudience(db querier, orgID int, chatboardIDs []int) ([]int, error) {
+	if len(chatboardIDs) == 0 {
+		return nil, nil
+	}
+
+	rules, err := loadChatboardRules(db, orgID, chatboardIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT user_id FROM organization_members WHERE organization_id = $1", orgID)
+	if err != nil {
+		return nil, err
+	}
+	var members []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		members = append(members, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	subjects, err := loadAccessSubjects(db, orgID, members)
+	if err != nil {
+		return nil, err
+	}
+	bans, err := chatboardBans(db, chatboardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	audience := make([]int, 0)
+	for _, userID := range members {
+		for id, rule := range rules {
+			if subjects[userID].matches(rule) && !bans[userID][id] {
+				audience = append(audience, userID)
+				break
+			}
+		}
+	}
+	return audience, nil
+}
+
+// validateAccessRule checks the structure of an access rule. It returns a
+// message for the client when the rule is invalid.
+func validateAccessRule(rule models.AccessRule, depth int) string {
+	if depth > maxAccessRuleDepth {
+		return fmt.Sprintf("Access policy cannot be nested more than %d levels deep", maxAccessRuleDepth)
+	}
+
+	if len(rule.All) == 0 && len(rule.Any) == 0 && rule.Not == nil &&
+		len(rule.SquadIDs) == 0 && rule.SquadStatus == "" &&
+		len(rule.RoleIDs) == 0 && len(rule.CountryIDs) == 0 {
+		return "Access policy contains an empty rule"
+	}
+
+	switch rule.SquadStatus {
+	case "", models.SquadStatusPending, models.SquadStatusApproved, models.SquadStatusRejected, models.SquadStatusRemoved:
+	default:
+		return fmt.Sprintf("Invalid squad status %q in access policy", rule.SquadStatus)
+	}
+
+	children := append(append([]models.AccessRule{}, rule.All...), rule.Any...)
+	if rule.Not != nil {
+		children = append(children, *rule.Not)
+	}
+	for _, child := range children {
+		if msg := validateAccessRule(child, depth+1); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+// accessRuleIDs collects every squad, role and country referenced by the rule
+// so they can be checked like the chatboard's access lists.
+func accessRuleIDs(rule models.AccessRule) models.ChatboardAccess {
+	ids := models.ChatboardAccess{
+		SquadIDs:   append([]int{}, rule.SquadIDs...),
+		RoleIDs:    append([]int{}, rule.RoleIDs...),
+		CountryIDs: append([]int{}, rule.CountryIDs...),
+	}
+
+	children := append(append([]models.AccessRule{}, rule.All...), rule.Any...)
+	if rule.Not != nil {
+		children = append(children, *rule.Not)
+	}
+	for _, child := range children {
+		childIDs := accessRuleIDs(child)
+		ids.SquadIDs = append(ids.SquadIDs, childIDs.SquadIDs...)
+		ids.RoleIDs = append(ids.RoleIDs, childIDs.RoleIDs...)
+		ids.CountryIDs = append(ids.CountryIDs, childIDs.CountryIDs...)
+	}
+
+	return ids
+}
+
+// uniqueInts returns the values without duplicates, keeping their order
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool, len(values))
+	unique := make([]int, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,670 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// announcementReminderInterval is how long recipients are left alone after a
+// reminder, so repeated clicks don't flood them
+const announcementReminderInterval = time.Hour
+
+type AnnouncementHandler struct {
+	db *sql.DB
+}
+
+func NewAnnouncementHandler(db *sql.DB) *AnnouncementHandler {
+	return &AnnouncementHandler{db: db}
+}
+
+// uniqueIDs returns the IDs sorted without duplicates
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sort.Ints(unique)
+	return unique
+}
+
+// canAnnounce reports whether the user may send an announcement to the
+// chatboards and squads: Admins and Head Unicorns anywhere, others only to
+// chatboards they moderate and squads they manage
+func canAnnounce(db *sql.DB, userID, orgID int, chatboardIDs, squadIDs []int) (bool, error) {
+	allowed, err := hasGlobalRole(db, userID, orgID, "Admin", "Head Unicorn")
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	for _, id := range chatboardIDs {
+		if allowed, err := isChatboardModerator(db, userID, orgID, id); err != nil || !allowed {
+			return false, err
+		}
+	}
+	for _, id := range squadIDs {
+		if allowed, err := canManageSquad(db, userID, orgID, id); err != nil || !allowed {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// CreateAnnouncement sends an announcement to the members of the chatboards
+// and squads. Each chatboard gets it as a post; every recipient is notified
+// and asked to acknowledge it.
+func (h *AnnouncementHandler) CreateAnnouncement(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	var req models.CreateAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Title == "" || req.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title and content cannot be empty"})
+		return
+	}
+	chatboardIDs := uniqueIDs(req.ChatboardIDs)
+	squadIDs := uniqueIDs(req.SquadIDs)
+	if len(chatboardIDs) == 0 && len(squadIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An announcement needs at least one chatboard or squad"})
+		return
+	}
+
+	var foundChatboards, foundSquads int
+	err := h.db.QueryRow(`
+        SELECT
+            (SELECT COUNT(*) FROM chatboards WHERE id = ANY($1) AND organization_id = $3 AND archived_at IS NULL),
+            (SELECT COUNT(*) FROM squads WHERE id = ANY($2) AND organization_id = $3)
+    `, pq.Array(chatboardIDs), pq.Array(squadIDs), orgID).Scan(&foundChatboards, &foundSquads)
+	if err != nil {
+		log.Printf("Error verifying announcement targets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboards and squads"})
+		return
+	}
+	if foundChatboards != len(chatboardIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chatboard not found or archived"})
+		return
+	}
+	if foundSquads != len(squadIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return
+	}
+
+	allowed, err := canAnnounce(h.db, userID, orgID, chatboardIDs, squadIDs)
+	if err != nil {
+		log.Printf("Error checking permissions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Admins, Head Unicorns and the leaders of these chatboards and squads can send this announcement"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) {
+		return
+	}
+
+	// Announcements reach their recipients right away, so content the filter
+	// would hold for a moderator is rejected instead. Squad-only
+	// announcements are checked against the organization's rules.
+	screened := chatboardIDs
+	if len(screened) == 0 {
+		screened = []int{0}
+	}
+	for _, chatboardID := range screened {
+		heldReasons, ok := screenContent(c, h.db, chatboardID, req.Title, req.Content)
+		if !ok {
+			return
+		}
+		if heldReasons.Valid {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "This contains content that needs a moderator's review, which announcements can't wait for",
+				"code":    "needs_review",
+				"reasons": json.RawMessage(heldReasons.String),
+			})
+			return
+		}
+	}
+
+	recipients, err := chatboardAudience(h.db, orgID, chatboardIDs)
+	if err != nil {
+		log.Printf("Error resolving announcement audience: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+	defer tx.Rollback()
+
+	announcement := models.Announcement{
+		AuthorID:     &userID,
+		Title:        req.Title,
+		Content:      req.Content,
+		ChatboardIDs: chatboardIDs,
+		SquadIDs:     squadIDs,
+	}
+	err = tx.QueryRow(`
+        INSERT INTO announcements (organization_id, author_id, title, content)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, (SELECT COALESCE(username, '') FROM users WHERE id = $2)
+    `, orgID, userID, req.Title, req.Content).Scan(&announcement.ID, &announcement.CreatedAt, &announcement.Author)
+	if err != nil {
+		log.Printf("Error creating announcement: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO announcement_chatboards (announcement_id, chatboard_id)
+        SELECT $1, UNNEST($2::INTEGER[])
+    `, announcement.ID, pq.Array(chatboardIDs))
+	if err == nil {
+		_, err = tx.Exec(`
+            INSERT INTO announcement_squads (announcement_id, squad_id)
+            SELECT $1, UNNEST($2::INTEGER[])
+        `, announcement.ID, pq.Array(squadIDs))
+	}
+	if err != nil {
+		log.Printf("Error saving announcement targets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+
+	// Approved squad members join the chatboards' audience; the author is
+	// never a recipient of their own announcement
+	rows, err := tx.Query(`
+        INSERT INTO announcement_recipients (announcement_id, user_id)
+        SELECT $1, user_id FROM (
+            SELECT UNNEST($2::INTEGER[]) AS user_id
+            UNION
+            SELECT us.user_id FROM user_squads us
+            WHERE us.squad_id = ANY($3) AND us.status = $4
+        ) audience
+        WHERE user_id <> $5
+        RETURNING user_id
+    `, announcement.ID, pq.Array(recipients), pq.Array(squadIDs), models.SquadStatusApproved, userID)
+	if err != nil {
+		log.Printf("Error saving announcement recipients: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+	var recipientIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("Error saving announcement recipients: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+			return
+		}
+		recipientIDs = append(recipientIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("Error saving announcement recipients: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+
+	message := fmt.Sprintf("New announcement: %s", req.Title)
+	for _, id := range recipientIDs {
+		if err := createNotification(tx, orgID, id, "announcement", message, gin.H{"announcement_id": announcement.ID}); err != nil {
+			log.Printf("Error notifying announcement recipient: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+			return
+		}
+	}
+
+	// Users mentioned in several of the announcement's posts are notified
+	// once
+	mentionNotified := make(map[int]bool)
+	postIDs := make([]int, 0, len(chatboardIDs))
+	for _, chatboardID := range chatboardIDs {
+		var postID int
+		err := tx.QueryRow(`
+            INSERT INTO posts (chatboard_id, user_id, title, content, created_at, announcement_id)
+            VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, $5)
+            RETURNING id
+        `, chatboardID, userID, req.Title, req.Content, announcement.ID).Scan(&postID)
+		if err != nil {
+			log.Printf("Error creating announcement post: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+			return
+		}
+		postIDs = append(postIDs, postID)
+
+		if err := realtime.Publish(tx, realtime.Event{
+			Type:        realtime.EventPostCreated,
+			ChatboardID: chatboardID,
+			PostID:      postID,
+		}); err != nil {
+			log.Printf("Error publishing post event: %v", err)
+		}
+
+		mentioned, err := saveMentions(tx, orgID, chatboardID, userID, postTarget, postID, req.Content)
+		if err == nil {
+			var notify []int
+			for _, id := range mentioned {
+				if !mentionNotified[id] {
+					mentionNotified[id] = true
+					notify = append(notify, id)
+				}
+			}
+			err = notifyMentions(tx, orgID, notify, userID, "an announcement", gin.H{
+				"chatboard_id": chatboardID,
+				"post_id":      postID,
+			})
+		}
+		if err != nil {
+			log.Printf("Error saving announcement mentions: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing announcement: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create announcement"})
+		return
+	}
+
+	recipientCount, acknowledgedCount := len(recipientIDs), 0
+	announcement.RecipientCount = &recipientCount
+	announcement.AcknowledgedCount = &acknowledgedCount
+	c.JSON(http.StatusCreated, gin.H{
+		"announcement": announcement,
+		"post_ids":     postIDs,
+	})
+}
+
+// announcementSelect selects an announcement with its targets, and for user
+// $1 whether they acknowledged it. Join it against announcement_recipients as
+// ar.
+const announcementSelect = `
+        SELECT a.id, a.author_id, COALESCE(u.username, ''), a.title, a.content, a.created_at,
+               ARRAY(SELECT chatboard_id FROM announcement_chatboards WHERE announcement_id = a.id ORDER BY chatboard_id),
+               ARRAY(SELECT squad_id FROM announcement_squads WHERE announcement_id = a.id ORDER BY squad_id),
+               ar.acknowledged_at,
+               (SELECT COUNT(*) FROM announcement_recipients WHERE announcement_id = a.id),
+               (SELECT COUNT(*) FROM announcement_recipients WHERE announcement_id = a.id AND acknowledged_at IS NOT NULL)
+        FROM announcements a
+        LEFT JOIN users u ON u.id = a.author_id`
+
+func scanAnnouncement(row interface{ Scan(...interface{}) error }) (models.Announcement, error) {
+	var (
+		announcement                      models.Announcement
+		authorID                          sql.NullInt64
+		chatboardIDs, squadIDs            pq.Int64Array
+		acknowledgedAt                    sql.NullTime
+		recipientCount, acknowledgedCount int
+	)
+	err := row.Scan(
+		&announcement.ID,
+		&authorID,
+		&announcement.Author,
+		&announcement.Title,
+		&announcement.Content,
+		&announcement.CreatedAt,
+		&chatboardIDs,
+		&squadIDs,
+		&acknowledgedAt,
+		&recipientCount,
+		&acknowledgedCount,
+	)
+	announcement.AuthorID = nullIntPtr(authorID)
+	announcement.ChatboardIDs = toInts(chatboardIDs)
+	announcement.SquadIDs = toInts(squadIDs)
+	if acknowledgedAt.Valid {
+		announcement.Acknowledged = true
+		announcement.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	announcement.RecipientCount = &recipientCount
+	announcement.AcknowledgedCount = &acknowledgedCount
+	return announcement, err
+}
+
+// GetAnnouncements lists the announcements sent to the caller, newest first.
+// Pass unacknowledged=true to see only those they still have to acknowledge.
+func (h *AnnouncementHandler) GetAnnouncements(c *gin.Context) {
+	h.listAnnouncements(c, false)
+}
+
+// GetSentAnnouncements lists the announcements the caller sent, newest first,
+// with how many recipients acknowledged them. Admins and Head Unicorns see
+// every announcement of the organization.
+func (h *AnnouncementHandler) GetSentAnnouncements(c *gin.Context) {
+	h.listAnnouncements(c, true)
+}
+
+func (h *AnnouncementHandler) listAnnouncements(c *gin.Context, sent bool) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	query := announcementSelect
+	args := []interface{}{userID, orgID}
+	if sent {
+		isLeader, err := hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		query += `
+        LEFT JOIN announcement_recipients ar ON ar.announcement_id = a.id AND ar.user_id = $1
+        WHERE a.organization_id = $2 AND (a.author_id = $1 OR $3)`
+		args = append(args, isLeader)
+	} else {
+		query += `
+        JOIN announcement_recipients ar ON ar.announcement_id = a.id AND ar.user_id = $1
+        WHERE a.organization_id = $2`
+		if c.Query("unacknowledged") == "true" {
+			query += " AND ar.acknowledged_at IS NULL"
+		}
+	}
+
+	keyset, args := page.keyset("a.created_at", "a.id", false, args)
+	query += keyset + " ORDER BY a.created_at DESC, a.id DESC"
+	limit, args := page.limit(args)
+	query += limit
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching announcements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
+	}
+	defer rows.Close()
+
+	announcements := make([]models.Announcement, 0)
+	for rows.Next() {
+		announcement, err := scanAnnouncement(rows)
+		if err != nil {
+			log.Printf("Error scanning announcement: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+			return
+		}
+		if !sent {
+			// Recipients don't need the audience's progress
+			announcement.RecipientCount = nil
+			announcement.AcknowledgedCount = nil
+		}
+		announcements = append(announcements, announcement)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading announcements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcements"})
+		return
+	}
+
+	announcements, next := trimPage(announcements, page.Limit, func(i int) pageCursor {
+		return pageCursor{CreatedAt: announcements[i].CreatedAt, ID: announcements[i].ID}
+	})
+	c.JSON(http.StatusOK, pageResponse(announcements, next))
+}
+
+// loadAnnouncement loads the announcement from the path as the caller sees
+// it. Recipients, its author, Admins and Head Unicorns can see it; canManage
+// tells whether the caller is one of the latter. It writes the error response
+// and returns false when the announcement can't be seen.
+func (h *AnnouncementHandler) loadAnnouncement(c *gin.Context) (announcement models.Announcement, canManage bool, ok bool) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	announcementID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		return announcement, false, false
+	}
+
+	var isRecipient bool
+	announcement, err = scanAnnouncement(h.db.QueryRow(announcementSelect+`
+        LEFT JOIN announcement_recipients ar ON ar.announcement_id = a.id AND ar.user_id = $1
+        WHERE a.id = $2 AND a.organization_id = $3
+    `, userID, announcementID, orgID))
+	if err == nil {
+		err = h.db.QueryRow(`
+            SELECT EXISTS (SELECT 1 FROM announcement_recipients WHERE announcement_id = $1 AND user_id = $2)
+        `, announcementID, userID).Scan(&isRecipient)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return announcement, false, false
+	} else if err != nil {
+		log.Printf("Error fetching announcement: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch announcement"})
+		return announcement, false, false
+	}
+
+	canManage = announcement.AuthorID != nil && *announcement.AuthorID == userID
+	if !canManage {
+		canManage, err = hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return announcement, false, false
+		}
+	}
+	if !canManage && !isRecipient {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return announcement, false, false
+	}
+	if !canManage {
+		announcement.RecipientCount = nil
+		announcement.AcknowledgedCount = nil
+	}
+
+	return announcement, canManage, true
+}
+
+// GetAnnouncement returns one announcement
+func (h *AnnouncementHandler) GetAnnouncement(c *gin.Context) {
+	announcement, _, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, announcement)
+}
+
+// AcknowledgeAnnouncement records that the caller has read the announcement.
+// Acknowledging again keeps the first time.
+func (h *AnnouncementHandler) AcknowledgeAnnouncement(c *gin.Context) {
+	announcementID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement ID"})
+		return
+	}
+
+	var acknowledgedAt time.Time
+	err = h.db.QueryRow(`
+        UPDATE announcement_recipients ar
+        SET acknowledged_at = COALESCE(ar.acknowledged_at, NOW())
+        FROM announcements a
+        WHERE a.id = ar.announcement_id
+        AND ar.announcement_id = $1 AND ar.user_id = $2 AND a.organization_id = $3
+        RETURNING ar.acknowledged_at
+    `, announcementID, c.GetInt("userID"), c.GetInt("orgID")).Scan(&acknowledgedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Announcement not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error acknowledging announcement: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge announcement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Announcement acknowledged",
+		"acknowledged":    true,
+		"acknowledged_at": acknowledgedAt,
+	})
+}
+
+// GetAcknowledgements shows the announcement's author and leaders who has
+// acknowledged it and who hasn't yet
+func (h *AnnouncementHandler) GetAcknowledgements(c *gin.Context) {
+	announcement, canManage, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author, Admins and Head Unicorns can see acknowledgements"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+        SELECT ar.user_id, COALESCE(u.username, ''), u.first_name, u.last_name, ar.acknowledged_at, ar.reminded_at
+        FROM announcement_recipients ar
+        JOIN users u ON u.id = ar.user_id
+        WHERE ar.announcement_id = $1
+        ORDER BY ar.acknowledged_at NULLS LAST, u.first_name, u.last_name, ar.user_id
+    `, announcement.ID)
+	if err != nil {
+		log.Printf("Error fetching acknowledgements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch acknowledgements"})
+		return
+	}
+	defer rows.Close()
+
+	report := models.AcknowledgementReport{
+		AnnouncementID: announcement.ID,
+		Acknowledged:   make([]models.AnnouncementRecipient, 0),
+		Pending:        make([]models.AnnouncementRecipient, 0),
+	}
+	for rows.Next() {
+		var recipient models.AnnouncementRecipient
+		var acknowledgedAt, remindedAt sql.NullTime
+		err := rows.Scan(&recipient.UserID, &recipient.Username, &recipient.FirstName, &recipient.LastName, &acknowledgedAt, &remindedAt)
+		if err != nil {
+			log.Printf("Error scanning acknowledgement: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch acknowledgements"})
+			return
+		}
+		if remindedAt.Valid {
+			recipient.RemindedAt = &remindedAt.Time
+		}
+		if acknowledgedAt.Valid {
+			recipient.AcknowledgedAt = &acknowledgedAt.Time
+			report.Acknowledged = append(report.Acknowledged, recipient)
+		} else {
+			report.Pending = append(report.Pending, recipient)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading acknowledgements: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch acknowledgements"})
+		return
+	}
+	report.AcknowledgedCount = len(report.Acknowledged)
+	report.RecipientCount = report.AcknowledgedCount + len(report.Pending)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RemindAnnouncement notifies the recipients who haven't acknowledged the
+// announcement yet, or only the given ones. Recipients reminded within the
+// last hour are skipped.
+func (h *AnnouncementHandler) RemindAnnouncement(c *gin.Context) {
+	announcement, canManage, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	if !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author, Admins and Head Unicorns can send reminders"})
+		return
+	}
+
+	var req models.RemindAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+		return
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        UPDATE announcement_recipients SET reminded_at = NOW()
+        WHERE announcement_id = $1 AND acknowledged_at IS NULL
+        AND (CARDINALITY($2::INTEGER[]) = 0 OR user_id = ANY($2))
+        AND (reminded_at IS NULL OR reminded_at <= NOW() - MAKE_INTERVAL(secs => $3))
+        RETURNING user_id
+    `, announcement.ID, pq.Array(uniqueIDs(req.UserIDs)), announcementReminderInterval.Seconds())
+	if err != nil {
+		log.Printf("Error updating reminders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+		return
+	}
+	var reminded []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("Error updating reminders: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+			return
+		}
+		reminded = append(reminded, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("Error updating reminders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+		return
+	}
+
+	message := fmt.Sprintf("Reminder: please read and acknowledge \"%s\"", announcement.Title)
+	for _, id := range reminded {
+		if err := createNotification(tx, c.GetInt("orgID"), id, "announcement_reminder", message, gin.H{"announcement_id": announcement.ID}); err != nil {
+			log.Printf("Error sending reminder: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing reminders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("Reminded %d recipients", len(reminded)),
+		"reminded": len(reminded),
+	})
+}
@@ -0,0 +1,619 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"unicorn_app_backend/imaging"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultAttachmentMaxBytes is the largest file accepted unless
+	// ATTACHMENT_MAX_BYTES says otherwise
+	defaultAttachmentMaxBytes = 10 << 20
+	maxAttachmentsPerPost     = 10
+	// attachmentURLExpiry is how long presigned download URLs stay valid
+	attachmentURLExpiry = 5 * time.Minute
+)
+
+// attachmentTypes are the accepted content types, as sniffed from the file's
+// first bytes, with the extension objects are stored under
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type AttachmentHandler struct {
+	db       *sql.DB
+	store    storage.Store
+	maxBytes int64
+}
+
+func NewAttachmentHandler(db *sql.DB, store storage.Store) *AttachmentHandler {
+	return &AttachmentHandler{db: db, store: store, maxBytes: attachmentMaxBytes()}
+}
+
+func attachmentMaxBytes() int64 {
+	value := os.Getenv("ATTACHMENT_MAX_BYTES")
+	if value == "" {
+		return defaultAttachmentMaxBytes
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid ATTACHMENT_MAX_BYTES %q, using %d", value, defaultAttachmentMaxBytes)
+		return defaultAttachmentMaxBytes
+	}
+	return n
+}
+
+// attachmentTarget is the post and chatboard an attachment belongs to
+type attachmentTarget struct {
+	PostID      int
+	ChatboardID int
+	AuthorID    int
+	Deleted     bool
+}
+
+// UploadAttachment attaches the multipart file field to a post. Only the
+// post's author can attach files. The type is sniffed from the content, the
+// name and type sent by the client are not trusted.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var post attachmentTarget
+	err = h.db.QueryRow(`
+        SELECT p.id, p.chatboard_id, p.user_id, p.deleted_at IS NOT NULL
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+    `, postID, orgID).Scan(&post.PostID, &post.ChatboardID, &post.AuthorID, &post.Deleted)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	if post.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can attach files to this post"})
+		return
+	}
+	if post.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Removed posts cannot get attachments"})
+		return
+	}
+
+	if !ensureNotRestricted(c, h.db, post.ChatboardID) {
+		return
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, post.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	archived, err := isChatboardArchived(h.db, post.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) {
+		return
+	}
+
+	var count int
+	err = h.db.QueryRow("SELECT COUNT(*) FROM attachments WHERE post_id = $1", post.PostID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachments"})
+		return
+	}
+	if count >= maxAttachmentsPerPost {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Posts can have at most %d attachments", maxAttachmentsPerPost)})
+		return
+	}
+
+	// Leave room for the multipart headers around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Files can be at most %d bytes", h.maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the file field"})
+		return
+	}
+	if header.Size > h.maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Files can be at most %d bytes", h.maxBytes)})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := attachmentTypes[contentType]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only JPEG, PNG, GIF, WebP images and PDF files can be attached"})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	// Images are stored re-encoded without their metadata, EXIF GPS
+	// coordinates included, and get resized variants
+	var content io.Reader = file
+	size := header.Size
+	var processed *imaging.Result
+	if strings.HasPrefix(contentType, "image/") {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
+		}
+		processed, err = imaging.Process(data, contentType)
+		if errors.Is(err, imaging.ErrTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image dimensions are too large"})
+			return
+		} else if err != nil {
+			log.Printf("Error processing image: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Image could not be processed"})
+			return
+		}
+		content = bytes.NewReader(processed.Data)
+		size = int64(len(processed.Data))
+	}
+
+	base, err := attachmentKeyBase(post.PostID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	// Objects stored so far, deleted again if the upload fails
+	var stored []string
+	cleanup := func() {
+		for _, key := range stored {
+			if err := h.store.Delete(context.Background(), key); err != nil {
+				log.Printf("Error deleting orphaned attachment %s: %v", key, err)
+			}
+		}
+	}
+
+	key := base + ext
+	if err := h.store.Put(c.Request.Context(), key, content, size, contentType); err != nil {
+		log.Printf("Error storing attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+	stored = append(stored, key)
+
+	attachment := models.AttachmentResponse{
+		PostID:      post.PostID,
+		Filename:    attachmentFilename(header.Filename, ext),
+		ContentType: contentType,
+		Size:        size,
+	}
+
+	variantKeys := make(map[string]string)
+	if processed != nil {
+		attachment.Width = &processed.Width
+		attachment.Height = &processed.Height
+		attachment.BlurHash = &processed.BlurHash
+
+		for _, variant := range processed.Variants {
+			variantKey := base + "_" + variant.Name + attachmentTypes[variant.ContentType]
+			err := h.store.Put(c.Request.Context(), variantKey, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType)
+			if err != nil {
+				log.Printf("Error storing attachment variant: %v", err)
+				cleanup()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+				return
+			}
+			stored = append(stored, variantKey)
+			variantKeys[variant.Name] = variantKey
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		cleanup()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+        INSERT INTO attachments (post_id, user_id, storage_key, filename, content_type, size_bytes, width, height, blurhash)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, created_at
+    `, post.PostID, userID, key, attachment.Filename, contentType, size,
+		attachment.Width, attachment.Height, attachment.BlurHash).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating attachment: %v", err)
+		cleanup()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create attachment"})
+		return
+	}
+	attachment.URL = attachmentURL(attachment.ID)
+
+	if processed != nil {
+		attachment.Variants = make(map[string]models.AttachmentVariant)
+		for _, variant := range processed.Variants {
+			_, err := tx.Exec(`
+                INSERT INTO attachment_variants (attachment_id, variant, storage_key, content_type, width, height, size_bytes)
+                VALUES ($1, $2, $3, $4, $5, $6, $7)
+            `, attachment.ID, variant.Name, variantKeys[variant.Name], variant.ContentType, variant.Width, variant.Height, len(variant.Data))
+			if err != nil {
+				log.Printf("Error creating attachment variant: %v", err)
+				cleanup()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create attachment"})
+				return
+			}
+			attachment.Variants[variant.Name] = models.AttachmentVariant{
+				URL:    attachmentVariantURL(attachment.ID, variant.Name),
+				Width:  variant.Width,
+				Height: variant.Height,
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		cleanup()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadAttachment serves an attachment, or with ?variant= one of its
+// resized images, after checking the caller can still read the post's
+// chatboard. Stores that can presign URLs redirect there, the file is
+// streamed through the API otherwise.
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	var post attachmentTarget
+	var held bool
+	var key, filename, contentType string
+	var size int64
+	err = h.db.QueryRow(`
+        SELECT p.id, p.chatboard_id, p.user_id, p.deleted_at IS NOT NULL, p.held_at IS NOT NULL,
+            a.storage_key, a.filename, a.content_type, a.size_bytes
+        FROM attachments a
+        JOIN posts p ON p.id = a.post_id
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE a.id = $1 AND cb.organization_id = $2
+        AND (p.status = 'published' OR p.user_id = $3)
+    `, attachmentID, orgID, userID).Scan(&post.PostID, &post.ChatboardID, &post.AuthorID, &post.Deleted, &held, &key, &filename, &contentType, &size)
+	if err == sql.ErrNoRows || (err == nil && post.Deleted) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+		return
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, post.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	// Attachments of held posts are only served to the author and the
+	// moderators reviewing the post
+	if held && post.AuthorID != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, orgID, post.ChatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+	}
+
+	if variant := c.Query("variant"); variant != "" {
+		err = h.db.QueryRow(`
+            SELECT storage_key, content_type, size_bytes
+            FROM attachment_variants
+            WHERE attachment_id = $1 AND variant = $2
+        `, attachmentID, variant).Scan(&key, &contentType, &size)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment variant not found"})
+			return
+		} else if err != nil {
+			log.Printf("Error fetching attachment variant: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+			return
+		}
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + "_" + variant + attachmentTypes[contentType]
+	}
+
+	if presigner, ok := h.store.(storage.Presigner); ok {
+		url, err := presigner.PresignGet(c.Request.Context(), key, attachmentURLExpiry)
+		if err != nil {
+			log.Printf("Error presigning attachment: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+			return
+		}
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	content, err := h.store.Open(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("Error opening attachment %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+	c.Header("Cache-Control", "private, max-age=300")
+	c.DataFromReader(http.StatusOK, size, contentType, content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType(disposition, map[string]string{"filename": filename}),
+	})
+}
+
+// DeleteAttachment removes an attachment for the uploader and the
+// chatboard's moderators
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	var chatboardID int
+	var uploaderID sql.NullInt64
+	err = h.db.QueryRow(`
+        SELECT p.chatboard_id, a.user_id
+        FROM attachments a
+        JOIN posts p ON p.id = a.post_id
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE a.id = $1 AND cb.organization_id = $2
+    `, attachmentID, orgID).Scan(&chatboardID, &uploaderID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch attachment"})
+		return
+	}
+
+	if !uploaderID.Valid || int(uploaderID.Int64) != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, orgID, chatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader and moderators can delete this attachment"})
+			return
+		}
+	}
+
+	// Deleting the rows queues the stored objects of the attachment and its
+	// variants for deletion by the storage sweep
+	if _, err := h.db.Exec("DELETE FROM attachments WHERE id = $1", attachmentID); err != nil {
+		log.Printf("Error deleting attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// loadAttachments returns the attachments of each post with one query.
+// Every ID gets a list, empty when the post has none.
+func loadAttachments(db querier, postIDs []int) (map[int][]models.AttachmentResponse, error) {
+	attachments := make(map[int][]models.AttachmentResponse, len(postIDs))
+	for _, id := range postIDs {
+		attachments[id] = make([]models.AttachmentResponse, 0)
+	}
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+
+	variants, err := loadAttachmentVariants(db, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+        SELECT id, post_id, filename, content_type, size_bytes, width, height, blurhash, created_at
+        FROM attachments
+        WHERE post_id = ANY($1)
+        ORDER BY created_at, id
+    `, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.AttachmentResponse
+		var width, height sql.NullInt64
+		var blurHash sql.NullString
+		err := rows.Scan(&a.ID, &a.PostID, &a.Filename, &a.ContentType, &a.Size, &width, &height, &blurHash, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.URL = attachmentURL(a.ID)
+		if width.Valid && height.Valid {
+			w, h := int(width.Int64), int(height.Int64)
+			a.Width, a.Height = &w, &h
+		}
+		if blurHash.Valid {
+			a.BlurHash = &blurHash.String
+		}
+		a.Variants = variants[a.ID]
+		attachments[a.PostID] = append(attachments[a.PostID], a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// loadAttachmentVariants returns the variants of the posts' attachments by
+// attachment ID
+func loadAttachmentVariants(db querier, postIDs []int) (map[int]map[string]models.AttachmentVariant, error) {
+	rows, err := db.Query(`
+        SELECT v.attachment_id, v.variant, v.width, v.height
+        FROM attachment_variants v
+        JOIN attachments a ON a.id = v.attachment_id
+        WHERE a.post_id = ANY($1)
+    `, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make(map[int]map[string]models.AttachmentVariant)
+	for rows.Next() {
+		var attachmentID int
+		var name string
+		var v models.AttachmentVariant
+		if err := rows.Scan(&attachmentID, &name, &v.Width, &v.Height); err != nil {
+			return nil, err
+		}
+		v.URL = attachmentVariantURL(attachmentID, name)
+		if variants[attachmentID] == nil {
+			variants[attachmentID] = make(map[string]models.AttachmentVariant)
+		}
+		variants[attachmentID][name] = v
+	}
+
+	return variants, rows.Err()
+}
+
+func attachmentURL(id int) string {
+	return "/attachments/" + strconv.Itoa(id)
+}
+
+func attachmentVariantURL(id int, variant string) string {
+	return attachmentURL(id) + "?variant=" + variant
+}
+
+// attachmentKeyBase picks a random, unguessable storage key for a post's
+// file. The file and its variants are stored under it with their suffixes.
+func attachmentKeyBase(postID int) (string, error) {
+	name, err := randomStorageName()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%s", postID, name), nil
+}
+
+// randomStorageName returns an unguessable name for a stored object
+func randomStorageName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// attachmentFilename cleans up the name sent by the client for display and
+// Content-Disposition, making sure it ends in the sniffed type's extension
+func attachmentFilename(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+
+	if !strings.EqualFold(filepath.Ext(name), ext) && !(ext == ".jpg" && strings.EqualFold(filepath.Ext(name), ".jpeg")) {
+		name += ext
+	}
+	for len(name) > 255 || !utf8.ValidString(name) {
+		name = strings.ToValidUTF8(name, "")
+		if len(name) > 255 {
+			name = name[:255-len(ext)] + ext
+		}
+	}
+	return name
+}
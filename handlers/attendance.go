@@ -16,25 +16,16 @@ func NewAttendanceHandler(db *sql.DB) *AttendanceHandler {
 	return &AttendanceHandler{db: db}
 }
 
-func (h *AttendanceHandler) checkPermission(userID int) (bool, error) {
-	var hasPermission bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 FROM user_roles ur
-            JOIN roles r ON r.id = ur.role_id
-            WHERE ur.user_id = $1 
-            AND r.role IN ('Admin', 'Head Unicorn', 'Helper Unicorn')
-        )
-    `, userID).Scan(&hasPermission)
-
-	return hasPermission, err
+func (h *AttendanceHandler) checkPermission(userID, orgID int) (bool, error) {
+	return hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn", "Helper Unicorn")
 }
 
 func (h *AttendanceHandler) CreateAttendance(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
 	// Check if user has permission
-	hasPermission, err := h.checkPermission(userID)
+	hasPermission, err := h.checkPermission(userID, orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
 		return
@@ -55,10 +46,11 @@ func (h *AttendanceHandler) CreateAttendance(c *gin.Context) {
 	var lessonExists bool
 	err = h.db.QueryRow(`
         SELECT EXISTS (
-            SELECT 1 FROM lessons 
-            WHERE id = $1
+            SELECT 1 FROM lessons l
+            JOIN courses co ON co.id = l.course_id
+            WHERE l.id = $1 AND co.organization_id = $2
         )
-    `, req.LessonID).Scan(&lessonExists)
+    `, req.LessonID, orgID).Scan(&lessonExists)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify lesson"})
@@ -74,10 +66,10 @@ func (h *AttendanceHandler) CreateAttendance(c *gin.Context) {
 	var userExists bool
 	err = h.db.QueryRow(`
         SELECT EXISTS (
-            SELECT 1 FROM users 
-            WHERE id = $1
+            SELECT 1 FROM organization_members 
+            WHERE user_id = $1 AND organization_id = $2
         )
-    `, req.UserID).Scan(&userExists)
+    `, req.UserID, orgID).Scan(&userExists)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify user"})
@@ -172,9 +164,10 @@ func (h *AttendanceHandler) CreateAttendance(c *gin.Context) {
 
 func (h *AttendanceHandler) GetAttendances(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
 	// Check if user has permission
-	hasPermission, err := h.checkPermission(userID)
+	hasPermission, err := h.checkPermission(userID, orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
 		return
@@ -187,6 +180,11 @@ func (h *AttendanceHandler) GetAttendances(c *gin.Context) {
 
 	lessonID := c.Query("lesson_id")
 
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
 	query := `
         SELECT 
             a.id,
@@ -201,15 +199,19 @@ func (h *AttendanceHandler) GetAttendances(c *gin.Context) {
         JOIN users u ON u.id = a.user_id
         JOIN lessons l ON l.id = a.lesson_id
         JOIN courses c ON c.id = l.course_id
+        WHERE c.organization_id = $1
     `
-	params := []interface{}{}
+	params := []interface{}{orgID}
 
 	if lessonID != "" {
-		query += " WHERE a.lesson_id = $1"
+		query += " AND a.lesson_id = $2"
 		params = append(params, lessonID)
 	}
 
-	query += " ORDER BY a.created_at DESC"
+	keyset, params := page.keyset("a.created_at", "a.id", false, params)
+	query += keyset + " ORDER BY a.created_at DESC, a.id DESC"
+	limit, params := page.limit(params)
+	query += limit
 
 	rows, err := h.db.Query(query, params...)
 	if err != nil {
@@ -218,11 +220,11 @@ func (h *AttendanceHandler) GetAttendances(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var attendances []struct {
+	attendances := make([]struct {
 		models.AttendanceResponse
 		LessonTitle string `json:"lesson_title"`
 		CourseName  string `json:"course_name"`
-	}
+	}, 0)
 
 	for rows.Next() {
 		var attendance struct {
@@ -247,15 +249,19 @@ func (h *AttendanceHandler) GetAttendances(c *gin.Context) {
 		attendances = append(attendances, attendance)
 	}
 
-	c.JSON(http.StatusOK, attendances)
+	attendances, next := trimPage(attendances, page.Limit, func(i int) pageCursor {
+		return pageCursor{CreatedAt: attendances[i].CreatedAt, ID: attendances[i].ID}
+	})
+	c.JSON(http.StatusOK, pageResponse(attendances, next))
 }
 
 func (h *AttendanceHandler) DeleteAttendance(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 	attendanceID := c.Param("id")
 
 	// Check if user has permission
-	hasPermission, err := h.checkPermission(userID)
+	hasPermission, err := h.checkPermission(userID, orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
 		return
@@ -270,10 +276,12 @@ func (h *AttendanceHandler) DeleteAttendance(c *gin.Context) {
 	var exists bool
 	err = h.db.QueryRow(`
         SELECT EXISTS (
-            SELECT 1 FROM attendances 
-            WHERE id = $1
+            SELECT 1 FROM attendances a
+            JOIN lessons l ON l.id = a.lesson_id
+            JOIN courses co ON co.id = l.course_id
+            WHERE a.id = $1 AND co.organization_id = $2
         )
-    `, attendanceID).Scan(&exists)
+    `, attendanceID, orgID).Scan(&exists)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify attendance"})
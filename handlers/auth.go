@@ -38,6 +38,16 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	log.Printf("Registration attempt for email: %s", req.Email)
 
+	// New users join the default organization. Admins of other organizations
+	// add members themselves, so nobody can sign up into one.
+	var orgID int
+	err := h.db.QueryRow("SELECT id FROM organizations WHERE slug = 'default'").Scan(&orgID)
+	if err != nil {
+		log.Printf("Error looking up organization: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization"})
+		return
+	}
+
 	var exists bool
 	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`, req.Email).Scan(&exists); err != nil {
 		log.Printf("Error checking email existence: %v", err)
@@ -79,6 +89,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.db.Exec(
+		"INSERT INTO organization_members (organization_id, user_id) VALUES ($1, $2)",
+		orgID, userID,
+	); err != nil {
+		log.Printf("Error adding user to organization: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+		return
+	}
+
 	// Get user profile
 	profile, err := h.getUserProfile(userID)
 	if err != nil {
@@ -187,34 +206,65 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// getUserProfile fetches all related information for a user
+// getUserProfile fetches all related information for a user. Roles, squads
+// and countries come from the user's first organization, which is also the
+// one requests act in when no X-Organization-ID header is sent.
 func (h *AuthHandler) getUserProfile(userID int) (gin.H, error) {
 	profile := gin.H{
-		"username":  "",
-		"roles":     []string{},
-		"squads":    []gin.H{},
-		"countries": []string{},
+		"username":      "",
+		"roles":         []string{},
+		"squads":        []gin.H{},
+		"countries":     []string{},
+		"organizations": []gin.H{},
+	}
+
+	// Get organizations
+	orgRows, err := h.db.Query(`
+		SELECT o.id, o.name, o.slug
+		FROM organization_members om
+		JOIN organizations o ON o.id = om.organization_id
+		WHERE om.user_id = $1
+		ORDER BY om.created_at, o.id
+	`, userID)
+	if err != nil {
+		return profile, err
 	}
+	defer orgRows.Close()
 
-	// Get username
-	var username sql.NullString
-	err := h.db.QueryRow(`
-		SELECT username FROM users WHERE id = $1
-	`, userID).Scan(&username)
+	organizations := []gin.H{}
+	var orgID int
+	for orgRows.Next() {
+		var id int
+		var name, slug string
+		if err := orgRows.Scan(&id, &name, &slug); err == nil {
+			if len(organizations) == 0 {
+				orgID = id
+			}
+			organizations = append(organizations, gin.H{"id": id, "name": name, "slug": slug})
+		}
+	}
+	profile["organizations"] = organizations
+
+	// Get username and picture
+	var username, avatarPreset, avatarImageKey sql.NullString
+	err = h.db.QueryRow(`
+		SELECT username, avatar_preset, avatar_image_key FROM users WHERE id = $1
+	`, userID).Scan(&username, &avatarPreset, &avatarImageKey)
 	if err != nil && err != sql.ErrNoRows {
 		return profile, err
 	}
 	if username.Valid {
 		profile["username"] = username.String
 	}
+	profile["avatar_url"] = avatarURL(userID, avatarPreset, avatarImageKey)
 
 	// Get global roles
 	roleRows, err := h.db.Query(`
 		SELECT DISTINCT r.role 
 		FROM user_roles ur
 		JOIN roles r ON r.id = ur.role_id
-		WHERE ur.user_id = $1
-	`, userID)
+		WHERE ur.user_id = $1 AND ur.organization_id = $2
+	`, userID, orgID)
 	if err != nil && err != sql.ErrNoRows {
 		return profile, err
 	}
@@ -239,8 +289,8 @@ func (h *AuthHandler) getUserProfile(userID int) (gin.H, error) {
 			us.status
 		FROM user_squads us
 		JOIN squads s ON s.id = us.squad_id
-		WHERE us.user_id = $1
-	`, userID)
+		WHERE us.user_id = $1 AND s.organization_id = $2
+	`, userID, orgID)
 	if err != nil && err != sql.ErrNoRows {
 		return profile, err
 	}
@@ -267,8 +317,8 @@ func (h *AuthHandler) getUserProfile(userID int) (gin.H, error) {
 		SELECT c.name 
 		FROM user_countries uc
 		JOIN countries c ON c.id = uc.country_id
-		WHERE uc.user_id = $1
-	`, userID)
+		WHERE uc.user_id = $1 AND c.organization_id = $2
+	`, userID, orgID)
 	if err != nil && err != sql.ErrNoRows {
 		return profile, err
 	}
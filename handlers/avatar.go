@@ -7,24 +7,26 @@ import (
 	"net/http"
 
 	"unicorn_app_backend/models"
+	"unicorn_app_backend/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
 type AvatarHandler struct {
-	db *sql.DB
+	db    *sql.DB
+	store storage.Store
 }
 
-func NewAvatarHandler(db *sql.DB) *AvatarHandler {
-	return &AvatarHandler{db: db}
+func NewAvatarHandler(db *sql.DB, store storage.Store) *AvatarHandler {
+	return &AvatarHandler{db: db, store: store}
 }
 
 // GetUserAvatar retrieves all user-related information
 func (h *AvatarHandler) GetUserAvatar(c *gin.Context) {
 	userID := c.GetInt("userID")
 
-	profile, err := h.getUserProfile(userID)
+	profile, err := h.getUserProfile(userID, c.GetInt("orgID"))
 	if err != nil {
 		log.Printf("Error getting user profile: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
@@ -37,6 +39,7 @@ func (h *AvatarHandler) GetUserAvatar(c *gin.Context) {
 // CreateUserAvatar handles setting up user's profile information
 func (h *AvatarHandler) CreateUserAvatar(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 	var req models.CreateAvatarRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -67,47 +70,36 @@ func (h *AvatarHandler) CreateUserAvatar(c *gin.Context) {
 
 	// Process squad roles
 	for _, squadRole := range req.SquadRoles {
-		// Insert/update user_squads
-		_, err = tx.Exec(`
-			INSERT INTO user_squads (user_id, squad_id, status)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (user_id, squad_id) 
-			DO UPDATE SET status = EXCLUDED.status
-		`, userID, squadRole.SquadID, squadRole.Status)
+		// New memberships go through the join workflow and start as Pending
+		var status sql.NullString
+		var archived bool
+		err = tx.QueryRow(`
+			SELECT us.status, s.archived_at IS NOT NULL
+			FROM squads s
+			LEFT JOIN user_squads us ON us.squad_id = s.id AND us.user_id = $1
+			WHERE s.id = $2 AND s.organization_id = $3
+		`, userID, squadRole.SquadID, orgID).Scan(&status, &archived)
 		if err != nil {
-			log.Printf("Error saving user squad: %v", err)
+			log.Printf("Error checking squad %d: %v", squadRole.SquadID, err)
 			continue
 		}
-
-		// Check if role is Admin
-		var isAdmin bool
-		err = tx.QueryRow(`
-			SELECT role = 'Admin' 
-			FROM roles 
-			WHERE id = $1
-		`, squadRole.RoleID).Scan(&isAdmin)
-		if err != nil {
-			log.Printf("Error checking if role is admin: %v", err)
+		if archived && !status.Valid {
+			log.Printf("Skipping archived squad %d", squadRole.SquadID)
 			continue
 		}
 
-		if isAdmin {
-			// For Admin role, add to user_roles
-			_, err = tx.Exec(`
-				INSERT INTO user_roles (user_id, role_id)
-				VALUES ($1, $2)
-				ON CONFLICT (user_id, role_id) DO NOTHING
-			`, userID, squadRole.RoleID)
-		} else {
-			// For non-Admin roles, add to user_squad_roles
-			_, err = tx.Exec(`
-				INSERT INTO user_squad_roles (user_id, squad_id, role_id)
-				VALUES ($1, $2, $3)
-				ON CONFLICT (user_id, squad_id, role_id) DO NOTHING
-			`, userID, squadRole.SquadID, squadRole.RoleID)
+		if !status.Valid || status.String == models.SquadStatusRejected || status.String == models.SquadStatusRemoved {
+			err = transitionSquadMembership(tx, userID, squadRole.SquadID, models.SquadStatusPending, userID, "")
+			if err != nil {
+				log.Printf("Error applying to squad %d: %v", squadRole.SquadID, err)
+				continue
+			}
 		}
-		if err != nil {
-			log.Printf("Error saving role assignment: %v", err)
+
+		// The role is only a request, granted when the membership is
+		// approved. Approved members' roles are changed by the squad's managers.
+		if err = requestSquadRole(tx, userID, squadRole.SquadID, squadRole.RoleID); err != nil {
+			log.Printf("Error requesting role %d in squad %d: %v", squadRole.RoleID, squadRole.SquadID, err)
 			continue
 		}
 	}
@@ -116,9 +108,10 @@ func (h *AvatarHandler) CreateUserAvatar(c *gin.Context) {
 	if req.CountryID > 0 {
 		_, err = tx.Exec(`
 			INSERT INTO user_countries (user_id, country_id)
-			VALUES ($1, $2)
+			SELECT $1, id FROM countries
+			WHERE id = $2 AND organization_id = $3
 			ON CONFLICT (user_id, country_id) DO NOTHING
-		`, userID, req.CountryID)
+		`, userID, req.CountryID, orgID)
 		if err != nil {
 			log.Printf("Error saving user country: %v", err)
 		}
@@ -130,7 +123,7 @@ func (h *AvatarHandler) CreateUserAvatar(c *gin.Context) {
 	}
 
 	// Get updated profile
-	profile, err := h.getUserProfile(userID)
+	profile, err := h.getUserProfile(userID, orgID)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"message": "Avatar saved successfully, but failed to fetch updated profile"})
 		return
@@ -139,19 +132,19 @@ func (h *AvatarHandler) CreateUserAvatar(c *gin.Context) {
 	c.JSON(http.StatusOK, profile)
 }
 
-// Add this method to AvatarHandler
-func (h *AvatarHandler) getUserProfile(userID int) (models.UserAvatarResponse, error) {
+// getUserProfile returns the user's roles, squads and countries within the organization
+func (h *AvatarHandler) getUserProfile(userID, orgID int) (models.UserAvatarResponse, error) {
 	profile := models.UserAvatarResponse{
 		Roles:     make([]string, 0),
 		Squads:    make([]models.UserSquad, 0),
 		Countries: make([]string, 0),
 	}
 
-	// Get username
-	var username sql.NullString
+	// Get username and picture
+	var username, avatarPreset, avatarImageKey sql.NullString
 	err := h.db.QueryRow(`
-		SELECT username FROM users WHERE id = $1
-	`, userID).Scan(&username)
+		SELECT username, avatar_preset, avatar_image_key FROM users WHERE id = $1
+	`, userID).Scan(&username, &avatarPreset, &avatarImageKey)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return profile, nil // Return empty profile if user not found
@@ -161,14 +154,15 @@ func (h *AvatarHandler) getUserProfile(userID int) (models.UserAvatarResponse, e
 	if username.Valid {
 		profile.Username = username.String
 	}
+	profile.AvatarURL = avatarURL(userID, avatarPreset, avatarImageKey)
 
 	// Get global roles (including Admin)
 	roleRows, err := h.db.Query(`
 		SELECT DISTINCT r.role 
 		FROM user_roles ur
 		JOIN roles r ON r.id = ur.role_id
-		WHERE ur.user_id = $1
-	`, userID)
+		WHERE ur.user_id = $1 AND ur.organization_id = $2
+	`, userID, orgID)
 	if err != nil {
 		return profile, fmt.Errorf("failed to fetch roles: %w", err)
 	}
@@ -192,10 +186,10 @@ func (h *AvatarHandler) getUserProfile(userID int) (models.UserAvatarResponse, e
 		JOIN squads s ON s.id = us.squad_id
 		LEFT JOIN user_squad_roles usr ON usr.squad_id = s.id AND usr.user_id = us.user_id
 		LEFT JOIN roles r ON r.id = usr.role_id
-		WHERE us.user_id = $1
+		WHERE us.user_id = $1 AND s.organization_id = $2
 		GROUP BY s.id, s.name, us.status
 		ORDER BY s.name
-	`, userID)
+	`, userID, orgID)
 	if err != nil {
 		return profile, fmt.Errorf("failed to fetch squads: %w", err)
 	}
@@ -222,9 +216,9 @@ func (h *AvatarHandler) getUserProfile(userID int) (models.UserAvatarResponse, e
 		SELECT c.name 
 		FROM user_countries uc
 		JOIN countries c ON c.id = uc.country_id
-		WHERE uc.user_id = $1
+		WHERE uc.user_id = $1 AND c.organization_id = $2
 		ORDER BY c.name
-	`, userID)
+	`, userID, orgID)
 	if err != nil {
 		return profile, fmt.Errorf("failed to fetch countries: %w", err)
 	}
@@ -242,8 +236,9 @@ func (h *AvatarHandler) getUserProfile(userID int) (models.UserAvatarResponse, e
 
 // Add this method to AvatarHandler
 func (h *AvatarHandler) VerifyUserSquad(c *gin.Context) {
-	// Get the admin's userID from the context
+	// Get the admin's userID and organization from the context
 	adminID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
 	// Parse request
 	var req models.VerificationRequest
@@ -260,17 +255,25 @@ func (h *AvatarHandler) VerifyUserSquad(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	// Check if the admin has permission (must be Admin or Head Unicorn)
-	var hasPermission bool
-	err = tx.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 FROM user_roles ur
-			JOIN roles r ON r.id = ur.role_id
-			WHERE ur.user_id = $1 
-			AND r.role IN ('Admin', 'Head Unicorn')
-		)
-	`, adminID).Scan(&hasPermission)
+	// Squads of other organizations are invisible
+	var squadExists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM squads WHERE id = $1 AND organization_id = $2)",
+		req.SquadID, orgID,
+	).Scan(&squadExists)
+	if err != nil {
+		log.Printf("Error checking squad existence: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check squad existence"})
+		return
+	}
+
+	if !squadExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad does not exist"})
+		return
+	}
 
+	// Check if the admin has permission (Admin, Head Unicorn or a leader of the squad)
+	hasPermission, err := canManageSquad(tx, adminID, orgID, req.SquadID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 		return
@@ -311,34 +314,14 @@ func (h *AvatarHandler) VerifyUserSquad(c *gin.Context) {
 			return
 		}
 
-		// Check if squad exists
-		var squadExists bool
-		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM squads WHERE id = $1)", req.SquadID).Scan(&squadExists)
-		if err != nil {
-			log.Printf("Error checking squad existence: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check squad existence"})
-			return
-		}
-
-		if !squadExists {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Squad does not exist"})
-			return
-		}
-
 		// If both user and squad exist but there's no association
 		c.JSON(http.StatusNotFound, gin.H{"error": "User is not associated with this squad"})
 		return
 	}
 
-	// Update the status
-	_, err = tx.Exec(`
-		UPDATE user_squads 
-		SET status = $1
-		WHERE user_id = $2 AND squad_id = $3
-	`, req.Status, req.UserID, req.SquadID)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+	// Update the status through the membership workflow
+	if err := transitionSquadMembership(tx, req.UserID, req.SquadID, req.Status, adminID, req.Reason); err != nil {
+		respondSquadTransitionError(c, err)
 		return
 	}
 
@@ -349,7 +332,7 @@ func (h *AvatarHandler) VerifyUserSquad(c *gin.Context) {
 	}
 
 	// Get updated profile
-	profile, err := h.getUserProfile(req.UserID)
+	profile, err := h.getUserProfile(req.UserID, orgID)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"message": "Status updated successfully, but failed to fetch updated profile"})
 		return
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicorn_app_backend/imaging"
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAvatarUploadBytes is the largest picture accepted before resizing
+const maxAvatarUploadBytes = 10 << 20
+
+// avatarPresets are the built-in unicorn avatars, so kids can pick a picture
+// instead of uploading a photo. Colors are the coat, mane and horn.
+var avatarPresets = []struct {
+	ID    string
+	Name  string
+	Coat  string
+	Mane  string
+	Horn  string
+	Field string
+}{
+	{"rainbow", "Rainbow", "#ffffff", "#ff6fb5", "#ffd23f", "#bde0fe"},
+	{"lavender", "Lavender", "#f3e8ff", "#9b5de5", "#f8c537", "#e0c3fc"},
+	{"mint", "Mint", "#f0fff4", "#2ec4b6", "#ffbf69", "#cbf3f0"},
+	{"sunset", "Sunset", "#fff5eb", "#f15bb5", "#fee440", "#ffd6a5"},
+	{"sky", "Sky", "#f0f8ff", "#00bbf9", "#fee440", "#a0c4ff"},
+	{"midnight", "Midnight", "#e5e5ff", "#3a0ca3", "#f9c74f", "#4361ee"},
+}
+
+// unicornSVG draws a preset. Placeholders are the field, coat, mane and horn
+// colors.
+const unicornSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 256 256" width="256" height="256">
+<circle cx="128" cy="128" r="128" fill="%[1]s"/>
+<path d="M150 28 L166 92 L140 86 Z" fill="%[4]s" stroke="#00000022" stroke-width="2"/>
+<path d="M88 96 C70 120 72 170 92 206 L116 214 C100 176 98 132 112 104 Z" fill="%[3]s"/>
+<ellipse cx="138" cy="148" rx="58" ry="66" fill="%[2]s" stroke="#00000022" stroke-width="2"/>
+<ellipse cx="160" cy="196" rx="36" ry="26" fill="%[2]s" stroke="#00000022" stroke-width="2"/>
+<path d="M96 92 C112 70 150 70 164 90 C140 86 118 96 104 116 Z" fill="%[3]s"/>
+<circle cx="150" cy="138" r="9" fill="#2b2d42"/>
+<circle cx="153" cy="135" r="3" fill="#ffffff"/>
+<circle cx="176" cy="196" r="4" fill="#2b2d42"/>
+<circle cx="120" cy="170" r="10" fill="#ffafcc" opacity="0.6"/>
+</svg>`
+
+// avatarURL is where the user's profile picture is served from, nil when
+// they have none. Uploaded pictures carry their storage key's name, so a new
+// picture gets a new URL and clients can cache them for good.
+func avatarURL(userID int, preset, imageKey sql.NullString) *string {
+	var url string
+	switch {
+	case imageKey.Valid:
+		name := strings.TrimSuffix(path.Base(imageKey.String), path.Ext(imageKey.String))
+		url = fmt.Sprintf("/users/%d/avatar-image?v=%s", userID, name)
+	case preset.Valid:
+		url = "/avatars/presets/" + preset.String
+	default:
+		return nil
+	}
+	return &url
+}
+
+func isAvatarPreset(id string) bool {
+	for _, preset := range avatarPresets {
+		if preset.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAvatarPresets lists the built-in unicorn avatars
+func (h *AvatarHandler) GetAvatarPresets(c *gin.Context) {
+	presets := make([]models.AvatarPreset, 0, len(avatarPresets))
+	for _, preset := range avatarPresets {
+		presets = append(presets, models.AvatarPreset{
+			ID:   preset.ID,
+			Name: preset.Name,
+			URL:  "/avatars/presets/" + preset.ID,
+		})
+	}
+	c.JSON(http.StatusOK, presets)
+}
+
+// GetAvatarPresetImage serves a built-in unicorn avatar as SVG
+func (h *AvatarHandler) GetAvatarPresetImage(c *gin.Context) {
+	for _, preset := range avatarPresets {
+		if preset.ID == c.Param("id") {
+			c.Header("Cache-Control", "public, max-age=86400")
+			c.Data(http.StatusOK, "image/svg+xml", []byte(fmt.Sprintf(unicornSVG, preset.Field, preset.Coat, preset.Mane, preset.Horn)))
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Avatar not found"})
+}
+
+// SetAvatarImage sets the caller's profile picture: a multipart upload in
+// the file field, or {"preset": "rainbow"} to pick a built-in unicorn.
+// Uploads are cropped square, resized and stored without metadata.
+func (h *AvatarHandler) SetAvatarImage(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	var preset, imageKey sql.NullString
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		key, ok := h.storeAvatarUpload(c, userID)
+		if !ok {
+			return
+		}
+		imageKey = sql.NullString{String: key, Valid: true}
+	} else {
+		var req models.SetAvatarImageRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !isAvatarPreset(req.Preset) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown avatar preset"})
+			return
+		}
+		preset = sql.NullString{String: req.Preset, Valid: true}
+	}
+
+	if !h.replaceAvatarImage(c, userID, preset, imageKey) {
+		return
+	}
+
+	profile, err := h.getUserProfile(userID, orgID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Avatar image saved successfully, but failed to fetch updated profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteAvatarImage removes the caller's profile picture
+func (h *AvatarHandler) DeleteAvatarImage(c *gin.Context) {
+	if !h.replaceAvatarImage(c, c.GetInt("userID"), sql.NullString{}, sql.NullString{}) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Avatar image removed successfully"})
+}
+
+// storeAvatarUpload processes the uploaded picture and stores it. It writes
+// the error response and returns false when the upload is rejected.
+func (h *AvatarHandler) storeAvatarUpload(c *gin.Context, userID int) (string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAvatarUploadBytes+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Pictures can be at most %d bytes", maxAvatarUploadBytes)})
+			return "", false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A picture is required in the file field"})
+		return "", false
+	}
+	if header.Size > maxAvatarUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Pictures can be at most %d bytes", maxAvatarUploadBytes)})
+		return "", false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return "", false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return "", false
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := attachmentTypes[contentType]; !ok || !strings.HasPrefix(contentType, "image/") {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only JPEG, PNG, GIF and WebP pictures can be uploaded"})
+		return "", false
+	}
+
+	avatar, err := imaging.Avatar(data, contentType)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image dimensions are too large"})
+		return "", false
+	} else if err != nil {
+		log.Printf("Error processing avatar image: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image could not be processed"})
+		return "", false
+	}
+
+	base, err := randomStorageName()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store picture"})
+		return "", false
+	}
+	key := fmt.Sprintf("avatars/%d/%s%s", userID, base, attachmentTypes[avatar.ContentType])
+	err = h.store.Put(c.Request.Context(), key, bytes.NewReader(avatar.Data), int64(len(avatar.Data)), avatar.ContentType)
+	if err != nil {
+		log.Printf("Error storing avatar image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store picture"})
+		return "", false
+	}
+
+	return key, true
+}
+
+// replaceAvatarImage saves the user's new picture and deletes the uploaded
+// one it replaces. It writes the error response and returns false on
+// failure.
+func (h *AvatarHandler) replaceAvatarImage(c *gin.Context, userID int, preset, imageKey sql.NullString) bool {
+	var oldKey sql.NullString
+	err := h.db.QueryRow(`
+        UPDATE users u SET avatar_preset = $2, avatar_image_key = $3
+        FROM users old
+        WHERE u.id = $1 AND old.id = u.id
+        RETURNING old.avatar_image_key
+    `, userID, preset, imageKey).Scan(&oldKey)
+	if err != nil {
+		log.Printf("Error saving avatar image: %v", err)
+		if imageKey.Valid {
+			if err := h.store.Delete(c.Request.Context(), imageKey.String); err != nil {
+				log.Printf("Error deleting orphaned avatar image %s: %v", imageKey.String, err)
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save avatar image"})
+		return false
+	}
+
+	if oldKey.Valid && oldKey != imageKey {
+		if err := h.store.Delete(c.Request.Context(), oldKey.String); err != nil {
+			log.Printf("Error deleting old avatar image %s: %v", oldKey.String, err)
+		}
+	}
+	return true
+}
+
+// GetUserAvatarImage serves a user's uploaded profile picture to members of
+// the caller's organization
+func (h *AvatarHandler) GetUserAvatarImage(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var key sql.NullString
+	err = h.db.QueryRow(`
+        SELECT u.avatar_image_key
+        FROM users u
+        JOIN organization_members om ON om.user_id = u.id
+        WHERE u.id = $1 AND om.organization_id = $2
+    `, userID, c.GetInt("orgID")).Scan(&key)
+	if err == sql.ErrNoRows || (err == nil && !key.Valid) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Avatar image not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching avatar image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch avatar image"})
+		return
+	}
+
+	content, err := h.store.Open(c.Request.Context(), key.String)
+	if err != nil {
+		log.Printf("Error opening avatar image %s: %v", key.String, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch avatar image"})
+		return
+	}
+	defer content.Close()
+
+	contentType := "image/jpeg"
+	if strings.HasSuffix(key.String, ".png") {
+		contentType = "image/png"
+	}
+	// The URL changes with every new picture
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.DataFromReader(http.StatusOK, -1, contentType, content, nil)
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 	"unicorn_app_backend/models"
 
@@ -21,20 +23,30 @@ func NewChatboardHandler(db *sql.DB) *ChatboardHandler {
 }
 
 func (h *ChatboardHandler) CreateChatboard(c *gin.Context) {
-	// Get user ID from context
+	// Get user and organization from context
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
-	// Check if user has permission to create chatboards
-	var hasPermission bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 
-            FROM user_roles ur
-            JOIN roles r ON r.id = ur.role_id
-            WHERE ur.user_id = $1 
-            AND r.role IN ('Admin', 'Head Unicorn')
-        )
-    `, userID).Scan(&hasPermission)
+	var req models.CreateChatboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Policy != nil {
+		if msg := validateAccessRule(*req.Policy, 1); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+	accessIDs := chatboardAccessIDs(req.Access, req.Policy)
+
+	// Check if user has permission to create chatboards. Country admins can
+	// create boards restricted to the countries they manage.
+	hasPermission, err := hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn")
+	if err == nil && !hasPermission {
+		hasPermission, err = countryAdminCanGrant(h.db, userID, req.Access, req.Policy)
+	}
 
 	if err != nil {
 		log.Printf("Error checking permissions: %v", err)
@@ -43,13 +55,7 @@ func (h *ChatboardHandler) CreateChatboard(c *gin.Context) {
 	}
 
 	if !hasPermission {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and head unicorns can create chatboards"})
-		return
-	}
-
-	var req models.CreateChatboardRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins, head unicorns and country admins can create chatboards"})
 		return
 	}
 
@@ -61,64 +67,28 @@ func (h *ChatboardHandler) CreateChatboard(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	// Validate squad IDs
-	if len(req.Access.SquadIDs) > 0 {
-		var count int
-		err = tx.QueryRow(`
-            SELECT COUNT(*) FROM squads WHERE id = ANY($1)
-        `, pq.Array(req.Access.SquadIDs)).Scan(&count)
-		if err != nil {
-			log.Printf("Error validating squad IDs: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate squad IDs"})
-			return
-		}
-		if count != len(req.Access.SquadIDs) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more squad IDs are invalid"})
-			return
-		}
-	}
-
-	// Validate role IDs
-	if len(req.Access.RoleIDs) > 0 {
-		var count int
-		err = tx.QueryRow(`
-            SELECT COUNT(*) FROM roles WHERE id = ANY($1)
-        `, pq.Array(req.Access.RoleIDs)).Scan(&count)
-		if err != nil {
-			log.Printf("Error validating role IDs: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate role IDs"})
-			return
-		}
-		if count != len(req.Access.RoleIDs) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more role IDs are invalid"})
-			return
-		}
+	if msg, err := validateChatboardAccess(tx, orgID, accessIDs); err != nil {
+		log.Printf("Error validating chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate chatboard access"})
+		return
+	} else if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
 	}
 
-	// Validate country IDs
-	if len(req.Access.CountryIDs) > 0 {
-		var count int
-		err = tx.QueryRow(`
-            SELECT COUNT(*) FROM countries WHERE id = ANY($1)
-        `, pq.Array(req.Access.CountryIDs)).Scan(&count)
-		if err != nil {
-			log.Printf("Error validating country IDs: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate country IDs"})
-			return
-		}
-		if count != len(req.Access.CountryIDs) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more country IDs are invalid"})
-			return
-		}
+	policy, err := accessPolicyJSON(req.Policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access policy"})
+		return
 	}
 
 	// Create chatboard
 	var chatboardID int
 	err = tx.QueryRow(`
-        INSERT INTO chatboards (title, description)
-        VALUES ($1, $2)
+        INSERT INTO chatboards (title, description, organization_id, creator_id, access_policy)
+        VALUES ($1, $2, $3, $4, $5)
         RETURNING id`,
-		req.Title, req.Description,
+		req.Title, req.Description, orgID, userID, policy,
 	).Scan(&chatboardID)
 
 	if err != nil {
@@ -127,43 +97,10 @@ func (h *ChatboardHandler) CreateChatboard(c *gin.Context) {
 		return
 	}
 
-	// Add squad access
-	for _, squadID := range req.Access.SquadIDs {
-		_, err = tx.Exec(`
-            INSERT INTO chatboard_squads (chatboard_id, squad_id)
-            VALUES ($1, $2)`,
-			chatboardID, squadID)
-		if err != nil {
-			log.Printf("Error adding squad access: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add squad access"})
-			return
-		}
-	}
-
-	// Add role access
-	for _, roleID := range req.Access.RoleIDs {
-		_, err = tx.Exec(`
-            INSERT INTO chatboard_roles (chatboard_id, role_id)
-            VALUES ($1, $2)`,
-			chatboardID, roleID)
-		if err != nil {
-			log.Printf("Error adding role access: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add role access"})
-			return
-		}
-	}
-
-	// Add country access
-	for _, countryID := range req.Access.CountryIDs {
-		_, err = tx.Exec(`
-            INSERT INTO chatboard_countries (chatboard_id, country_id)
-            VALUES ($1, $2)`,
-			chatboardID, countryID)
-		if err != nil {
-			log.Printf("Error adding country access: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add country access"})
-			return
-		}
+	if err = replaceChatboardAccess(tx, chatboardID, req.Access); err != nil {
+		log.Printf("Error adding chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add chatboard access"})
+		return
 	}
 
 	// Commit transaction
@@ -184,24 +121,208 @@ func (h *ChatboardHandler) CreateChatboard(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// validateChatboardAccess checks that every squad, role and country of the
+// access lists exists in the organization. It returns a message for the
+// client when an ID is invalid.
+func validateChatboardAccess(tx *sql.Tx, orgID int, access models.ChatboardAccess) (string, error) {
+	// Roles are shared by all organizations
+	checks := []struct {
+		ids     []int
+		query   string
+		args    []interface{}
+		message string
+	}{
+		{access.SquadIDs, "SELECT COUNT(*) FROM squads WHERE id = ANY($1) AND organization_id = $2", []interface{}{orgID}, "One or more squad IDs are invalid"},
+		{access.RoleIDs, "SELECT COUNT(*) FROM roles WHERE id = ANY($1)", nil, "One or more role IDs are invalid"},
+		{access.CountryIDs, "SELECT COUNT(*) FROM countries WHERE id = ANY($1) AND organization_id = $2", []interface{}{orgID}, "One or more country IDs are invalid"},
+	}
+
+	for _, check := range checks {
+		if len(check.ids) == 0 {
+			continue
+		}
+		args := append([]interface{}{pq.Array(check.ids)}, check.args...)
+		var count int
+		if err := tx.QueryRow(check.query, args...).Scan(&count); err != nil {
+			return "", err
+		}
+		if count != len(check.ids) {
+			return check.message, nil
+		}
+	}
+
+	return "", nil
+}
+
+// countryAdminCanGrant reports whether a country admin without a global role
+// may give a chatboard these access lists and policy. The lists are OR'd, so
+// they need at least one country, all administered by the user, squads only
+// from those countries and no roles, whose holders would get access in every
+// country. A policy must require one of the user's countries for every match,
+// since negations and alternatives would reach members elsewhere.
+func countryAdminCanGrant(db queryRower, userID int, access models.ChatboardAccess, policy *models.AccessRule) (bool, error) {
+	if len(access.RoleIDs) > 0 {
+		return false, nil
+	}
+	allowed, err := isCountryAdminOfAll(db, userID, access.CountryIDs)
+	if err == nil && allowed {
+		allowed, err = isSquadCountryAdminOfAll(db, userID, access.SquadIDs)
+	}
+	if err != nil || !allowed || policy == nil {
+		return allowed, err
+	}
+
+	for _, countryIDs := range requiredCountryClauses(*policy) {
+		allowed, err = isCountryAdminOfAll(db, userID, countryIDs)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// requiredCountryClauses returns the country conditions every match of the
+// rule must meet: the rule's own and those of its "all" children. Conditions
+// under "any" and "not" don't restrict who matches.
+func requiredCountryClauses(rule models.AccessRule) [][]int {
+	var clauses [][]int
+	if len(rule.CountryIDs) > 0 {
+		clauses = append(clauses, rule.CountryIDs)
+	}
+	for _, child := range rule.All {
+		clauses = append(clauses, requiredCountryClauses(child)...)
+	}
+	return clauses
+}
+
+// loadChatboardAccess returns the chatboard's current access lists and
+// policy
+func loadChatboardAccess(db queryRower, chatboardID int) (models.ChatboardAccess, *models.AccessRule, error) {
+	var squadIDs, roleIDs, countryIDs pq.Int64Array
+	var policy []byte
+	err := db.QueryRow(`
+        SELECT
+            ARRAY(SELECT squad_id FROM chatboard_squads WHERE chatboard_id = cb.id),
+            ARRAY(SELECT role_id FROM chatboard_roles WHERE chatboard_id = cb.id),
+            ARRAY(SELECT country_id FROM chatboard_countries WHERE chatboard_id = cb.id),
+            cb.access_policy
+        FROM chatboards cb
+        WHERE cb.id = $1
+    `, chatboardID).Scan(&squadIDs, &roleIDs, &countryIDs, &policy)
+	if err != nil {
+		return models.ChatboardAccess{}, nil, err
+	}
+
+	access := models.ChatboardAccess{
+		SquadIDs:   toInts(squadIDs),
+		RoleIDs:    toInts(roleIDs),
+		CountryIDs: toInts(countryIDs),
+	}
+	if policy == nil {
+		return access, nil, nil
+	}
+	var rule models.AccessRule
+	if err := json.Unmarshal(policy, &rule); err != nil {
+		return access, nil, fmt.Errorf("invalid access policy of chatboard %d: %w", chatboardID, err)
+	}
+	return access, &rule, nil
+}
+
+// chatboardAccessIDs merges the access lists with every ID referenced by the
+// access policy, without duplicates.
+func chatboardAccessIDs(access models.ChatboardAccess, policy *models.AccessRule) models.ChatboardAccess {
+	ids := access
+	if policy != nil {
+		policyIDs := accessRuleIDs(*policy)
+		ids.SquadIDs = append(append([]int{}, ids.SquadIDs...), policyIDs.SquadIDs...)
+		ids.RoleIDs = append(append([]int{}, ids.RoleIDs...), policyIDs.RoleIDs...)
+		ids.CountryIDs = append(append([]int{}, ids.CountryIDs...), policyIDs.CountryIDs...)
+	}
+
+	return models.ChatboardAccess{
+		SquadIDs:   uniqueInts(ids.SquadIDs),
+		RoleIDs:    uniqueInts(ids.RoleIDs),
+		CountryIDs: uniqueInts(ids.CountryIDs),
+	}
+}
+
+// accessPolicyJSON encodes the policy for the access_policy column. A nil
+// policy is stored as NULL.
+func accessPolicyJSON(policy *models.AccessRule) (interface{}, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// replaceChatboardAccess replaces the chatboard's squad, role and country
+// access lists
+func replaceChatboardAccess(tx *sql.Tx, chatboardID int, access models.ChatboardAccess) error {
+	for _, table := range []string{"chatboard_squads", "chatboard_roles", "chatboard_countries"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE chatboard_id = $1", chatboardID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO chatboard_squads (chatboard_id, squad_id)
+        SELECT $1, UNNEST($2::INTEGER[])
+        ON CONFLICT DO NOTHING`,
+		chatboardID, pq.Array(access.SquadIDs)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO chatboard_roles (chatboard_id, role_id)
+        SELECT $1, UNNEST($2::INTEGER[])
+        ON CONFLICT DO NOTHING`,
+		chatboardID, pq.Array(access.RoleIDs)); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+        INSERT INTO chatboard_countries (chatboard_id, country_id)
+        SELECT $1, UNNEST($2::INTEGER[])
+        ON CONFLICT DO NOTHING`,
+		chatboardID, pq.Array(access.CountryIDs))
+	return err
+}
+
 func (h *ChatboardHandler) getChatboardInfo(chatboardID int) (models.ChatboardResponse, error) {
 	var response models.ChatboardResponse
 	var createdAt sql.NullTime
 
+	var creatorID sql.NullInt64
+	var policy []byte
 	err := h.db.QueryRow(`
-        SELECT id, title, description, created_at
+        SELECT id, title, description, created_at, creator_id, archived_at IS NOT NULL, slow_mode_seconds, access_policy
         FROM chatboards
         WHERE id = $1`,
 		chatboardID,
-	).Scan(&response.ID, &response.Title, &response.Description, &createdAt)
+	).Scan(&response.ID, &response.Title, &response.Description, &createdAt, &creatorID, &response.Archived, &response.SlowMode, &policy)
 
 	if err != nil {
 		return response, err
 	}
 
+	if policy != nil {
+		response.Policy = &models.AccessRule{}
+		if err := json.Unmarshal(policy, response.Policy); err != nil {
+			return response, err
+		}
+	}
+
 	if createdAt.Valid {
 		response.CreatedAt = createdAt.Time.Format("2006-01-02")
 	}
+	if creatorID.Valid {
+		id := int(creatorID.Int64)
+		response.CreatorID = &id
+	}
 
 	// Get squads
 	rows, err := h.db.Query(`
@@ -268,29 +389,44 @@ func (h *ChatboardHandler) getChatboardInfo(chatboardID int) (models.ChatboardRe
 
 func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 	filterRole := c.Query("filter_role")
 	filterSquad := c.Query("filter_squad")
 	filterCountry := c.Query("filter_country")
 
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	// Access policies are evaluated in Go, so find the accessible boards first
+	accessibleIDs, err := accessibleChatboardIDs(h.db, userID, orgID)
+	if err != nil {
+		log.Printf("Error evaluating chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboards"})
+		return
+	}
+
 	query := `
-        WITH user_access AS (
-            -- Get user's roles
-            SELECT DISTINCT r.id as role_id, r.role as role_name
-            FROM user_roles ur
-            JOIN roles r ON r.id = ur.role_id
-            WHERE ur.user_id = $1
-            UNION
-            -- Get user's squad roles
-            SELECT DISTINCT r.id as role_id, r.role as role_name
-            FROM user_squad_roles usr
-            JOIN roles r ON r.id = usr.role_id
-            WHERE usr.user_id = $1
-        )
         SELECT DISTINCT 
             cb.id,
             cb.title,
             cb.description,
             cb.created_at,
+            cb.archived_at IS NOT NULL as archived,
+            cb.slow_mode_seconds,
+            (
+                SELECT COUNT(*) FROM posts p
+                WHERE p.chatboard_id = cb.id
+                AND p.user_id <> $4
+                AND p.deleted_at IS NULL
+                AND p.held_at IS NULL
+                AND p.status = 'published'
+                AND p.created_at > COALESCE(
+                    (SELECT last_read_at FROM chatboard_reads WHERE user_id = $4 AND chatboard_id = cb.id),
+                    '-infinity'
+                )
+            ) as unread_count,
             COALESCE(
                 ARRAY_AGG(DISTINCT s.name) FILTER (WHERE s.name IS NOT NULL),
                 ARRAY[]::VARCHAR[]
@@ -310,34 +446,13 @@ func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
         LEFT JOIN roles r ON cbr.role_id = r.id
         LEFT JOIN chatboard_countries cbc ON cb.id = cbc.chatboard_id
         LEFT JOIN countries co ON cbc.country_id = co.id
-        LEFT JOIN user_squads us ON us.squad_id = s.id AND us.user_id = $1
-        LEFT JOIN user_countries uc ON uc.country_id = co.id AND uc.user_id = $1
-        WHERE (
-            -- User has access through roles
-            EXISTS (
-                SELECT 1 FROM chatboard_roles cr
-                JOIN user_access ua ON ua.role_id = cr.role_id
-                WHERE cr.chatboard_id = cb.id
-            )
-            OR
-            -- User has access through squads
-            EXISTS (
-                SELECT 1 FROM chatboard_squads cs
-                JOIN user_squads us ON us.squad_id = cs.squad_id
-                WHERE cs.chatboard_id = cb.id AND us.user_id = $1
-            )
-            OR
-            -- User has access through countries
-            EXISTS (
-                SELECT 1 FROM chatboard_countries cc
-                JOIN user_countries uc ON uc.country_id = cc.country_id
-                WHERE cc.chatboard_id = cb.id AND uc.user_id = $1
-            )
-        )
+        WHERE cb.organization_id = $1
+        AND (cb.archived_at IS NULL OR $2)
+        AND cb.id = ANY($3)
     `
 
-	params := []interface{}{userID}
-	paramCount := 1
+	params := []interface{}{orgID, c.Query("include_archived") == "true", pq.Array(accessibleIDs), userID}
+	paramCount := 4
 
 	// Add filters if provided
 	if filterRole != "" {
@@ -358,7 +473,11 @@ func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
 		params = append(params, filterCountry)
 	}
 
-	query += " GROUP BY cb.id, cb.title, cb.description, cb.created_at ORDER BY cb.created_at DESC"
+	keyset, params := page.keyset("cb.created_at", "cb.id", false, params)
+	query += keyset
+	query += " GROUP BY cb.id, cb.title, cb.description, cb.created_at, cb.archived_at, cb.slow_mode_seconds ORDER BY cb.created_at DESC, cb.id DESC"
+	limit, params := page.limit(params)
+	query += limit
 
 	rows, err := h.db.Query(query, params...)
 	if err != nil {
@@ -368,7 +487,8 @@ func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var chatboards []models.ChatboardResponse
+	chatboards := make([]models.ChatboardResponse, 0)
+	var cursors []pageCursor
 	for rows.Next() {
 		var cb models.ChatboardResponse
 		var createdAt sql.NullTime
@@ -379,6 +499,9 @@ func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
 			&cb.Title,
 			&cb.Description,
 			&createdAt,
+			&cb.Archived,
+			&cb.SlowMode,
+			&cb.UnreadCount,
 			pq.Array(&squadNames),
 			pq.Array(&roleNames),
 			pq.Array(&countryNames),
@@ -399,6 +522,7 @@ func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
 		}
 
 		chatboards = append(chatboards, cb)
+		cursors = append(cursors, pageCursor{CreatedAt: createdAt.Time, ID: cb.ID})
 	}
 
 	if err = rows.Err(); err != nil {
@@ -407,7 +531,8 @@ func (h *ChatboardHandler) GetChatboards(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, chatboards)
+	chatboards, next := trimPage(chatboards, page.Limit, func(i int) pageCursor { return cursors[i] })
+	c.JSON(http.StatusOK, pageResponse(chatboards, next))
 }
 
 func (h *ChatboardHandler) GetPendingUsers(c *gin.Context) {
@@ -420,17 +545,10 @@ func (h *ChatboardHandler) GetPendingUsers(c *gin.Context) {
 
 	// Get the requesting user's ID
 	adminID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
 	// Check if the user has permission (must be Admin or Head Unicorn)
-	var hasPermission bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 FROM user_roles ur
-            JOIN roles r ON r.id = ur.role_id
-            WHERE ur.user_id = $1 
-            AND r.role IN ('Admin', 'Head Unicorn')
-        )
-    `, adminID).Scan(&hasPermission)
+	hasPermission, err := hasGlobalRole(h.db, adminID, orgID, "Admin", "Head Unicorn")
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
@@ -447,7 +565,8 @@ func (h *ChatboardHandler) GetPendingUsers(c *gin.Context) {
         WITH chatboard_squad_ids AS (
             SELECT DISTINCT cs.squad_id
             FROM chatboard_squads cs
-            WHERE cs.chatboard_id = $1
+            JOIN chatboards cb ON cb.id = cs.chatboard_id
+            WHERE cs.chatboard_id = $1 AND cb.organization_id = $2
         )
         SELECT 
             u.id,
@@ -466,7 +585,7 @@ func (h *ChatboardHandler) GetPendingUsers(c *gin.Context) {
         WHERE s.id IN (SELECT squad_id FROM chatboard_squad_ids)
         AND us.status = 'Pending'
         ORDER BY u.first_name, u.last_name, s.name
-    `, chatboardID)
+    `, chatboardID, orgID)
 
 	if err != nil {
 		log.Printf("Error fetching pending users: %v", err)
@@ -499,52 +618,122 @@ func (h *ChatboardHandler) GetPendingUsers(c *gin.Context) {
 		pendingUsers = make([]models.PendingUserResponse, 0)
 	}
 
+	if err := h.previewApprovals(orgID, pendingUsers); err != nil {
+		log.Printf("Error previewing approvals: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending users"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"pending_users": pendingUsers,
 		"count":         len(pendingUsers),
 	})
 }
 
-func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
+// MarkChatboardRead advances the user's read marker of the chatboard to now.
+// Markers never move backwards.
+func (h *ChatboardHandler) MarkChatboardRead(c *gin.Context) {
 	userID := c.GetInt("userID")
-	chatboardID := c.Param("id")
 
-	// First check if the user has access to this chatboard through any means (roles, squads, or countries)
-	var hasAccess bool
-	err := h.db.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 
-			FROM chatboards cb
-			WHERE cb.id = $2
-			AND (
-				-- Check squad access
-				EXISTS (
-					SELECT 1 FROM chatboard_squads cs
-					JOIN user_squads us ON us.squad_id = cs.squad_id
-					WHERE cs.chatboard_id = cb.id 
-					AND us.user_id = $1
-					AND us.status = 'approved'
-				)
-				OR
-				-- Check role access
-				EXISTS (
-					SELECT 1 FROM chatboard_roles cr
-					JOIN user_roles ur ON ur.role_id = cr.role_id
-					WHERE cr.chatboard_id = cb.id 
-					AND ur.user_id = $1
-				)
-				OR
-				-- Check country access
-				EXISTS (
-					SELECT 1 FROM chatboard_countries cc
-					JOIN user_countries uc ON uc.country_id = cc.country_id
-					WHERE cc.chatboard_id = cb.id 
-					AND uc.user_id = $1
-				)
-			)
-		)
-	`, userID, chatboardID).Scan(&hasAccess)
+	chatboardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+		return
+	}
 
+	hasAccess, err := canAccessChatboard(h.db, userID, c.GetInt("orgID"), chatboardID)
+	if err != nil {
+		log.Printf("Error checking chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	var lastReadAt time.Time
+	err = h.db.QueryRow(`
+        INSERT INTO chatboard_reads (user_id, chatboard_id, last_read_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (user_id, chatboard_id)
+        DO UPDATE SET last_read_at = GREATEST(chatboard_reads.last_read_at, EXCLUDED.last_read_at)
+        RETURNING last_read_at`,
+		userID, chatboardID,
+	).Scan(&lastReadAt)
+	if err != nil {
+		log.Printf("Error updating read marker: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark chatboard as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chatboard_id": chatboardID,
+		"last_read_at": lastReadAt,
+	})
+}
+
+// previewApprovals fills in the chatboards each pending request would unlock
+// if it was approved.
+func (h *ChatboardHandler) previewApprovals(orgID int, pendingUsers []models.PendingUserResponse) error {
+	if len(pendingUsers) == 0 {
+		return nil
+	}
+
+	rules, err := loadChatboardRules(h.db, orgID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.db.Query("SELECT id, title FROM chatboards WHERE organization_id = $1", orgID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	titles := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return err
+		}
+		titles[id] = title
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	subjects := make(map[int]*accessSubject)
+	for i := range pendingUsers {
+		user := &pendingUsers[i]
+		subject, ok := subjects[user.UserID]
+		if !ok {
+			if subject, err = loadAccessSubject(h.db, user.UserID, orgID); err != nil {
+				return err
+			}
+			subjects[user.UserID] = subject
+		}
+
+		user.WouldUnlock = make([]models.ChatboardSummary, 0)
+		for _, id := range chatboardsUnlockedByApproval(rules, subject, user.SquadID) {
+			user.WouldUnlock = append(user.WouldUnlock, models.ChatboardSummary{ID: id, Title: titles[id]})
+		}
+	}
+
+	return nil
+}
+
+func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
+	userID := c.GetInt("userID")
+	chatboardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+		return
+	}
+
+	// First check if the user has access to this chatboard
+	hasAccess, err := canAccessChatboard(h.db, userID, c.GetInt("orgID"), chatboardID)
 	if err != nil {
 		log.Printf("Error checking chatboard access: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
@@ -558,19 +747,23 @@ func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
 
 	// Get chatboard details
 	var chatboard struct {
-		ID          int            `json:"id"`
-		Title       string         `json:"title"`
-		Description string         `json:"description"`
-		CreatedAt   time.Time      `json:"created_at"`
-		UpdatedAt   sql.NullTime   `json:"updated_at,omitempty"`
-		Squads      []string       `json:"squads"`
-		Roles       []string       `json:"roles"`
-		Countries   []string       `json:"countries"`
-		CreatorID   sql.NullInt64  `json:"creator_id,omitempty"`
-		CreatorName sql.NullString `json:"creator_name,omitempty"`
+		ID          int                `json:"id"`
+		Title       string             `json:"title"`
+		Description string             `json:"description"`
+		CreatedAt   time.Time          `json:"created_at"`
+		UpdatedAt   sql.NullTime       `json:"updated_at,omitempty"`
+		ArchivedAt  sql.NullTime       `json:"archived_at,omitempty"`
+		SlowMode    int                `json:"slow_mode_seconds"`
+		Squads      []string           `json:"squads"`
+		Roles       []string           `json:"roles"`
+		Countries   []string           `json:"countries"`
+		CreatorID   sql.NullInt64      `json:"creator_id,omitempty"`
+		CreatorName sql.NullString     `json:"creator_name,omitempty"`
+		Policy      *models.AccessRule `json:"policy,omitempty"`
 	}
 
 	// Get basic chatboard info
+	var policy []byte
 	err = h.db.QueryRow(`
 		SELECT 
 			c.id,
@@ -578,8 +771,11 @@ func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
 			c.description,
 			c.created_at,
 			c.updated_at,
+			c.archived_at,
+			c.slow_mode_seconds,
 			c.creator_id,
-			CONCAT(u.first_name, ' ', u.last_name) as creator_name
+			CONCAT(u.first_name, ' ', u.last_name) as creator_name,
+			c.access_policy
 		FROM chatboards c
 		LEFT JOIN users u ON c.creator_id = u.id
 		WHERE c.id = $1
@@ -589,8 +785,11 @@ func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
 		&chatboard.Description,
 		&chatboard.CreatedAt,
 		&chatboard.UpdatedAt,
+		&chatboard.ArchivedAt,
+		&chatboard.SlowMode,
 		&chatboard.CreatorID,
 		&chatboard.CreatorName,
+		&policy,
 	)
 
 	if err == sql.ErrNoRows {
@@ -602,6 +801,13 @@ func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
 		return
 	}
 
+	if policy != nil {
+		chatboard.Policy = &models.AccessRule{}
+		if err := json.Unmarshal(policy, chatboard.Policy); err != nil {
+			log.Printf("Error decoding access policy: %v", err)
+		}
+	}
+
 	// Get squads
 	rows, err := h.db.Query(`
 		SELECT s.name
@@ -672,3 +878,250 @@ func (h *ChatboardHandler) GetChatboardByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, chatboard)
 }
+
+// authorizeChatboardOwner parses the chatboard ID from the path and checks that
+// the caller is an organization Admin or the board's creator. It writes the
+// error response and returns false when the caller may not manage the board.
+func (h *ChatboardHandler) authorizeChatboardOwner(c *gin.Context) (int, bool) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	chatboardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+		return 0, false
+	}
+
+	var creatorID sql.NullInt64
+	err = h.db.QueryRow(`
+        SELECT creator_id FROM chatboards
+        WHERE id = $1 AND organization_id = $2`,
+		chatboardID, orgID,
+	).Scan(&creatorID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chatboard not found"})
+		return 0, false
+	} else if err != nil {
+		log.Printf("Error fetching chatboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard"})
+		return 0, false
+	}
+
+	if creatorID.Valid && int(creatorID.Int64) == userID {
+		return chatboardID, true
+	}
+
+	isAdmin, err := hasGlobalRole(h.db, userID, orgID, "Admin")
+	if err != nil {
+		log.Printf("Error checking permissions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return 0, false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and the chatboard's owner can manage this chatboard"})
+		return 0, false
+	}
+
+	return chatboardID, true
+}
+
+// isChatboardArchived reports whether the chatboard has been archived.
+// Archived chatboards are read-only.
+func isChatboardArchived(db queryRower, chatboardID int) (bool, error) {
+	var archived bool
+	err := db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM chatboards WHERE id = $1 AND archived_at IS NOT NULL
+        )`, chatboardID).Scan(&archived)
+	return archived, err
+}
+
+func (h *ChatboardHandler) UpdateChatboard(c *gin.Context) {
+	chatboardID, ok := h.authorizeChatboardOwner(c)
+	if !ok {
+		return
+	}
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	var req models.UpdateChatboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Title != nil && *req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+		return
+	}
+
+	if req.Policy != nil && req.ClearPolicy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot set and clear the access policy at once"})
+		return
+	}
+	if req.Policy != nil {
+		if msg := validateAccessRule(*req.Policy, 1); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+	var access models.ChatboardAccess
+	if req.Access != nil {
+		access = *req.Access
+	}
+	accessIDs := chatboardAccessIDs(access, req.Policy)
+
+	archived, err := isChatboardArchived(h.db, chatboardID)
+	if err != nil {
+		log.Printf("Error checking chatboard status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chatboard"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Archived chatboards cannot be changed"})
+		return
+	}
+
+	// Owners without a global role may only hand the board to countries they
+	// administer, the same rule as for creating it. The board is checked as it
+	// will be after the update: clearing the policy falls back to the access
+	// lists, so that is checked too.
+	if req.Access != nil || req.Policy != nil || req.ClearPolicy {
+		hasPermission, err := hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn")
+		if err == nil && !hasPermission {
+			var current models.ChatboardAccess
+			var policy *models.AccessRule
+			current, policy, err = loadChatboardAccess(h.db, chatboardID)
+			if req.Access != nil {
+				current = *req.Access
+			}
+			if req.Policy != nil || req.ClearPolicy {
+				policy = req.Policy
+			}
+			if err == nil {
+				hasPermission, err = countryAdminCanGrant(h.db, userID, current, policy)
+			}
+		}
+		if err != nil {
+			log.Printf("Error checking permissions: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !hasPermission {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only grant access to countries you administer"})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if msg, err := validateChatboardAccess(tx, orgID, accessIDs); err != nil {
+		log.Printf("Error validating chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate chatboard access"})
+		return
+	} else if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	policy, err := accessPolicyJSON(req.Policy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access policy"})
+		return
+	}
+
+	_, err = tx.Exec(`
+        UPDATE chatboards
+        SET title = COALESCE($1, title),
+            description = COALESCE($2, description),
+            access_policy = CASE WHEN $3 THEN $4::JSONB ELSE access_policy END,
+            updated_at = NOW()
+        WHERE id = $5`,
+		req.Title, req.Description, req.Policy != nil || req.ClearPolicy, policy, chatboardID,
+	)
+	if err != nil {
+		log.Printf("Error updating chatboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chatboard"})
+		return
+	}
+
+	if req.Access != nil {
+		if err = replaceChatboardAccess(tx, chatboardID, *req.Access); err != nil {
+			log.Printf("Error replacing chatboard access: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chatboard access"})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	response, err := h.getChatboardInfo(chatboardID)
+	if err != nil {
+		log.Printf("Error fetching chatboard info: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard info"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ChatboardHandler) ArchiveChatboard(c *gin.Context) {
+	h.setChatboardArchived(c, true)
+}
+
+func (h *ChatboardHandler) UnarchiveChatboard(c *gin.Context) {
+	h.setChatboardArchived(c, false)
+}
+
+func (h *ChatboardHandler) setChatboardArchived(c *gin.Context, archive bool) {
+	chatboardID, ok := h.authorizeChatboardOwner(c)
+	if !ok {
+		return
+	}
+
+	_, err := h.db.Exec(`
+        UPDATE chatboards
+        SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, NOW()) ELSE NULL END,
+            updated_at = NOW()
+        WHERE id = $2`,
+		archive, chatboardID,
+	)
+	if err != nil {
+		log.Printf("Error changing chatboard archive state: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chatboard"})
+		return
+	}
+
+	response, err := h.getChatboardInfo(chatboardID)
+	if err != nil {
+		log.Printf("Error fetching chatboard info: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard info"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ChatboardHandler) DeleteChatboard(c *gin.Context) {
+	chatboardID, ok := h.authorizeChatboardOwner(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM chatboards WHERE id = $1", chatboardID); err != nil {
+		log.Printf("Error deleting chatboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chatboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chatboard deleted successfully"})
+}
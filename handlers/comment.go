@@ -2,35 +2,178 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
+// maxCommentDepth is how deeply replies can nest below a comment on the post
+const maxCommentDepth = 3
+
+// commentSelect selects comments in the shape of models.CommentResponse. The
+// organization ID is $1, used to pick the author's role.
+const commentSelect = `
+        SELECT
+            c.id,
+            c.post_id,
+            c.parent_comment_id,
+            c.depth,
+            c.user_id,
+            c.comment,
+            c.created_at,
+            c.edited_at,
+            c.deleted_at IS NOT NULL,
+            COALESCE(u.username, ''),
+            COALESCE((
+                SELECT r.role
+                FROM user_roles ur
+                JOIN roles r ON r.id = ur.role_id
+                WHERE ur.user_id = u.id AND ur.organization_id = $1
+                ORDER BY r.id
+                LIMIT 1
+            ), ''),
+            (
+                SELECT COUNT(*) FROM comments rc
+                WHERE rc.parent_comment_id = c.id AND rc.deleted_at IS NULL AND rc.held_at IS NULL
+            ),
+            u.avatar_preset,
+            u.avatar_image_key,
+            c.held_at IS NOT NULL
+        FROM comments c
+        JOIN users u ON u.id = c.user_id`
+
 type CommentHandler struct {
-	db *sql.DB
+	db         *sql.DB
+	editWindow time.Duration
 }
 
 func NewCommentHandler(db *sql.DB) *CommentHandler {
-	return &CommentHandler{db: db}
+	return &CommentHandler{db: db, editWindow: postEditWindow()}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanComment reads a row selected with commentSelect. Removed comments keep
+// their place in threads without their text.
+func scanComment(row rowScanner) (models.CommentResponse, error) {
+	var comment models.CommentResponse
+	var parentID sql.NullInt64
+	var editedAt sql.NullTime
+	var avatarPreset, avatarImageKey sql.NullString
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&parentID,
+		&comment.Depth,
+		&comment.UserID,
+		&comment.Comment,
+		&comment.CreatedAt,
+		&editedAt,
+		&comment.Removed,
+		&comment.Author,
+		&comment.UserRole,
+		&comment.ReplyCount,
+		&avatarPreset,
+		&avatarImageKey,
+		&comment.Held,
+	)
+	if err != nil {
+		return comment, err
+	}
+
+	comment.AuthorAvatarURL = avatarURL(comment.UserID, avatarPreset, avatarImageKey)
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		comment.ParentCommentID = &id
+	}
+	if editedAt.Valid {
+		comment.Edited = true
+		comment.EditedAt = &editedAt.Time
+	}
+	if comment.Removed {
+		comment.Comment = ""
+	}
+
+	return comment, nil
+}
+
+// getComment fetches a comment with its reactions as seen by the user
+func (h *CommentHandler) getComment(userID, orgID, commentID int) (models.CommentResponse, error) {
+	comment, err := scanComment(h.db.QueryRow(commentSelect+" WHERE c.id = $2", orgID, commentID))
+	if err != nil {
+		return comment, err
+	}
+
+	reactions, err := loadReactions(h.db, commentTarget, []int{commentID}, userID)
+	if err != nil {
+		return comment, err
+	}
+	comment.Reactions = reactions[commentID]
+
+	mentions, err := loadMentions(h.db, commentTarget, []int{commentID})
+	if err != nil {
+		return comment, err
+	}
+	comment.Mentions = mentions[commentID]
+	if comment.Removed {
+		comment.Mentions = []models.Mention{}
+	}
+
+	return comment, nil
+}
+
+// saveMentions stores the @mentions in a comment and notifies the newly
+// mentioned users, as part of the transaction. Mentions in held comments
+// notify once a moderator approves the comment. It writes the error response
+// and returns false on failure.
+func (h *CommentHandler) saveMentions(c *gin.Context, tx mentionStore, chatboardID, postID, commentID int, text string, held bool) bool {
+	userID := c.GetInt("userID")
+
+	mentioned, err := saveMentions(tx, c.GetInt("orgID"), chatboardID, userID, commentTarget, commentID, text)
+	if err == nil && !held {
+		err = notifyMentions(tx, c.GetInt("orgID"), mentioned, userID, "a comment", gin.H{
+			"chatboard_id": chatboardID,
+			"post_id":      postID,
+			"comment_id":   commentID,
+		})
+	}
+	if err != nil {
+		log.Printf("Error saving comment mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mentions"})
+		return false
+	}
+	return true
 }
 
 func (h *CommentHandler) CreateComment(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 	var req models.CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// First get the chatboard ID for this post
+	// First get the chatboard ID for this post. Removed posts take no comments.
 	var chatboardID int
 	err := h.db.QueryRow(`
-        SELECT chatboard_id 
-        FROM posts 
-        WHERE id = $1
-    `, req.PostID).Scan(&chatboardID)
+        SELECT p.chatboard_id
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+        AND p.deleted_at IS NULL AND p.held_at IS NULL AND p.status = 'published'
+    `, req.PostID, orgID).Scan(&chatboardID)
 
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
@@ -40,68 +183,127 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
-	// Get user's role
-	var userRole string
-	err = h.db.QueryRow(`
-        SELECT r.role
-        FROM user_roles ur
-        JOIN roles r ON r.id = ur.role_id
-        WHERE ur.user_id = $1
-        LIMIT 1
-    `, userID).Scan(&userRole)
+	if !ensureNotRestricted(c, h.db, chatboardID) {
+		return
+	}
 
-	if err != nil && err != sql.ErrNoRows {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user role"})
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
 		return
 	}
 
-	// Create the comment
+	archived, err := isChatboardArchived(h.db, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) || !ensureSlowMode(c, h.db, chatboardID) {
+		return
+	}
+
+	// Replies go one level below their parent, which must be on the same post
+	depth := 0
+	if req.ParentCommentID != nil {
+		var parentDepth int
+		err = h.db.QueryRow(`
+            SELECT depth FROM comments
+            WHERE id = $1 AND post_id = $2 AND deleted_at IS NULL AND held_at IS NULL
+        `, *req.ParentCommentID, req.PostID).Scan(&parentDepth)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch parent comment"})
+			return
+		}
+
+		depth = parentDepth + 1
+		if depth > maxCommentDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Replies cannot be nested more than %d levels deep", maxCommentDepth)})
+			return
+		}
+	}
+
+	heldReasons, ok := screenContent(c, h.db, chatboardID, req.Comment)
+	if !ok {
+		return
+	}
+	held := heldReasons.Valid
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Create the comment, held for a moderator when the content filter
+	// flagged it
 	var commentID int
-	err = h.db.QueryRow(`
-        INSERT INTO comments (post_id, user_id, comment, created_at)
-        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+	err = tx.QueryRow(`
+        INSERT INTO comments (post_id, parent_comment_id, depth, user_id, comment, created_at, held_at, held_reasons)
+        VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CASE WHEN $6 THEN CURRENT_TIMESTAMP END, $7)
         RETURNING id
-    `, req.PostID, userID, req.Comment).Scan(&commentID)
+    `, req.PostID, req.ParentCommentID, depth, userID, req.Comment, held, heldReasons).Scan(&commentID)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
 		return
 	}
 
-	// Get the complete comment information
-	var comment models.CommentResponse
-	err = h.db.QueryRow(`
-        SELECT 
-            c.id,
-            c.post_id,
-            c.user_id,
-            c.comment,
-            c.created_at,
-            u.username
-        FROM comments c
-        JOIN users u ON u.id = c.user_id
-        WHERE c.id = $1
-    `, commentID).Scan(
-		&comment.ID,
-		&comment.PostID,
-		&comment.UserID,
-		&comment.Comment,
-		&comment.CreatedAt,
-		&comment.Author,
-	)
+	if !h.saveMentions(c, tx, chatboardID, req.PostID, commentID, req.Comment, held) {
+		return
+	}
+
+	if !held {
+		if err := realtime.Publish(tx, realtime.Event{
+			Type:        realtime.EventCommentCreated,
+			ChatboardID: chatboardID,
+			PostID:      req.PostID,
+			CommentID:   commentID,
+		}); err != nil {
+			log.Printf("Error publishing comment event: %v", err)
+		}
+	}
 
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	// Get the complete comment information
+	comment, err := h.getComment(userID, orgID, commentID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment details"})
 		return
 	}
 
-	comment.UserRole = userRole
-
+	// Held comments wait for a moderator before anyone else sees them
+	if held {
+		c.JSON(http.StatusAccepted, comment)
+		return
+	}
 	c.JSON(http.StatusCreated, comment)
 }
 
+// GetComments returns the post's comment threads as a flattened list: each
+// comment is followed by its replies, and depth tells how far to indent it.
+// Pagination goes by comments on the post itself, oldest first, and every
+// page carries the whole threads of its comments.
 func (h *CommentHandler) GetComments(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 	postID := c.Query("post_id")
 
 	if postID == "" {
@@ -112,10 +314,11 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 	// First get the chatboard ID for this post
 	var chatboardID int
 	err := h.db.QueryRow(`
-        SELECT chatboard_id 
-        FROM posts 
-        WHERE id = $1
-    `, postID).Scan(&chatboardID)
+        SELECT p.chatboard_id
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2 AND p.status = 'published'
+    `, postID, orgID).Scan(&chatboardID)
 
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
@@ -125,28 +328,8 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		return
 	}
 
-	// Check if user has access to the chatboard and get their role
-	var hasAccess bool
-	err = h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 
-            FROM chatboards cb
-            LEFT JOIN chatboard_squads cbs ON cb.id = cbs.chatboard_id
-            LEFT JOIN user_squads us ON us.squad_id = cbs.squad_id
-            LEFT JOIN chatboard_roles cbr ON cb.id = cbr.chatboard_id
-            LEFT JOIN user_roles ur ON ur.role_id = cbr.role_id
-            LEFT JOIN chatboard_countries cbc ON cb.id = cbc.chatboard_id
-            LEFT JOIN user_countries uc ON uc.country_id = cbc.country_id
-            WHERE cb.id = $1 
-            AND us.user_id = $2
-            AND (
-                (us.squad_id IS NOT NULL AND cbs.squad_id IS NOT NULL)
-                OR (ur.role_id IS NOT NULL AND cbr.role_id IS NOT NULL)
-                OR (uc.country_id IS NOT NULL AND cbc.country_id IS NOT NULL)
-            )
-        )
-    `, chatboardID, userID).Scan(&hasAccess)
-
+	// Check if user has access to the chatboard
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, chatboardID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
 		return
@@ -157,56 +340,487 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		return
 	}
 
-	// Get all comments for the post with user information and roles
-	rows, err := h.db.Query(`
-        SELECT 
-            c.id,
-            c.post_id,
-            c.user_id,
-            c.comment,
-            c.created_at,
-            u.username,
-            r.role
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	// Held comments are only shown to their authors and the moderators
+	isModerator, err := isChatboardModerator(h.db, userID, orgID, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+
+	// Find the page of comments on the post itself
+	query := `
+        SELECT c.id, c.created_at
         FROM comments c
-        JOIN users u ON u.id = c.user_id
-        LEFT JOIN user_roles ur ON ur.user_id = u.id
-        LEFT JOIN roles r ON r.id = ur.role_id
-        LEFT JOIN posts p ON p.id = c.post_id
-        LEFT JOIN chatboard_roles cbr ON cbr.chatboard_id = p.chatboard_id AND cbr.role_id = r.id
-        WHERE c.post_id = $1
-        ORDER BY c.created_at ASC
-    `, postID)
+        WHERE c.post_id = $1 AND c.parent_comment_id IS NULL
+        AND (c.held_at IS NULL OR c.user_id = $2 OR $3)`
+	args := []interface{}{postID, userID, isModerator}
+
+	keyset, args := page.keyset("c.created_at", "c.id", true, args)
+	query += keyset + " ORDER BY c.created_at ASC, c.id ASC"
+	limit, args := page.limit(args)
+	query += limit
 
+	rows, err := h.db.Query(query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
 		return
 	}
 	defer rows.Close()
 
-	var comments []models.CommentResponse
+	var roots []pageCursor
 	for rows.Next() {
-		var comment models.CommentResponse
-		var role sql.NullString
-		err := rows.Scan(
-			&comment.ID,
-			&comment.PostID,
-			&comment.UserID,
-			&comment.Comment,
-			&comment.CreatedAt,
-			&comment.Author,
-			&role,
-		)
+		var root pageCursor
+		if err := rows.Scan(&root.ID, &root.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan comment"})
+			return
+		}
+		roots = append(roots, root)
+	}
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	roots, next := trimPage(roots, page.Limit, func(i int) pageCursor { return roots[i] })
+
+	rootIDs := make([]int, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+
+	// Load the threads below those comments
+	threadRows, err := h.db.Query(`
+        WITH RECURSIVE thread AS (
+            SELECT id FROM comments WHERE id = ANY($2)
+            UNION ALL
+            SELECT rc.id FROM comments rc JOIN thread t ON rc.parent_comment_id = t.id
+        )`+commentSelect+`
+        WHERE c.id IN (SELECT id FROM thread)
+        AND (c.held_at IS NULL OR c.user_id = $3 OR $4)
+    `, orgID, pq.Array(rootIDs), userID, isModerator)
+	if err != nil {
+		log.Printf("Error fetching comment threads: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+	defer threadRows.Close()
+
+	var thread []models.CommentResponse
+	var threadIDs []int
+	for threadRows.Next() {
+		comment, err := scanComment(threadRows)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan comment"})
 			return
 		}
+		thread = append(thread, comment)
+		threadIDs = append(threadIDs, comment.ID)
+	}
+	if err = threadRows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	reactions, err := loadReactions(h.db, commentTarget, threadIDs, userID)
+	if err != nil {
+		log.Printf("Error fetching comment reactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+	mentions, err := loadMentions(h.db, commentTarget, threadIDs)
+	if err != nil {
+		log.Printf("Error fetching comment mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+	for i := range thread {
+		thread[i].Reactions = reactions[thread[i].ID]
+		thread[i].Mentions = mentions[thread[i].ID]
+		if thread[i].Removed {
+			thread[i].Mentions = []models.Mention{}
+		}
+	}
+
+	c.JSON(http.StatusOK, pageResponse(flattenCommentThreads(rootIDs, thread), next))
+}
 
-		if role.Valid {
-			comment.UserRole = role.String
+// flattenCommentThreads orders the comments depth first, replies oldest first
+// below their parent, starting from the given roots
+func flattenCommentThreads(rootIDs []int, comments []models.CommentResponse) []models.CommentResponse {
+	byID := make(map[int]models.CommentResponse, len(comments))
+	replies := make(map[int][]int)
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+		if comment.ParentCommentID != nil {
+			replies[*comment.ParentCommentID] = append(replies[*comment.ParentCommentID], comment.ID)
 		}
+	}
+	for _, ids := range replies {
+		sort.Slice(ids, func(i, j int) bool {
+			a, b := byID[ids[i]], byID[ids[j]]
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		})
+	}
 
-		comments = append(comments, comment)
+	flat := make([]models.CommentResponse, 0, len(comments))
+	var visit func(id int)
+	visit = func(id int) {
+		comment, ok := byID[id]
+		if !ok {
+			return
+		}
+		flat = append(flat, comment)
+		for _, replyID := range replies[id] {
+			visit(replyID)
+		}
+	}
+	for _, id := range rootIDs {
+		visit(id)
+	}
+
+	return flat
+}
+
+// commentState is what editing, deleting and restoring a comment needs to know
+type commentState struct {
+	ID          int
+	PostID      int
+	ChatboardID int
+	AuthorID    int
+	CreatedAt   time.Time
+	Deleted     bool
+	Held        bool
+}
+
+// getCommentState loads the comment from the path within the caller's
+// organization. It writes the error response and returns false when the
+// comment cannot be loaded.
+func (h *CommentHandler) getCommentState(c *gin.Context) (commentState, bool) {
+	var comment commentState
+
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return comment, false
+	}
+
+	err = h.db.QueryRow(`
+        SELECT c.id, c.post_id, p.chatboard_id, c.user_id, c.created_at, c.deleted_at IS NOT NULL, c.held_at IS NOT NULL
+        FROM comments c
+        JOIN posts p ON p.id = c.post_id
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE c.id = $1 AND cb.organization_id = $2
+    `, commentID, c.GetInt("orgID")).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.ChatboardID,
+		&comment.AuthorID,
+		&comment.CreatedAt,
+		&comment.Deleted,
+		&comment.Held,
+	)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return comment, false
+	} else if err != nil {
+		log.Printf("Error fetching comment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment"})
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// UpdateComment lets authors change their comment within the edit window. The
+// previous version is kept in comment_revisions.
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	comment, ok := h.getCommentState(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if comment.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this comment"})
+		return
+	}
+	if comment.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Removed comments cannot be edited"})
+		return
+	}
+	if time.Since(comment.CreatedAt) > h.editWindow {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Comments can only be edited within %s of posting", h.editWindow)})
+		return
+	}
+
+	if !ensureNotRestricted(c, h.db, comment.ChatboardID) {
+		return
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, comment.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	archived, err := isChatboardArchived(h.db, comment.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) {
+		return
+	}
+
+	heldReasons, ok := screenContent(c, h.db, comment.ChatboardID, req.Comment)
+	if !ok {
+		return
+	}
+	// Held comments stay held until a moderator reviews them
+	held := comment.Held || heldReasons.Valid
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Keep the version being replaced
+	_, err = tx.Exec(`
+        INSERT INTO comment_revisions (comment_id, comment, edited_by)
+        SELECT id, comment, $2 FROM comments WHERE id = $1
+    `, comment.ID, userID)
+	if err != nil {
+		log.Printf("Error saving comment revision: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	_, err = tx.Exec(`
+        UPDATE comments
+        SET comment = $1,
+            edited_at = NOW(),
+            held_at = CASE WHEN $3 THEN COALESCE(held_at, NOW()) ELSE held_at END,
+            held_reasons = COALESCE($4, held_reasons)
+        WHERE id = $2
+    `, req.Comment, comment.ID, heldReasons.Valid, heldReasons)
+	if err != nil {
+		log.Printf("Error updating comment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	// Mentions move with the edited text, only new ones notify
+	if !h.saveMentions(c, tx, comment.ChatboardID, comment.PostID, comment.ID, req.Comment, held) {
+		return
+	}
+
+	// Nobody else has seen a held comment, and one held by this edit
+	// disappears for them
+	if !comment.Held {
+		eventType := realtime.EventCommentUpdated
+		if held {
+			eventType = realtime.EventCommentRemoved
+		}
+		if err := realtime.Publish(tx, realtime.Event{
+			Type:        eventType,
+			ChatboardID: comment.ChatboardID,
+			PostID:      comment.PostID,
+			CommentID:   comment.ID,
+		}); err != nil {
+			log.Printf("Error publishing comment event: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	updated, err := h.getComment(userID, orgID, comment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comment details"})
+		return
+	}
+
+	if held {
+		c.JSON(http.StatusAccepted, updated)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteComment removes a comment for its author or the chatboard's
+// moderators. Its replies stay, and the comment shows as removed until an
+// Admin restores it.
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	comment, ok := h.getCommentState(c)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, c.GetInt("orgID"), comment.ChatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author and moderators can remove this comment"})
+			return
+		}
+	}
+
+	if comment.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Comment is already removed"})
+		return
+	}
+
+	_, err := h.db.Exec("UPDATE comments SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1", comment.ID, userID)
+	if err != nil {
+		log.Printf("Error removing comment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove comment"})
+		return
+	}
+
+	if err := realtime.Publish(h.db, realtime.Event{
+		Type:        realtime.EventCommentRemoved,
+		ChatboardID: comment.ChatboardID,
+		PostID:      comment.PostID,
+		CommentID:   comment.ID,
+	}); err != nil {
+		log.Printf("Error publishing comment event: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment removed successfully"})
+}
+
+// RestoreComment brings back a removed comment. Only Admins can restore
+// comments.
+func (h *CommentHandler) RestoreComment(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	comment, ok := h.getCommentState(c)
+	if !ok {
+		return
+	}
+
+	isAdmin, err := hasGlobalRole(h.db, userID, c.GetInt("orgID"), "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can restore comments"})
+		return
+	}
+
+	if !comment.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Comment is not removed"})
+		return
+	}
+
+	_, err = h.db.Exec("UPDATE comments SET deleted_at = NULL, deleted_by = NULL WHERE id = $1", comment.ID)
+	if err != nil {
+		log.Printf("Error restoring comment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore comment"})
+		return
+	}
+
+	if err := realtime.Publish(h.db, realtime.Event{
+		Type:        realtime.EventCommentRestored,
+		ChatboardID: comment.ChatboardID,
+		PostID:      comment.PostID,
+		CommentID:   comment.ID,
+	}); err != nil {
+		log.Printf("Error publishing comment event: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment restored successfully"})
+}
+
+// GetCommentRevisions lists the previous versions of a comment, newest first.
+// The author and the chatboard's moderators can see them.
+func (h *CommentHandler) GetCommentRevisions(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	comment, ok := h.getCommentState(c)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, c.GetInt("orgID"), comment.ChatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author and moderators can see revisions"})
+			return
+		}
+	}
+
+	rows, err := h.db.Query(`
+        SELECT id, comment, edited_by, created_at
+        FROM comment_revisions
+        WHERE comment_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, comment.ID)
+	if err != nil {
+		log.Printf("Error fetching comment revisions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	defer rows.Close()
+
+	revisions := make([]models.CommentRevisionResponse, 0)
+	for rows.Next() {
+		var revision models.CommentRevisionResponse
+		var editedBy sql.NullInt64
+		if err := rows.Scan(&revision.ID, &revision.Comment, &editedBy, &revision.CreatedAt); err != nil {
+			log.Printf("Error scanning comment revision: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+			return
+		}
+		if editedBy.Valid {
+			id := int(editedBy.Int64)
+			revision.EditedBy = &id
+		}
+		revisions = append(revisions, revision)
 	}
 
-	c.JSON(http.StatusOK, comments)
+	c.JSON(http.StatusOK, revisions)
 }
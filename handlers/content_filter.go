@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicorn_app_backend/contentfilter"
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// domainPattern accepts host names like "youtube.com" or "docs.example.org"
+var domainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+// blockedMessages explain to the author why their content was rejected
+var blockedMessages = map[string]string{
+	contentfilter.CodeBlockedWord:  "This contains words that aren't allowed here",
+	contentfilter.CodeEmailAddress: "Email addresses can't be shared here",
+	contentfilter.CodePhoneNumber:  "Phone numbers can't be shared here",
+}
+
+type ContentFilterHandler struct {
+	db *sql.DB
+}
+
+func NewContentFilterHandler(db *sql.DB) *ContentFilterHandler {
+	return &ContentFilterHandler{db: db}
+}
+
+// loadContentFilter builds the organization's filter for content the author
+// writes in the chatboard. Rules for a locale apply when the chatboard or the
+// author belongs to a country with that locale.
+func loadContentFilter(db querier, orgID, chatboardID, authorID int) (*contentfilter.Filter, error) {
+	rows, err := db.Query(`
+        SELECT kind, pattern, action
+        FROM content_filter_rules
+        WHERE organization_id = $1
+        AND (locale = '' OR locale IN (
+            SELECT co.locale FROM countries co
+            WHERE co.id IN (
+                SELECT country_id FROM chatboard_countries WHERE chatboard_id = $2
+                UNION
+                SELECT country_id FROM user_countries WHERE user_id = $3
+            )
+        ))
+    `, orgID, chatboardID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []contentfilter.Rule
+	for rows.Next() {
+		var rule contentfilter.Rule
+		if err := rows.Scan(&rule.Kind, &rule.Pattern, &rule.Action); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	domainRows, err := db.Query("SELECT domain FROM content_filter_domains WHERE organization_id = $1", orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer domainRows.Close()
+
+	var domains []string
+	for domainRows.Next() {
+		var domain string
+		if err := domainRows.Scan(&domain); err != nil {
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+	if err := domainRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contentfilter.New(rules, domains), nil
+}
+
+// screenContent runs the content filter over what the caller wrote in the
+// chatboard. It writes the error response and returns false when the content
+// is blocked; otherwise it returns why the content must be held, NULL when it
+// can be published.
+func screenContent(c *gin.Context, db querier, chatboardID int, texts ...string) (sql.NullString, bool) {
+	filter, err := loadContentFilter(db, c.GetInt("orgID"), chatboardID, c.GetInt("userID"))
+	if err != nil {
+		log.Printf("Error loading content filter: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check content"})
+		return sql.NullString{}, false
+	}
+
+	result := filter.Check(texts...)
+	if result.Block != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": blockedMessages[result.Block.Code],
+			"code":  result.Block.Code,
+			"match": result.Block.Text,
+		})
+		return sql.NullString{}, false
+	}
+	if len(result.Flags) == 0 {
+		return sql.NullString{}, true
+	}
+
+	reasons, err := json.Marshal(result.Flags)
+	if err != nil {
+		log.Printf("Error encoding held reasons: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check content"})
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: string(reasons), Valid: true}, true
+}
+
+func (h *ContentFilterHandler) requireAdmin(c *gin.Context) bool {
+	isAdmin, err := hasGlobalRole(h.db, c.GetInt("userID"), c.GetInt("orgID"), "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify admin status"})
+		return false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage the content filter"})
+		return false
+	}
+	return true
+}
+
+// GetContentFilterRules lists the organization's block and flag rules
+func (h *ContentFilterHandler) GetContentFilterRules(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+        SELECT id, locale, kind, pattern, action, created_at
+        FROM content_filter_rules
+        WHERE organization_id = $1
+        ORDER BY locale, action, pattern
+    `, c.GetInt("orgID"))
+	if err != nil {
+		log.Printf("Error fetching content filter rules: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch content filter rules"})
+		return
+	}
+	defer rows.Close()
+
+	rules := make([]models.ContentFilterRule, 0)
+	for rows.Next() {
+		var rule models.ContentFilterRule
+		if err := rows.Scan(&rule.ID, &rule.Locale, &rule.Kind, &rule.Pattern, &rule.Action, &rule.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch content filter rules"})
+			return
+		}
+		rules = append(rules, rule)
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateContentFilterRule adds a word or regular expression to block or flag
+func (h *ContentFilterHandler) CreateContentFilterRule(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	var req models.CreateContentFilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.ContentFilterRule{
+		Locale:  req.Locale,
+		Kind:    req.Kind,
+		Pattern: req.Pattern,
+		Action:  req.Action,
+	}
+	if rule.Kind == contentfilter.KindWord {
+		rule.Pattern = strings.ToLower(strings.TrimSpace(rule.Pattern))
+	}
+	if rule.Locale != "" && !localePattern.MatchString(rule.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "locale must be a language tag like et or en-GB"})
+		return
+	}
+	if rule.Action != contentfilter.ActionBlock && rule.Action != contentfilter.ActionFlag {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be block or flag"})
+		return
+	}
+	if _, err := (contentfilter.Rule{Kind: rule.Kind, Pattern: rule.Pattern}).Compile(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.QueryRow(`
+        INSERT INTO content_filter_rules (organization_id, locale, kind, pattern, action, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, c.GetInt("orgID"), rule.Locale, rule.Kind, rule.Pattern, rule.Action, c.GetInt("userID")).Scan(&rule.ID, &rule.CreatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This rule already exists"})
+		return
+	} else if err != nil {
+		log.Printf("Error creating content filter rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create content filter rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteContentFilterRule removes a rule
+func (h *ContentFilterHandler) DeleteContentFilterRule(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM content_filter_rules WHERE id = $1 AND organization_id = $2", c.Param("id"), c.GetInt("orgID"))
+	if err != nil {
+		log.Printf("Error deleting content filter rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete content filter rule"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content filter rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Content filter rule deleted successfully"})
+}
+
+// GetAllowedDomains lists the domains members can link to without their
+// content being held
+func (h *ContentFilterHandler) GetAllowedDomains(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	rows, err := h.db.Query(`
+        SELECT id, domain, created_at
+        FROM content_filter_domains
+        WHERE organization_id = $1
+        ORDER BY domain
+    `, c.GetInt("orgID"))
+	if err != nil {
+		log.Printf("Error fetching allowed domains: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allowed domains"})
+		return
+	}
+	defer rows.Close()
+
+	domains := make([]models.AllowedDomain, 0)
+	for rows.Next() {
+		var domain models.AllowedDomain
+		if err := rows.Scan(&domain.ID, &domain.Domain, &domain.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allowed domains"})
+			return
+		}
+		domains = append(domains, domain)
+	}
+
+	c.JSON(http.StatusOK, domains)
+}
+
+// AddAllowedDomain allows links to a domain and its subdomains
+func (h *ContentFilterHandler) AddAllowedDomain(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	var req models.AddAllowedDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	domain := models.AllowedDomain{Domain: contentfilter.NormalizeDomain(req.Domain)}
+	if len(domain.Domain) > 253 || !domainPattern.MatchString(domain.Domain) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "domain must be a host name like example.com"})
+		return
+	}
+
+	err := h.db.QueryRow(`
+        INSERT INTO content_filter_domains (organization_id, domain)
+        VALUES ($1, $2)
+        RETURNING id, created_at
+    `, c.GetInt("orgID"), domain.Domain).Scan(&domain.ID, &domain.CreatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This domain is already allowed"})
+		return
+	} else if err != nil {
+		log.Printf("Error adding allowed domain: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add allowed domain"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain)
+}
+
+// DeleteAllowedDomain stops allowing links to a domain
+func (h *ContentFilterHandler) DeleteAllowedDomain(c *gin.Context) {
+	if !h.requireAdmin(c) {
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM content_filter_domains WHERE id = $1 AND organization_id = $2", c.Param("id"), c.GetInt("orgID"))
+	if err != nil {
+		log.Printf("Error deleting allowed domain: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete allowed domain"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Allowed domain not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Allowed domain deleted successfully"})
+}
@@ -2,13 +2,45 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"unicorn_app_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// localePattern accepts language tags like "et", "en" or "en-GB"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// isoCountryCodes are the officially assigned ISO 3166-1 alpha-2 codes
+var isoCountryCodes = func() map[string]bool {
+	codes := make(map[string]bool)
+	for _, code := range strings.Fields(
+		"AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE " +
+			"BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ CA CC CD " +
+			"CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM " +
+			"DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR GA GB GD GE GF " +
+			"GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU " +
+			"ID IE IL IM IN IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN " +
+			"KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME " +
+			"MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ NA " +
+			"NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM " +
+			"PN PR PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI " +
+			"SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK " +
+			"TL TM TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI " +
+			"VN VU WF WS YE YT ZA ZM ZW",
+	) {
+		codes[code] = true
+	}
+	return codes
+}()
+
 type CountryHandler struct {
 	db *sql.DB
 }
@@ -19,32 +51,59 @@ func NewCountryHandler(db *sql.DB) *CountryHandler {
 
 // CreateCountry handles the creation of a new country
 func (h *CountryHandler) CreateCountry(c *gin.Context) {
+	if !h.requireAdmin(c, "Only admins can create countries") {
+		return
+	}
+
 	var req models.CreateCountryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var countryID int
-	err := h.db.QueryRow(
-		"INSERT INTO countries (name) VALUES ($1) RETURNING id",
-		req.Name,
-	).Scan(&countryID)
+	country := models.CountryResponse{
+		Name:     strings.TrimSpace(req.Name),
+		ISOCode:  strings.ToUpper(req.ISOCode),
+		Timezone: req.Timezone,
+		Locale:   req.Locale,
+	}
+	if country.Timezone == "" {
+		country.Timezone = "UTC"
+	}
+	if country.Locale == "" {
+		country.Locale = "en"
+	}
+	if err := validateCountry(country, true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.QueryRow(`
+		INSERT INTO countries (name, iso_code, timezone, locale, organization_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, country.Name, country.ISOCode, country.Timezone, country.Locale, c.GetInt("orgID")).Scan(&country.ID)
 
-	if err != nil {
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A country with this name or ISO code already exists"})
+		return
+	} else if err != nil {
+		log.Printf("Error creating country: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create country"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.CountryResponse{
-		ID:   countryID,
-		Name: req.Name,
-	})
+	c.JSON(http.StatusCreated, country)
 }
 
 // GetCountries handles retrieving all countries
 func (h *CountryHandler) GetCountries(c *gin.Context) {
-	rows, err := h.db.Query("SELECT id, name FROM countries")
+	rows, err := h.db.Query(`
+		SELECT id, name, COALESCE(iso_code, ''), timezone, locale
+		FROM countries
+		WHERE organization_id = $1
+		ORDER BY name
+	`, c.GetInt("orgID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch countries"})
 		return
@@ -54,7 +113,7 @@ func (h *CountryHandler) GetCountries(c *gin.Context) {
 	var countries []models.CountryResponse
 	for rows.Next() {
 		var country models.CountryResponse
-		if err := rows.Scan(&country.ID, &country.Name); err != nil {
+		if err := rows.Scan(&country.ID, &country.Name, &country.ISOCode, &country.Timezone, &country.Locale); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan country"})
 			return
 		}
@@ -63,3 +122,281 @@ func (h *CountryHandler) GetCountries(c *gin.Context) {
 
 	c.JSON(http.StatusOK, countries)
 }
+
+// UpdateCountry changes a country's name, ISO code, timezone or locale
+func (h *CountryHandler) UpdateCountry(c *gin.Context) {
+	if !h.requireAdmin(c, "Only admins can update countries") {
+		return
+	}
+
+	countryID := c.Param("id")
+
+	var req models.UpdateCountryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var country models.CountryResponse
+	err := h.db.QueryRow(`
+		SELECT id, name, COALESCE(iso_code, ''), timezone, locale
+		FROM countries
+		WHERE id = $1 AND organization_id = $2
+	`, countryID, c.GetInt("orgID")).Scan(&country.ID, &country.Name, &country.ISOCode, &country.Timezone, &country.Locale)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Country not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch country"})
+		return
+	}
+
+	if req.Name != nil {
+		country.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ISOCode != nil {
+		country.ISOCode = strings.ToUpper(*req.ISOCode)
+	}
+	if req.Timezone != nil {
+		country.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		country.Locale = *req.Locale
+	}
+	// Countries created before ISO codes existed keep working without one
+	if err := validateCountry(country, req.ISOCode != nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		UPDATE countries
+		SET name = $1, iso_code = NULLIF($2, ''), timezone = $3, locale = $4
+		WHERE id = $5
+	`, country.Name, country.ISOCode, country.Timezone, country.Locale, country.ID)
+
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A country with this name or ISO code already exists"})
+		return
+	} else if err != nil {
+		log.Printf("Error updating country: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update country"})
+		return
+	}
+
+	c.JSON(http.StatusOK, country)
+}
+
+// DeleteCountry deletes a country that is no longer used by users, squads or chatboards
+func (h *CountryHandler) DeleteCountry(c *gin.Context) {
+	if !h.requireAdmin(c, "Only admins can delete countries") {
+		return
+	}
+
+	countryID := c.Param("id")
+
+	var exists, inUse bool
+	err := h.db.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM countries WHERE id = $1 AND organization_id = $2),
+			EXISTS (SELECT 1 FROM user_countries WHERE country_id = $1)
+			OR EXISTS (SELECT 1 FROM squads WHERE country_id = $1)
+			OR EXISTS (SELECT 1 FROM chatboard_countries WHERE country_id = $1)
+	`, countryID, c.GetInt("orgID")).Scan(&exists, &inUse)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check country usage"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Country not found"})
+		return
+	}
+	if inUse {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete country that is in use"})
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM countries WHERE id = $1 AND organization_id = $2", countryID, c.GetInt("orgID"))
+	if err != nil {
+		log.Printf("Error deleting country: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete country"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Country not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Country deleted successfully"})
+}
+
+// GetCountryAdmins lists the admins of a country to Admins and the
+// country's own admins
+func (h *CountryHandler) GetCountryAdmins(c *gin.Context) {
+	countryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid country ID"})
+		return
+	}
+
+	allowed, err := hasGlobalRole(h.db, c.GetInt("userID"), c.GetInt("orgID"), "Admin")
+	if err == nil && !allowed {
+		allowed, err = isCountryAdmin(h.db, c.GetInt("userID"), countryID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and the country's admins can see its admins"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT u.id, COALESCE(u.username, ''), u.first_name, u.last_name, ca.created_at
+		FROM country_admins ca
+		JOIN users u ON u.id = ca.user_id
+		JOIN countries co ON co.id = ca.country_id
+		WHERE ca.country_id = $1 AND co.organization_id = $2
+		ORDER BY u.first_name, u.last_name
+	`, countryID, c.GetInt("orgID"))
+	if err != nil {
+		log.Printf("Error fetching country admins: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch country admins"})
+		return
+	}
+	defer rows.Close()
+
+	admins := make([]models.CountryAdminResponse, 0)
+	for rows.Next() {
+		var admin models.CountryAdminResponse
+		var createdAt sql.NullTime
+		if err := rows.Scan(&admin.UserID, &admin.Username, &admin.FirstName, &admin.LastName, &createdAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan country admin"})
+			return
+		}
+		if createdAt.Valid {
+			admin.CreatedAt = createdAt.Time.Format("2006-01-02")
+		}
+		admins = append(admins, admin)
+	}
+
+	c.JSON(http.StatusOK, admins)
+}
+
+// AssignCountryAdmin makes a user an admin of a country
+func (h *CountryHandler) AssignCountryAdmin(c *gin.Context) {
+	if !h.requireAdmin(c, "Only admins can assign country admins") {
+		return
+	}
+
+	countryID := c.Param("id")
+
+	var req models.AssignCountryAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var countryExists, userExists bool
+	err := h.db.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM countries WHERE id = $1 AND organization_id = $3),
+			EXISTS (SELECT 1 FROM organization_members WHERE user_id = $2 AND organization_id = $3)
+	`, countryID, req.UserID, c.GetInt("orgID")).Scan(&countryExists, &userExists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify country and user"})
+		return
+	}
+	if !countryExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Country not found"})
+		return
+	}
+	if !userExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO country_admins (user_id, country_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, country_id) DO NOTHING
+	`, req.UserID, countryID)
+	if err != nil {
+		log.Printf("Error assigning country admin: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign country admin"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Country admin assigned successfully"})
+}
+
+// RemoveCountryAdmin revokes a user's admin role for a country
+func (h *CountryHandler) RemoveCountryAdmin(c *gin.Context) {
+	if !h.requireAdmin(c, "Only admins can remove country admins") {
+		return
+	}
+
+	result, err := h.db.Exec(`
+		DELETE FROM country_admins ca
+		USING countries co
+		WHERE co.id = ca.country_id
+		AND ca.country_id = $1 AND ca.user_id = $2
+		AND co.organization_id = $3
+	`, c.Param("id"), c.Param("user_id"), c.GetInt("orgID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove country admin"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify removal"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not an admin of this country"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Country admin removed successfully"})
+}
+
+// requireAdmin writes a 403 response with the given message unless the
+// caller is a global Admin
+func (h *CountryHandler) requireAdmin(c *gin.Context, message string) bool {
+	isAdmin, err := hasGlobalRole(h.db, c.GetInt("userID"), c.GetInt("orgID"), "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify admin status"})
+		return false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
+// validateCountry checks the country's fields. The ISO code is only checked
+// when requireISO is set or the country has one.
+func validateCountry(country models.CountryResponse, requireISO bool) error {
+	if country.Name == "" {
+		return fmt.Errorf("country name cannot be empty")
+	}
+	if (requireISO || country.ISOCode != "") && !isoCountryCodes[country.ISOCode] {
+		return fmt.Errorf("iso_code must be a two-letter ISO 3166-1 code")
+	}
+	if _, err := time.LoadLocation(country.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", country.Timezone)
+	}
+	if !localePattern.MatchString(country.Locale) {
+		return fmt.Errorf("locale must look like \"et\" or \"en-GB\"")
+	}
+	return nil
+}
@@ -19,16 +19,19 @@ func NewCourseHandler(db *sql.DB) *CourseHandler {
 
 func (h *CourseHandler) CreateCourse(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
-	// Check if user is an admin
-	var isAdmin bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 FROM user_roles ur
-            JOIN roles r ON r.id = ur.role_id
-            WHERE ur.user_id = $1 AND r.role = 'Admin'
-        )
-    `, userID).Scan(&isAdmin)
+	var req models.CreateCourseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if user is an admin, or an admin of the course's country
+	isAdmin, err := hasGlobalRole(h.db, userID, orgID, "Admin")
+	if err == nil && !isAdmin && req.CountryID != nil {
+		isAdmin, err = isCountryAdmin(h.db, userID, *req.CountryID)
+	}
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify admin status"})
@@ -36,39 +39,61 @@ func (h *CourseHandler) CreateCourse(c *gin.Context) {
 	}
 
 	if !isAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can create courses"})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and country admins can create courses"})
 		return
 	}
 
-	var req models.CreateCourseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	if req.CountryID != nil {
+		var countryExists bool
+		err = h.db.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM countries WHERE id = $1 AND organization_id = $2)",
+			*req.CountryID, orgID,
+		).Scan(&countryExists)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify country"})
+			return
+		}
+		if !countryExists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Country not found"})
+			return
+		}
 	}
 
 	// Create the course
 	var course models.CourseResponse
 	err = h.db.QueryRow(`
-        INSERT INTO courses (name, created_at)
-        VALUES ($1, CURRENT_DATE)
+        INSERT INTO courses (name, country_id, organization_id, created_at)
+        VALUES ($1, $2, $3, CURRENT_DATE)
         RETURNING id, name, created_at
-    `, req.Name).Scan(&course.ID, &course.Name, &course.CreatedAt)
+    `, req.Name, req.CountryID, orgID).Scan(&course.ID, &course.Name, &course.CreatedAt)
 
 	if err != nil {
+		log.Printf("Error creating course: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create course"})
 		return
 	}
 
+	course.CountryID = req.CountryID
 	c.JSON(http.StatusCreated, course)
 }
 
 func (h *CourseHandler) GetCourses(c *gin.Context) {
 	// No permission check needed - all authenticated users can access
-	rows, err := h.db.Query(`
-        SELECT id, name, created_at
+	query := `
+        SELECT id, name, country_id, created_at
         FROM courses
-        ORDER BY created_at DESC
-    `)
+        WHERE organization_id = $1
+    `
+	params := []interface{}{c.GetInt("orgID")}
+
+	if countryID := c.Query("country_id"); countryID != "" {
+		query += " AND country_id = $2"
+		params = append(params, countryID)
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	rows, err := h.db.Query(query, params...)
 	if err != nil {
 		log.Printf("Error fetching courses: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch courses"})
@@ -80,7 +105,8 @@ func (h *CourseHandler) GetCourses(c *gin.Context) {
 	for rows.Next() {
 		var course models.Course
 		var createdAt sql.NullTime
-		err := rows.Scan(&course.ID, &course.Name, &createdAt)
+		var countryID sql.NullInt64
+		err := rows.Scan(&course.ID, &course.Name, &countryID, &createdAt)
 		if err != nil {
 			log.Printf("Error scanning course: %v", err)
 			continue
@@ -88,6 +114,10 @@ func (h *CourseHandler) GetCourses(c *gin.Context) {
 		if createdAt.Valid {
 			course.CreatedAt = createdAt.Time.Format("2006-01-02")
 		}
+		if countryID.Valid {
+			id := int(countryID.Int64)
+			course.CountryID = &id
+		}
 		courses = append(courses, course)
 	}
 
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Post statuses. Drafts and scheduled posts are only visible to their author.
+const (
+	postDraft     = "draft"
+	postScheduled = "scheduled"
+	postPublished = "published"
+)
+
+// GetDrafts lists the caller's drafts and scheduled posts, newest first.
+// chatboard_id narrows it to one board.
+func (h *PostHandler) GetDrafts(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	query := `
+        SELECT p.id, p.chatboard_id, p.title, p.content, p.status, p.publish_at,
+               p.created_at, p.edited_at, p.held_reasons IS NOT NULL
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.user_id = $1 AND cb.organization_id = $2
+        AND p.status <> 'published' AND p.deleted_at IS NULL`
+	args := []interface{}{userID, c.GetInt("orgID")}
+
+	if value := c.Query("chatboard_id"); value != "" {
+		chatboardID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+			return
+		}
+		query += fmt.Sprintf(" AND p.chatboard_id = $%d", len(args)+1)
+		args = append(args, chatboardID)
+	}
+
+	keyset, args := page.keyset("p.created_at", "p.id", false, args)
+	query += keyset + " ORDER BY p.created_at DESC, p.id DESC"
+	limit, args := page.limit(args)
+	query += limit
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching drafts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+	defer rows.Close()
+
+	var drafts []gin.H
+	var cursors []pageCursor
+	for rows.Next() {
+		var (
+			id, chatboardID        int
+			title, content, status string
+			publishAt, editedAt    sql.NullTime
+			createdAt              time.Time
+			flagged                bool
+		)
+		if err := rows.Scan(&id, &chatboardID, &title, &content, &status, &publishAt,
+			&createdAt, &editedAt, &flagged); err != nil {
+			log.Printf("Error scanning draft: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+			return
+		}
+
+		draft := gin.H{
+			"id":           id,
+			"chatboard_id": chatboardID,
+			"title":        title,
+			"content":      content,
+			"status":       status,
+			"publish_at":   nil,
+			"created_at":   createdAt,
+			"edited":       editedAt.Valid,
+			// The content filter flagged it, so it will wait for a moderator
+			"flagged": flagged,
+		}
+		if publishAt.Valid {
+			draft["publish_at"] = publishAt.Time
+		}
+		if editedAt.Valid {
+			draft["edited_at"] = editedAt.Time
+		}
+		drafts = append(drafts, draft)
+		cursors = append(cursors, pageCursor{CreatedAt: createdAt, ID: id})
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating drafts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+
+	drafts, next := trimPage(drafts, page.Limit, func(i int) pageCursor { return cursors[i] })
+
+	postIDs := make([]int, len(drafts))
+	for i := range drafts {
+		postIDs[i] = cursors[i].ID
+	}
+	attachments, err := loadAttachments(h.db, postIDs)
+	if err != nil {
+		log.Printf("Error fetching draft attachments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+	mentions, err := loadMentions(h.db, postTarget, postIDs)
+	if err != nil {
+		log.Printf("Error fetching draft mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+	polls, err := loadPolls(h.db, postIDs, userID, h.voterKey)
+	if err != nil {
+		log.Printf("Error fetching draft polls: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+	for i, draft := range drafts {
+		draft["attachments"] = attachments[postIDs[i]]
+		draft["mentions"] = mentions[postIDs[i]]
+		draft["poll"] = polls[postIDs[i]]
+	}
+
+	if drafts == nil {
+		drafts = []gin.H{}
+	}
+	c.JSON(http.StatusOK, pageResponse(drafts, next))
+}
+
+// getUnpublishedPost loads the caller's draft or scheduled post from the
+// path. It writes the error response and returns false otherwise.
+func (h *PostHandler) getUnpublishedPost(c *gin.Context) (postState, bool) {
+	post, ok := h.getPostState(c)
+	if !ok {
+		return post, false
+	}
+
+	if post.Status == postPublished {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is already published"})
+		return post, false
+	}
+	if post.Deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return post, false
+	}
+	return post, true
+}
+
+// PublishPost publishes one of the caller's drafts or scheduled posts now
+func (h *PostHandler) PublishPost(c *gin.Context) {
+	post, ok := h.getUnpublishedPost(c)
+	if !ok {
+		return
+	}
+
+	if !ensureNotRestricted(c, h.db, post.ChatboardID) {
+		return
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, post.AuthorID, c.GetInt("orgID"), post.ChatboardID)
+	if err != nil {
+		log.Printf("Error checking chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboard access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	archived, err := isChatboardArchived(h.db, post.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboard access"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) || !ensureSlowMode(c, h.db, post.ChatboardID) {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	held, err := publishPost(tx, c.GetInt("orgID"), post.ID)
+	if err == sql.ErrNoRows {
+		// The scheduler got to it first
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is already published"})
+		return
+	} else if err != nil {
+		log.Printf("Error publishing post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish post"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish post"})
+		return
+	}
+
+	// Held posts wait for a moderator before anyone else sees them
+	if held {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Post published and waiting for a moderator", "status": postPublished, "held": true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Post published successfully", "status": postPublished, "held": false})
+}
+
+// SchedulePost schedules one of the caller's drafts, or moves the publishing
+// time of a scheduled post
+func (h *PostHandler) SchedulePost(c *gin.Context) {
+	post, ok := h.getUnpublishedPost(c)
+	if !ok {
+		return
+	}
+
+	var req models.SchedulePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.PublishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "publish_at must be in the future"})
+		return
+	}
+
+	result, err := h.db.Exec(`
+        UPDATE posts SET status = 'scheduled', publish_at = $2
+        WHERE id = $1 AND status <> 'published'
+    `, post.ID, req.PublishAt)
+	if err != nil {
+		log.Printf("Error scheduling post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule post"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is already published"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Post scheduled successfully",
+		"status":     postScheduled,
+		"publish_at": req.PublishAt,
+	})
+}
+
+// UnschedulePost cancels publishing a scheduled post, which stays as a draft
+func (h *PostHandler) UnschedulePost(c *gin.Context) {
+	post, ok := h.getUnpublishedPost(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.db.Exec(`
+        UPDATE posts SET status = 'draft', publish_at = NULL
+        WHERE id = $1 AND status = 'scheduled'
+    `, post.ID)
+	if err != nil {
+		log.Printf("Error unscheduling post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unschedule post"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is not scheduled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post moved back to drafts", "status": postDraft})
+}
+
+// publishPost publishes a draft or scheduled post as part of the transaction,
+// as if it was posted now. Posts the content filter flagged go to the
+// moderators instead; otherwise the mentions notify and subscribers hear about
+// the new post. It returns sql.ErrNoRows when the post is already published.
+func publishPost(tx *sql.Tx, orgID, postID int) (bool, error) {
+	var chatboardID, authorID int
+	var held bool
+	err := tx.QueryRow(`
+        UPDATE posts
+        SET status = 'published', publish_at = NULL, created_at = NOW(),
+            held_at = CASE WHEN held_reasons IS NOT NULL THEN NOW() END
+        WHERE id = $1 AND status <> 'published' AND deleted_at IS NULL
+        RETURNING chatboard_id, user_id, held_at IS NOT NULL
+    `, postID).Scan(&chatboardID, &authorID, &held)
+	if err != nil {
+		return false, err
+	}
+	if held {
+		return true, nil
+	}
+
+	err = notifyHeldMentions(tx, orgID, postTarget, postID, authorID, "a post", gin.H{
+		"chatboard_id": chatboardID,
+		"post_id":      postID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := realtime.Publish(tx, realtime.Event{
+		Type:        realtime.EventPostCreated,
+		ChatboardID: chatboardID,
+		PostID:      postID,
+	}); err != nil {
+		log.Printf("Error publishing post event: %v", err)
+	}
+	return false, nil
+}
+
+// PublishScheduledPosts publishes the scheduled posts that are due, each in
+// its own transaction. Posts whose author can no longer post on the chatboard,
+// or whose chatboard was archived, go back to the author's drafts instead, as
+// do posts that fail to publish, so they don't hold back the posts due after
+// them.
+func PublishScheduledPosts(ctx context.Context, db *sql.DB) error {
+	for ctx.Err() == nil {
+		done, err := publishNextScheduledPost(ctx, db)
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishNextScheduledPost publishes the next due post no other instance is
+// working on. It returns true when there is none left.
+func publishNextScheduledPost(ctx context.Context, db *sql.DB) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var postID, chatboardID, authorID, orgID int
+	var title string
+	var blocked bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT p.id, p.chatboard_id, p.user_id, cb.organization_id, p.title,
+               cb.archived_at IS NOT NULL
+               OR EXISTS (
+                   SELECT 1 FROM chatboard_restrictions r
+                   WHERE r.chatboard_id = p.chatboard_id AND r.user_id = p.user_id
+                   AND (r.expires_at IS NULL OR r.expires_at > NOW())
+               )
+               OR EXISTS (
+                   SELECT 1 FROM organization_members om
+                   WHERE om.user_id = p.user_id AND om.organization_id = cb.organization_id
+                   AND om.suspended_until > NOW()
+               )
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.status = 'scheduled' AND p.publish_at <= NOW() AND p.deleted_at IS NULL
+        ORDER BY p.publish_at, p.id
+        LIMIT 1
+        FOR UPDATE OF p SKIP LOCKED
+    `).Scan(&postID, &chatboardID, &authorID, &orgID, &title, &blocked)
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !blocked {
+		hasAccess, err := canAccessChatboard(tx, authorID, orgID, chatboardID)
+		if err != nil {
+			return false, err
+		}
+		blocked = !hasAccess
+	}
+
+	if blocked {
+		err = unschedulePost(tx, orgID, chatboardID, authorID, postID, title)
+	} else if _, err = publishPost(tx, orgID, postID); err != nil {
+		log.Printf("Error publishing scheduled post %d: %v", postID, err)
+		tx.Rollback()
+		return false, unscheduleFailedPost(ctx, db, orgID, chatboardID, authorID, postID, title)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, tx.Commit()
+}
+
+// unscheduleFailedPost moves a post that failed to publish back to its
+// author's drafts in a transaction of its own. When that fails too, the
+// error was likely not the post's and it is retried on the next run.
+func unscheduleFailedPost(ctx context.Context, db *sql.DB, orgID, chatboardID, authorID, postID int, title string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := unschedulePost(tx, orgID, chatboardID, authorID, postID, title); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// unschedulePost moves a scheduled post back to its author's drafts and
+// tells them it could not be published
+func unschedulePost(tx *sql.Tx, orgID, chatboardID, authorID, postID int, title string) error {
+	result, err := tx.Exec("UPDATE posts SET status = 'draft', publish_at = NULL WHERE id = $1 AND status = 'scheduled'", postID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return err
+	}
+
+	return createNotification(tx, orgID, authorID, "post_not_published",
+		fmt.Sprintf("Your scheduled post \"%s\" could not be published and is back in your drafts", title),
+		gin.H{"chatboard_id": chatboardID, "post_id": postID})
+}
@@ -17,38 +17,39 @@ func NewLessonHandler(db *sql.DB) *LessonHandler {
 	return &LessonHandler{db: db}
 }
 
-func (h *LessonHandler) checkPermission(userID int) (bool, error) {
-	var hasPermission bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 FROM user_roles ur
-            JOIN roles r ON r.id = ur.role_id
-            WHERE ur.user_id = $1 
-            AND r.role IN ('Admin', 'Head Unicorn', 'Helper Unicorn')
-        )
-    `, userID).Scan(&hasPermission)
-
-	return hasPermission, err
+func (h *LessonHandler) checkPermission(userID, orgID int) (bool, error) {
+	return hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn", "Helper Unicorn")
 }
 
 func (h *LessonHandler) CreateLesson(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
-	// Check if user has permission
-	hasPermission, err := h.checkPermission(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+	var req models.CreateLessonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if !hasPermission {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only Admins, Head Unicorns, and Helper Unicorns can manage lessons"})
+	// Check if user has permission, either globally or as an admin of the
+	// course's country
+	hasPermission, err := h.checkPermission(userID, orgID)
+	if err == nil && !hasPermission {
+		err = h.db.QueryRow(`
+            SELECT EXISTS (
+                SELECT 1 FROM courses co
+                JOIN country_admins ca ON ca.country_id = co.country_id
+                WHERE co.id = $1 AND ca.user_id = $2
+            )
+        `, req.CourseID, userID).Scan(&hasPermission)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
 		return
 	}
 
-	var req models.CreateLessonRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Admins, Head Unicorns, Helper Unicorns and country admins can manage lessons"})
 		return
 	}
 
@@ -57,9 +58,9 @@ func (h *LessonHandler) CreateLesson(c *gin.Context) {
 	err = h.db.QueryRow(`
         SELECT EXISTS (
             SELECT 1 FROM courses 
-            WHERE id = $1
+            WHERE id = $1 AND organization_id = $2
         )
-    `, req.CourseID).Scan(&courseExists)
+    `, req.CourseID, orgID).Scan(&courseExists)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify course"})
@@ -96,6 +97,7 @@ func (h *LessonHandler) CreateLesson(c *gin.Context) {
 func (h *LessonHandler) GetLessons(c *gin.Context) {
 	// Get optional course_id filter from query params
 	courseID := c.Query("course_id")
+	orgID := c.GetInt("orgID")
 
 	var rows *sql.Rows
 	var err error
@@ -103,18 +105,21 @@ func (h *LessonHandler) GetLessons(c *gin.Context) {
 	if courseID != "" {
 		// If course_id is provided, filter lessons by course
 		rows, err = h.db.Query(`
-            SELECT id, course_id, title, description, created_at
-            FROM lessons
-            WHERE course_id = $1
-            ORDER BY created_at DESC
-        `, courseID)
+            SELECT l.id, l.course_id, l.title, l.description, l.created_at
+            FROM lessons l
+            JOIN courses co ON co.id = l.course_id
+            WHERE l.course_id = $1 AND co.organization_id = $2
+            ORDER BY l.created_at DESC
+        `, courseID, orgID)
 	} else {
 		// If no course_id, get all lessons
 		rows, err = h.db.Query(`
-            SELECT id, course_id, title, description, created_at
-            FROM lessons
-            ORDER BY created_at DESC
-        `)
+            SELECT l.id, l.course_id, l.title, l.description, l.created_at
+            FROM lessons l
+            JOIN courses co ON co.id = l.course_id
+            WHERE co.organization_id = $1
+            ORDER BY l.created_at DESC
+        `, orgID)
 	}
 
 	if err != nil {
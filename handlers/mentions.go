@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// maxMentionedUsers caps how many different users one text can mention, so
+// a post cannot notify a whole organization
+const maxMentionedUsers = 20
+
+// maxUsernameLength matches users.username
+const maxUsernameLength = 50
+
+// mentionToken is an @username found in a text. Start and Length are in
+// UTF-16 code units and include the @.
+type mentionToken struct {
+	Username string
+	Start    int
+	Length   int
+}
+
+// mentionStore is satisfied by both *sql.DB and *sql.Tx
+type mentionStore interface {
+	querier
+	execer
+}
+
+func isUsernameRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// parseMentions finds the @usernames in the text. An @ only starts a mention
+// at the beginning of the text or after a character that can't be part of a
+// username, so email addresses are not mentions.
+func parseMentions(text string) []mentionToken {
+	var tokens []mentionToken
+	offset := 0 // in UTF-16 code units
+	prev := rune(-1)
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '@' && (prev == -1 || !isUsernameRune(prev)) {
+			end := i + size
+			for end < len(text) {
+				next, nextSize := utf8.DecodeRuneInString(text[end:])
+				if !isUsernameRune(next) {
+					break
+				}
+				end += nextSize
+			}
+			// A sentence may end right after a mention
+			username := strings.TrimRight(text[i+size:end], ".-")
+			if username != "" && utf8.RuneCountInString(username) <= maxUsernameLength {
+				tokens = append(tokens, mentionToken{
+					Username: username,
+					Start:    offset,
+					Length:   1 + utf16Len(username),
+				})
+			}
+		}
+		offset += utf16.RuneLen(r)
+		prev = r
+		i += size
+	}
+	return tokens
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// saveMentions replaces the mentions of a post or comment with the ones in
+// its text. Usernames only resolve to members of the organization who can
+// see the chatboard; anything else stays plain text. It returns the users
+// mentioned for the first time, leaving out the author, for notifying.
+func saveMentions(db mentionStore, orgID, chatboardID, authorID int, target contentTarget, targetID int, text string) ([]int, error) {
+	previous := make(map[int]bool)
+	rows, err := db.Query(`
+        DELETE FROM mentions WHERE `+string(target)+` = $1
+        RETURNING user_id
+    `, targetID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous[userID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tokens := parseMentions(text)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, token := range tokens {
+		name := strings.ToLower(token.Username)
+		if !seen[name] && len(usernames) < maxMentionedUsers {
+			seen[name] = true
+			usernames = append(usernames, name)
+		}
+	}
+
+	users, err := resolveMentionedUsers(db, orgID, chatboardID, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	var notify []int
+	notified := make(map[int]bool)
+	for _, token := range tokens {
+		userID, ok := users[strings.ToLower(token.Username)]
+		if !ok {
+			continue
+		}
+		_, err := db.Exec(`
+            INSERT INTO mentions (`+string(target)+`, user_id, start_offset, length)
+            VALUES ($1, $2, $3, $4)
+        `, targetID, userID, token.Start, token.Length)
+		if err != nil {
+			return nil, err
+		}
+		if userID != authorID && !previous[userID] && !notified[userID] {
+			notified[userID] = true
+			notify = append(notify, userID)
+		}
+	}
+
+	return notify, nil
+}
+
+// resolveMentionedUsers maps lowercased usernames to the organization
+// members who can see the chatboard and are not banned from it. Usernames
+// shared by several such members are ambiguous and left out.
+func resolveMentionedUsers(db querier, orgID, chatboardID int, usernames []string) (map[string]int, error) {
+	rules, err := loadChatboardRules(db, orgID, chatboardID)
+	if err != nil {
+		return nil, err
+	}
+	rule, ok := rules[chatboardID]
+	if !ok {
+		return map[string]int{}, nil
+	}
+
+	rows, err := db.Query(`
+        SELECT u.id, LOWER(u.username)
+        FROM users u
+        JOIN organization_members om ON om.user_id = u.id AND om.organization_id = $2
+        WHERE LOWER(u.username) = ANY($1)
+    `, pq.Array(usernames), orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string][]int)
+	for rows.Next() {
+		var userID int
+		var username string
+		if err := rows.Scan(&userID, &username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates[username] = append(candidates[username], userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var userIDs []int
+	for _, ids := range candidates {
+		userIDs = append(userIDs, ids...)
+	}
+	subjects, err := loadAccessSubjects(db, orgID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	bans, err := chatboardBans(db, []int{chatboardID})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]int)
+	for username, ids := range candidates {
+		var visible []int
+		for _, userID := range ids {
+			if subjects[userID].matches(rule) && !bans[userID][chatboardID] {
+				visible = append(visible, userID)
+			}
+		}
+		if len(visible) == 1 {
+			users[username] = visible[0]
+		}
+	}
+	return users, nil
+}
+
+// notifyMentions tells the users they were mentioned by the author
+func notifyMentions(db mentionStore, orgID int, userIDs []int, authorID int, where string, data gin.H) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var author string
+	err := db.QueryRow("SELECT COALESCE(username, first_name) FROM users WHERE id = $1", authorID).Scan(&author)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s mentioned you in %s", author, where)
+	for _, userID := range userIDs {
+		if err := createNotification(db, orgID, userID, "mention", message, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMentions returns the mentions of each of the posts or comments with
+// one query. Every ID gets a list, empty when nobody is mentioned.
+func loadMentions(db querier, target contentTarget, ids []int) (map[int][]models.Mention, error) {
+	mentions := make(map[int][]models.Mention, len(ids))
+	for _, id := range ids {
+		mentions[id] = make([]models.Mention, 0)
+	}
+	if len(ids) == 0 {
+		return mentions, nil
+	}
+
+	rows, err := db.Query(`
+        SELECT m.`+string(target)+`, m.user_id, COALESCE(u.username, ''), m.start_offset, m.length
+        FROM mentions m
+        JOIN users u ON u.id = m.user_id
+        WHERE m.`+string(target)+` = ANY($1)
+        ORDER BY m.start_offset
+    `, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var mention models.Mention
+		if err := rows.Scan(&id, &mention.UserID, &mention.Username, &mention.Start, &mention.Length); err != nil {
+			return nil, err
+		}
+		mentions[id] = append(mentions[id], mention)
+	}
+
+	return mentions, rows.Err()
+}
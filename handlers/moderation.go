@@ -0,0 +1,1024 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// reportReasons are the categories members pick when reporting
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"self_harm":      true,
+	"sexual_content": true,
+	"misinformation": true,
+	"other":          true,
+}
+
+const (
+	maxReportDetailsLength = 2000
+	maxReportPreviewLength = 280
+	defaultSuspendDays     = 7
+	maxSuspendDays         = 365
+)
+
+type ModerationHandler struct {
+	db *sql.DB
+}
+
+func NewModerationHandler(db *sql.DB) *ModerationHandler {
+	return &ModerationHandler{db: db}
+}
+
+// reportTarget is the post, comment or user a report is about. UserID is the
+// reported user, or the author of the reported content.
+type reportTarget struct {
+	Type        string
+	PostID      sql.NullInt64
+	CommentID   sql.NullInt64
+	ChatboardID int
+	UserID      int
+	Removed     bool
+}
+
+// loadReportTarget finds the target within the organization. It returns
+// sql.ErrNoRows when there is no such target.
+func loadReportTarget(db queryRower, orgID int, targetType string, targetID int) (reportTarget, error) {
+	target := reportTarget{Type: targetType}
+	var err error
+
+	switch targetType {
+	case "post":
+		target.PostID = sql.NullInt64{Int64: int64(targetID), Valid: true}
+		err = db.QueryRow(`
+            SELECT p.chatboard_id, p.user_id, p.deleted_at IS NOT NULL
+            FROM posts p
+            JOIN chatboards cb ON cb.id = p.chatboard_id
+            WHERE p.id = $1 AND cb.organization_id = $2 AND p.status = 'published'
+        `, targetID, orgID).Scan(&target.ChatboardID, &target.UserID, &target.Removed)
+	case "comment":
+		target.CommentID = sql.NullInt64{Int64: int64(targetID), Valid: true}
+		err = db.QueryRow(`
+            SELECT p.chatboard_id, c.user_id, c.deleted_at IS NOT NULL
+            FROM comments c
+            JOIN posts p ON p.id = c.post_id
+            JOIN chatboards cb ON cb.id = p.chatboard_id
+            WHERE c.id = $1 AND cb.organization_id = $2
+        `, targetID, orgID).Scan(&target.ChatboardID, &target.UserID, &target.Removed)
+	case "user":
+		err = db.QueryRow(`
+            SELECT user_id FROM organization_members
+            WHERE user_id = $1 AND organization_id = $2
+        `, targetID, orgID).Scan(&target.UserID)
+	default:
+		return target, sql.ErrNoRows
+	}
+
+	return target, err
+}
+
+// ensureNotSuspended checks that the caller may post in the organization. It
+// writes the error response and returns false when they are suspended.
+func ensureNotSuspended(c *gin.Context, db queryRower) bool {
+	var until time.Time
+	err := db.QueryRow(`
+        SELECT suspended_until FROM organization_members
+        WHERE user_id = $1 AND organization_id = $2 AND suspended_until > NOW()
+    `, c.GetInt("userID"), c.GetInt("orgID")).Scan(&until)
+	if err == sql.ErrNoRows {
+		return true
+	} else if err != nil {
+		log.Printf("Error checking suspension: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":           "Your account is suspended from posting",
+		"suspended_until": until,
+	})
+	return false
+}
+
+// CreateReport flags a post, comment or user for the moderators
+func (h *ModerationHandler) CreateReport(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	var req models.CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TargetType != "post" && req.TargetType != "comment" && req.TargetType != "user" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type must be post, comment or user"})
+		return
+	}
+	if !reportReasons[req.Reason] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown report reason"})
+		return
+	}
+	if utf8.RuneCountInString(req.Details) > maxReportDetailsLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("details can be at most %d characters", maxReportDetailsLength)})
+		return
+	}
+
+	target, err := loadReportTarget(h.db, orgID, req.TargetType, req.TargetID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report target not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching report target: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit report"})
+		return
+	}
+
+	if target.Type != "user" {
+		hasAccess, err := canAccessChatboard(h.db, userID, orgID, target.ChatboardID)
+		if err != nil {
+			log.Printf("Error checking chatboard access: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboard access"})
+			return
+		}
+		if !hasAccess {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+			return
+		}
+		if target.Removed {
+			c.JSON(http.StatusConflict, gin.H{"error": "This content has already been removed"})
+			return
+		}
+	}
+
+	if target.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report yourself"})
+		return
+	}
+
+	var reportID int
+	err = h.db.QueryRow(`
+        INSERT INTO reports (organization_id, reporter_id, target_type, post_id, comment_id, user_id, reason, details)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `, orgID, userID, target.Type, target.PostID, target.CommentID, target.UserID, req.Reason, req.Details).Scan(&reportID)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this"})
+		return
+	} else if err != nil {
+		log.Printf("Error creating report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Report submitted successfully",
+		"id":      reportID,
+	})
+}
+
+// moderationScope tells what the caller moderates: everything for Admins,
+// otherwise the returned chatboards. It writes the error response and returns
+// false when the caller moderates nothing.
+func (h *ModerationHandler) moderationScope(c *gin.Context) (bool, []int, bool) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	isAdmin, err := hasGlobalRole(h.db, userID, orgID, "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false, nil, false
+	}
+	if isAdmin {
+		return true, nil, true
+	}
+
+	chatboardIDs, err := moderatedChatboardIDs(h.db, userID, orgID)
+	if err != nil {
+		log.Printf("Error fetching moderated chatboards: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false, nil, false
+	}
+	if len(chatboardIDs) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can use the moderation queue"})
+		return false, nil, false
+	}
+	return false, chatboardIDs, true
+}
+
+// GetReports is the moderation queue: open reports grouped by what they are
+// about, oldest first. Admins see every report in the organization; other
+// moderators see reports on content in the chatboards they moderate.
+func (h *ModerationHandler) GetReports(c *gin.Context) {
+	orgID := c.GetInt("orgID")
+
+	isAdmin, chatboardIDs, ok := h.moderationScope(c)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	query := `
+        WITH groups AS (
+            SELECT r.target_type, r.post_id, r.comment_id, r.user_id,
+                   COALESCE(p.chatboard_id, cp.chatboard_id) AS chatboard_id,
+                   MIN(r.created_at) AS first_reported_at,
+                   MAX(r.created_at) AS last_reported_at,
+                   MIN(r.id) AS first_id,
+                   ARRAY_AGG(r.id ORDER BY r.created_at, r.id) AS report_ids
+            FROM reports r
+            LEFT JOIN posts p ON p.id = r.post_id
+            LEFT JOIN comments cm ON cm.id = r.comment_id
+            LEFT JOIN posts cp ON cp.id = cm.post_id
+            WHERE r.organization_id = $1 AND r.status = 'open'
+            GROUP BY r.target_type, r.post_id, r.comment_id, r.user_id, p.chatboard_id, cp.chatboard_id
+        )
+        SELECT g.target_type, g.post_id, g.comment_id, g.chatboard_id, g.user_id,
+               COALESCE(u.username, ''),
+               COALESCE(p.title, cp.title, ''),
+               LEFT(COALESCE(p.content, cm.comment, ''), $2),
+               COALESCE(p.deleted_at, cm.deleted_at) IS NOT NULL,
+               g.first_reported_at, g.last_reported_at, g.first_id, g.report_ids
+        FROM groups g
+        JOIN users u ON u.id = g.user_id
+        LEFT JOIN posts p ON p.id = g.post_id
+        LEFT JOIN comments cm ON cm.id = g.comment_id
+        LEFT JOIN posts cp ON cp.id = cm.post_id
+        WHERE TRUE`
+	args := []interface{}{orgID, maxReportPreviewLength}
+
+	// User reports have no chatboard, so only Admins see them
+	if !isAdmin {
+		args = append(args, pq.Array(chatboardIDs))
+		query += fmt.Sprintf(" AND g.chatboard_id = ANY($%d)", len(args))
+	}
+
+	keyset, args := page.keyset("g.first_reported_at", "g.first_id", true, args)
+	query += keyset + " ORDER BY g.first_reported_at, g.first_id"
+	limit, args := page.limit(args)
+	query += limit
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	defer rows.Close()
+
+	groups := make([]models.ReportGroup, 0)
+	var (
+		cursors   []pageCursor
+		reportIDs [][]int64
+		allIDs    []int64
+	)
+	for rows.Next() {
+		var (
+			group       models.ReportGroup
+			postID      sql.NullInt64
+			commentID   sql.NullInt64
+			chatboardID sql.NullInt64
+			firstID     int
+			ids         []int64
+		)
+		err := rows.Scan(
+			&group.TargetType,
+			&postID,
+			&commentID,
+			&chatboardID,
+			&group.UserID,
+			&group.Username,
+			&group.Title,
+			&group.Preview,
+			&group.Removed,
+			&group.FirstReport,
+			&group.LastReport,
+			&firstID,
+			pq.Array(&ids),
+		)
+		if err != nil {
+			log.Printf("Error scanning report group: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+			return
+		}
+		group.PostID = nullIntPtr(postID)
+		group.CommentID = nullIntPtr(commentID)
+		group.ChatboardID = nullIntPtr(chatboardID)
+		group.ReportCount = len(ids)
+		groups = append(groups, group)
+		cursors = append(cursors, pageCursor{CreatedAt: group.FirstReport, ID: firstID})
+		reportIDs = append(reportIDs, ids)
+		allIDs = append(allIDs, ids...)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading report groups: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	reports, err := h.loadReports(allIDs)
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	for i := range groups {
+		groups[i].Reasons = make(map[string]int)
+		groups[i].Reports = make([]models.Report, 0, len(reportIDs[i]))
+		for _, id := range reportIDs[i] {
+			report := reports[int(id)]
+			groups[i].Reasons[report.Reason]++
+			groups[i].Reports = append(groups[i].Reports, report)
+		}
+	}
+
+	groups, next := trimPage(groups, page.Limit, func(i int) pageCursor { return cursors[i] })
+	c.JSON(http.StatusOK, pageResponse(groups, next))
+}
+
+func (h *ModerationHandler) loadReports(ids []int64) (map[int]models.Report, error) {
+	reports := make(map[int]models.Report, len(ids))
+	if len(ids) == 0 {
+		return reports, nil
+	}
+
+	rows, err := h.db.Query(`
+        SELECT r.id, r.reporter_id, COALESCE(u.username, ''), r.reason, r.details, r.created_at
+        FROM reports r
+        LEFT JOIN users u ON u.id = r.reporter_id
+        WHERE r.id = ANY($1)
+    `, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var report models.Report
+		var reporterID sql.NullInt64
+		if err := rows.Scan(&report.ID, &reporterID, &report.Reporter, &report.Reason, &report.Details, &report.CreatedAt); err != nil {
+			return nil, err
+		}
+		report.ReporterID = nullIntPtr(reporterID)
+		reports[report.ID] = report
+	}
+	return reports, rows.Err()
+}
+
+// ResolveReport acts on a report: dismiss it, hide the reported content, or
+// warn or suspend the reported user. The action closes every open report
+// about the same target and is kept in the moderation log.
+func (h *ModerationHandler) ResolveReport(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var req models.ModerateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.Action {
+	case "dismiss", "hide", "warn":
+	case "suspend":
+		if req.SuspendDays == 0 {
+			req.SuspendDays = defaultSuspendDays
+		}
+		if req.SuspendDays < 1 || req.SuspendDays > maxSuspendDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("suspend_days must be between 1 and %d", maxSuspendDays)})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be dismiss, hide, warn or suspend"})
+		return
+	}
+	if req.Action != "suspend" {
+		req.SuspendDays = 0
+	}
+
+	var (
+		targetType, status string
+		postID, commentID  sql.NullInt64
+		reportedUserID     int
+	)
+	err = h.db.QueryRow(`
+        SELECT target_type, post_id, comment_id, user_id, status
+        FROM reports
+        WHERE id = $1 AND organization_id = $2
+    `, reportID, orgID).Scan(&targetType, &postID, &commentID, &reportedUserID, &status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report"})
+		return
+	}
+	if status != "open" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Report is already closed"})
+		return
+	}
+
+	targetID := reportedUserID
+	switch {
+	case postID.Valid:
+		targetID = int(postID.Int64)
+	case commentID.Valid:
+		targetID = int(commentID.Int64)
+	}
+	target, err := loadReportTarget(h.db, orgID, targetType, targetID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report target not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching report target: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report"})
+		return
+	}
+
+	var allowed bool
+	if target.Type == "user" {
+		allowed, err = hasGlobalRole(h.db, userID, orgID, "Admin")
+	} else {
+		allowed, err = isChatboardModerator(h.db, userID, orgID, target.ChatboardID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can act on this report"})
+		return
+	}
+
+	if req.Action == "hide" && target.Type == "user" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only posts and comments can be hidden"})
+		return
+	}
+	if req.Action == "suspend" {
+		isAdmin, err := hasGlobalRole(h.db, target.UserID, orgID, "Admin")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admins cannot be suspended"})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+	defer tx.Rollback()
+
+	action := models.ModerationAction{
+		ModeratorID: &userID,
+		Action:      req.Action,
+		TargetType:  target.Type,
+		PostID:      nullIntPtr(target.PostID),
+		CommentID:   nullIntPtr(target.CommentID),
+		UserID:      &target.UserID,
+		Note:        req.Note,
+	}
+	var suspendedUntil sql.NullTime
+	err = tx.QueryRow(`
+        INSERT INTO moderation_actions (organization_id, moderator_id, action, target_type, post_id, comment_id, user_id, note, suspended_until)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+            CASE WHEN $9::INTEGER > 0 THEN NOW() + MAKE_INTERVAL(days => $9::INTEGER) END)
+        RETURNING id, suspended_until, created_at, (SELECT COALESCE(username, '') FROM users WHERE id = $2)
+    `, orgID, userID, req.Action, target.Type, target.PostID, target.CommentID, target.UserID, req.Note, req.SuspendDays).Scan(
+		&action.ID, &suspendedUntil, &action.CreatedAt, &action.Moderator,
+	)
+	if err != nil {
+		log.Printf("Error recording moderation action: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+	if suspendedUntil.Valid {
+		action.SuspendedUntil = &suspendedUntil.Time
+	}
+
+	newStatus := "resolved"
+	if req.Action == "dismiss" {
+		newStatus = "dismissed"
+	}
+	result, err := tx.Exec(`
+        UPDATE reports SET status = $1, action_id = $2
+        WHERE organization_id = $3 AND status = 'open'
+        AND target_type = $4
+        AND post_id IS NOT DISTINCT FROM $5
+        AND comment_id IS NOT DISTINCT FROM $6
+        AND user_id = $7
+    `, newStatus, action.ID, orgID, target.Type, target.PostID, target.CommentID, target.UserID)
+	if err != nil {
+		log.Printf("Error closing reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+	closed, _ := result.RowsAffected()
+	action.ReportCount = int(closed)
+
+	var event *realtime.Event
+	switch req.Action {
+	case "hide":
+		event, err = hideReportedContent(tx, target, userID)
+	case "warn":
+		message := "A moderator has warned you about your " + target.Type
+		if target.Type == "user" {
+			message = "A moderator has warned you about your behaviour"
+		}
+		err = createNotification(tx, orgID, target.UserID, "moderation_warning", message, gin.H{
+			"action_id": action.ID,
+			"note":      req.Note,
+		})
+	case "suspend":
+		_, err = tx.Exec(`
+            UPDATE organization_members SET suspended_until = $3
+            WHERE user_id = $1 AND organization_id = $2
+        `, target.UserID, orgID, suspendedUntil)
+		if err == nil {
+			err = createNotification(tx, orgID, target.UserID, "suspension",
+				fmt.Sprintf("You have been suspended from posting for %d days", req.SuspendDays), gin.H{
+					"action_id":       action.ID,
+					"note":            req.Note,
+					"suspended_until": action.SuspendedUntil,
+				})
+		}
+	}
+	if err != nil {
+		log.Printf("Error applying moderation action: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing moderation action: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+
+	if event != nil {
+		if err := realtime.Publish(h.db, *event); err != nil {
+			log.Printf("Error publishing moderation event: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, action)
+}
+
+// hideReportedContent removes the reported post or comment like a moderator
+// deleting it would. It returns the event to publish, nil when the content
+// was already removed.
+func hideReportedContent(tx *sql.Tx, target reportTarget, moderatorID int) (*realtime.Event, error) {
+	if target.Removed {
+		return nil, nil
+	}
+
+	if target.Type == "post" {
+		_, err := tx.Exec(`
+            UPDATE posts SET deleted_at = NOW(), deleted_by = $2,
+                pinned = FALSE, pin_order = 0, pinned_at = NULL, pinned_by = NULL, pin_expires_at = NULL
+            WHERE id = $1
+        `, target.PostID, moderatorID)
+		return &realtime.Event{
+			Type:        realtime.EventPostRemoved,
+			ChatboardID: target.ChatboardID,
+			PostID:      int(target.PostID.Int64),
+		}, err
+	}
+
+	var postID int
+	err := tx.QueryRow(`
+        UPDATE comments SET deleted_at = NOW(), deleted_by = $2
+        WHERE id = $1
+        RETURNING post_id
+    `, target.CommentID, moderatorID).Scan(&postID)
+	return &realtime.Event{
+		Type:        realtime.EventCommentRemoved,
+		ChatboardID: target.ChatboardID,
+		PostID:      postID,
+		CommentID:   int(target.CommentID.Int64),
+	}, err
+}
+
+// GetModerationActions is the moderation log, newest first. Only Admins can
+// read it. Pass user_id to see the actions taken against one user.
+func (h *ModerationHandler) GetModerationActions(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	isAdmin, err := hasGlobalRole(h.db, userID, orgID, "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view the moderation log"})
+		return
+	}
+
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	query := `
+        SELECT a.id, a.moderator_id, COALESCE(u.username, ''), a.action, a.target_type,
+               a.post_id, a.comment_id, a.chatboard_id, a.user_id, a.note, a.suspended_until, a.created_at,
+               (SELECT COUNT(*) FROM reports r WHERE r.action_id = a.id)
+        FROM moderation_actions a
+        LEFT JOIN users u ON u.id = a.moderator_id
+        WHERE a.organization_id = $1`
+	args := []interface{}{orgID}
+
+	if value := c.Query("user_id"); value != "" {
+		targetUserID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		args = append(args, targetUserID)
+		query += fmt.Sprintf(" AND a.user_id = $%d", len(args))
+	}
+
+	keyset, args := page.keyset("a.created_at", "a.id", false, args)
+	query += keyset + " ORDER BY a.created_at DESC, a.id DESC"
+	limit, args := page.limit(args)
+	query += limit
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching moderation actions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation log"})
+		return
+	}
+	defer rows.Close()
+
+	actions := make([]models.ModerationAction, 0)
+	for rows.Next() {
+		var (
+			action                                                    models.ModerationAction
+			moderatorID, postID, commentID, chatboardID, targetUserID sql.NullInt64
+			suspendedUntil                                            sql.NullTime
+		)
+		err := rows.Scan(
+			&action.ID,
+			&moderatorID,
+			&action.Moderator,
+			&action.Action,
+			&action.TargetType,
+			&postID,
+			&commentID,
+			&chatboardID,
+			&targetUserID,
+			&action.Note,
+			&suspendedUntil,
+			&action.CreatedAt,
+			&action.ReportCount,
+		)
+		if err != nil {
+			log.Printf("Error scanning moderation action: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation log"})
+			return
+		}
+		action.ModeratorID = nullIntPtr(moderatorID)
+		action.PostID = nullIntPtr(postID)
+		action.CommentID = nullIntPtr(commentID)
+		action.ChatboardID = nullIntPtr(chatboardID)
+		action.UserID = nullIntPtr(targetUserID)
+		if suspendedUntil.Valid {
+			action.SuspendedUntil = &suspendedUntil.Time
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading moderation actions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation log"})
+		return
+	}
+
+	actions, next := trimPage(actions, page.Limit, func(i int) pageCursor {
+		return pageCursor{CreatedAt: actions[i].CreatedAt, ID: actions[i].ID}
+	})
+	c.JSON(http.StatusOK, pageResponse(actions, next))
+}
+
+// GetHeldContent lists the posts and comments the content filter held,
+// oldest first, in the chatboards the caller moderates
+func (h *ModerationHandler) GetHeldContent(c *gin.Context) {
+	isAdmin, chatboardIDs, ok := h.moderationScope(c)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	// Posts and comments share one ordering, so their IDs are interleaved
+	// into a sort key that stays unique across both
+	query := `
+        WITH held AS (
+            SELECT 'post' AS type, p.id, p.id AS post_id, p.chatboard_id, p.user_id,
+                   p.title, p.content AS text, p.held_reasons, p.created_at, p.held_at,
+                   p.id * 2 AS sort_key
+            FROM posts p
+            JOIN chatboards cb ON cb.id = p.chatboard_id
+            WHERE cb.organization_id = $1
+            AND p.held_at IS NOT NULL AND p.deleted_at IS NULL
+            UNION ALL
+            SELECT 'comment', cm.id, cm.post_id, p.chatboard_id, cm.user_id,
+                   p.title, cm.comment, cm.held_reasons, cm.created_at, cm.held_at,
+                   cm.id * 2 + 1
+            FROM comments cm
+            JOIN posts p ON p.id = cm.post_id
+            JOIN chatboards cb ON cb.id = p.chatboard_id
+            WHERE cb.organization_id = $1
+            AND cm.held_at IS NOT NULL AND cm.deleted_at IS NULL
+        )
+        SELECT h.type, h.id, h.post_id, h.chatboard_id, h.user_id, COALESCE(u.username, ''),
+               h.title, h.text, COALESCE(h.held_reasons, '[]'), h.created_at, h.held_at, h.sort_key
+        FROM held h
+        JOIN users u ON u.id = h.user_id
+        WHERE TRUE`
+	args := []interface{}{c.GetInt("orgID")}
+
+	if !isAdmin {
+		args = append(args, pq.Array(chatboardIDs))
+		query += fmt.Sprintf(" AND h.chatboard_id = ANY($%d)", len(args))
+	}
+
+	keyset, args := page.keyset("h.held_at", "h.sort_key", true, args)
+	query += keyset + " ORDER BY h.held_at, h.sort_key"
+	limit, args := page.limit(args)
+	query += limit
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching held content: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch held content"})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.HeldContent, 0)
+	var cursors []pageCursor
+	for rows.Next() {
+		var (
+			item    models.HeldContent
+			reasons []byte
+			sortKey int
+		)
+		err := rows.Scan(
+			&item.Type,
+			&item.ID,
+			&item.PostID,
+			&item.ChatboardID,
+			&item.UserID,
+			&item.Author,
+			&item.Title,
+			&item.Text,
+			&reasons,
+			&item.CreatedAt,
+			&item.HeldAt,
+			&sortKey,
+		)
+		if err == nil {
+			err = json.Unmarshal(reasons, &item.Reasons)
+		}
+		if err != nil {
+			log.Printf("Error scanning held content: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch held content"})
+			return
+		}
+		items = append(items, item)
+		cursors = append(cursors, pageCursor{CreatedAt: item.HeldAt, ID: sortKey})
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading held content: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch held content"})
+		return
+	}
+
+	items, next := trimPage(items, page.Limit, func(i int) pageCursor { return cursors[i] })
+	c.JSON(http.StatusOK, pageResponse(items, next))
+}
+
+// ApprovePost publishes a held post
+func (h *ModerationHandler) ApprovePost(c *gin.Context) {
+	h.reviewHeld(c, postTarget, true)
+}
+
+// RejectPost removes a held post without publishing it
+func (h *ModerationHandler) RejectPost(c *gin.Context) {
+	h.reviewHeld(c, postTarget, false)
+}
+
+// ApproveComment publishes a held comment
+func (h *ModerationHandler) ApproveComment(c *gin.Context) {
+	h.reviewHeld(c, commentTarget, true)
+}
+
+// RejectComment removes a held comment without publishing it
+func (h *ModerationHandler) RejectComment(c *gin.Context) {
+	h.reviewHeld(c, commentTarget, false)
+}
+
+// reviewHeld approves or rejects the held post or comment from the path.
+// Approving publishes it and sends the mention notifications it held back;
+// rejecting removes it. The author is told either way, and the decision is
+// kept in the moderation log.
+func (h *ModerationHandler) reviewHeld(c *gin.Context, target contentTarget, approve bool) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	kind, table := "post", "posts"
+	if target == commentTarget {
+		kind, table = "comment", "comments"
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + kind + " ID"})
+		return
+	}
+
+	var req models.ReviewHeldContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var postID, chatboardID, authorID int
+	if target == postTarget {
+		err = h.db.QueryRow(`
+            SELECT p.id, p.chatboard_id, p.user_id
+            FROM posts p
+            JOIN chatboards cb ON cb.id = p.chatboard_id
+            WHERE p.id = $1 AND cb.organization_id = $2
+            AND p.held_at IS NOT NULL AND p.deleted_at IS NULL
+        `, id, orgID).Scan(&postID, &chatboardID, &authorID)
+	} else {
+		err = h.db.QueryRow(`
+            SELECT cm.post_id, p.chatboard_id, cm.user_id
+            FROM comments cm
+            JOIN posts p ON p.id = cm.post_id
+            JOIN chatboards cb ON cb.id = p.chatboard_id
+            WHERE cm.id = $1 AND cb.organization_id = $2
+            AND cm.held_at IS NOT NULL AND cm.deleted_at IS NULL
+        `, id, orgID).Scan(&postID, &chatboardID, &authorID)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Held " + kind + " not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching held %s: %v", kind, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + kind})
+		return
+	}
+
+	isModerator, err := isChatboardModerator(h.db, userID, orgID, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !isModerator {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can review held content"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review " + kind})
+		return
+	}
+	defer tx.Rollback()
+
+	data := gin.H{"chatboard_id": chatboardID, "post_id": postID}
+	if target == commentTarget {
+		data["comment_id"] = id
+	}
+
+	action := "reject"
+	if approve {
+		action = "approve"
+		_, err = tx.Exec("UPDATE "+table+" SET held_at = NULL, held_reasons = NULL WHERE id = $1", id)
+		if err == nil {
+			err = notifyHeldMentions(tx, orgID, target, id, authorID, "a "+kind, data)
+		}
+		if err == nil {
+			err = createNotification(tx, orgID, authorID, "content_approved", "A moderator approved your "+kind, data)
+		}
+	} else {
+		_, err = tx.Exec("UPDATE "+table+" SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1", id, userID)
+		if err == nil {
+			data["note"] = req.Note
+			err = createNotification(tx, orgID, authorID, "content_rejected", "A moderator rejected your "+kind, data)
+		}
+	}
+	if err != nil {
+		log.Printf("Error reviewing held %s: %v", kind, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review " + kind})
+		return
+	}
+
+	var commentID sql.NullInt64
+	if target == commentTarget {
+		commentID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+	var actionID int
+	err = tx.QueryRow(`
+        INSERT INTO moderation_actions (organization_id, moderator_id, action, target_type, post_id, comment_id, user_id, note)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `, orgID, userID, action, kind, postID, commentID, authorID, req.Note).Scan(&actionID)
+	if err != nil {
+		log.Printf("Error recording moderation action: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review " + kind})
+		return
+	}
+
+	if approve {
+		event := realtime.Event{Type: realtime.EventPostCreated, ChatboardID: chatboardID, PostID: postID}
+		if target == commentTarget {
+			event.Type = realtime.EventCommentCreated
+			event.CommentID = id
+		}
+		if err := realtime.Publish(tx, event); err != nil {
+			log.Printf("Error publishing %s event: %v", kind, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review " + kind})
+		return
+	}
+
+	message := "Post approved successfully"
+	switch {
+	case target == postTarget && !approve:
+		message = "Post rejected successfully"
+	case target == commentTarget && approve:
+		message = "Comment approved successfully"
+	case target == commentTarget:
+		message = "Comment rejected successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "action_id": actionID})
+}
+
+// notifyHeldMentions notifies the users mentioned in an approved post or
+// comment, or in a published draft, which were held back while it waited
+func notifyHeldMentions(tx *sql.Tx, orgID int, target contentTarget, id, authorID int, where string, data gin.H) error {
+	rows, err := tx.Query(`
+        SELECT DISTINCT user_id FROM mentions
+        WHERE `+string(target)+` = $1 AND user_id <> $2
+    `, id, authorID)
+	if err != nil {
+		return err
+	}
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return notifyMentions(tx, orgID, userIDs, authorID, where, data)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	db *sql.DB
+}
+
+func NewNotificationHandler(db *sql.DB) *NotificationHandler {
+	return &NotificationHandler{db: db}
+}
+
+// createNotification stores a notification for the user in the organization
+// it belongs to. Passing a transaction makes the notification part of the
+// change that caused it.
+func createNotification(db execer, orgID, userID int, notificationType, message string, data gin.H) error {
+	var payload []byte
+	if data != nil {
+		var err error
+		if payload, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO notifications (organization_id, user_id, type, message, data)
+		VALUES ($1, $2, $3, $4, $5)
+	`, orgID, userID, notificationType, message, payload)
+	return err
+}
+
+// GetNotifications returns the user's most recent notifications in the
+// current organization. Pass unread=true to only return unread ones.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	query := `
+		SELECT id, type, message, data, read_at IS NOT NULL, created_at
+		FROM notifications
+		WHERE user_id = $1 AND organization_id = $2
+	`
+	if c.Query("unread") == "true" {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT 100"
+
+	rows, err := h.db.Query(query, userID, orgID)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	defer rows.Close()
+
+	notifications := make([]models.NotificationResponse, 0)
+	for rows.Next() {
+		var notification models.NotificationResponse
+		var data []byte
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.Type,
+			&notification.Message,
+			&data,
+			&notification.Read,
+			&notification.CreatedAt,
+		); err != nil {
+			log.Printf("Error scanning notification: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+		if len(data) > 0 {
+			notification.Data = json.RawMessage(data)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// MarkNotificationRead marks a single notification as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+	notificationID := c.Param("id")
+
+	result, err := h.db.Exec(`
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND user_id = $2 AND organization_id = $3
+	`, notificationID, userID, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify update"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user in
+// the current organization as read
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	result, err := h.db.Exec(`
+		UPDATE notifications
+		SET read_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND organization_id = $2 AND read_at IS NULL
+	`, userID, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notifications marked as read",
+		"count":   rowsAffected,
+	})
+}
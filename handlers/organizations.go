@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// slugPattern accepts lowercase slugs like "estonia" or "north-chapter"
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type OrganizationHandler struct {
+	db *sql.DB
+}
+
+func NewOrganizationHandler(db *sql.DB) *OrganizationHandler {
+	return &OrganizationHandler{db: db}
+}
+
+// CreateOrganization creates a new organization. Only super admins can do this.
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	if !c.GetBool("isSuperAdmin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only super admins can create organizations"})
+		return
+	}
+
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !slugPattern.MatchString(req.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug may only contain lowercase letters, digits and dashes"})
+		return
+	}
+
+	org := models.OrganizationResponse{Name: req.Name, Slug: req.Slug}
+	var createdAt sql.NullTime
+	err := h.db.QueryRow(`
+		INSERT INTO organizations (name, slug)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, req.Name, req.Slug).Scan(&org.ID, &createdAt)
+
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An organization with this slug already exists"})
+		return
+	} else if err != nil {
+		log.Printf("Error creating organization: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	if createdAt.Valid {
+		org.CreatedAt = createdAt.Time.Format("2006-01-02")
+	}
+
+	c.JSON(http.StatusCreated, org)
+}
+
+// GetOrganizations lists the organizations the user belongs to. Super admins
+// see every organization.
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT o.id, o.name, o.slug, o.created_at
+		FROM organizations o
+		WHERE $2 OR EXISTS (
+			SELECT 1 FROM organization_members om
+			WHERE om.organization_id = o.id AND om.user_id = $1
+		)
+		ORDER BY o.name
+	`, c.GetInt("userID"), c.GetBool("isSuperAdmin"))
+	if err != nil {
+		log.Printf("Error fetching organizations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+	defer rows.Close()
+
+	organizations := make([]models.OrganizationResponse, 0)
+	for rows.Next() {
+		var org models.OrganizationResponse
+		var createdAt sql.NullTime
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &createdAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan organization"})
+			return
+		}
+		if createdAt.Valid {
+			org.CreatedAt = createdAt.Time.Format("2006-01-02")
+		}
+		organizations = append(organizations, org)
+	}
+
+	c.JSON(http.StatusOK, organizations)
+}
+
+// GetOrganizationMembers lists the members of an organization with their global roles
+func (h *OrganizationHandler) GetOrganizationMembers(c *gin.Context) {
+	orgID, ok := h.authorizeOrganizationAdmin(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT
+			u.id,
+			COALESCE(u.username, ''),
+			u.first_name,
+			u.last_name,
+			u.email,
+			om.created_at,
+			COALESCE(ARRAY_AGG(DISTINCT r.role) FILTER (WHERE r.role IS NOT NULL), ARRAY[]::VARCHAR[])
+		FROM organization_members om
+		JOIN users u ON u.id = om.user_id
+		LEFT JOIN user_roles ur ON ur.user_id = u.id AND ur.organization_id = om.organization_id
+		LEFT JOIN roles r ON r.id = ur.role_id
+		WHERE om.organization_id = $1
+		GROUP BY u.id, u.username, u.first_name, u.last_name, u.email, om.created_at
+		ORDER BY u.first_name, u.last_name
+	`, orgID)
+	if err != nil {
+		log.Printf("Error fetching organization members: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization members"})
+		return
+	}
+	defer rows.Close()
+
+	members := make([]models.OrganizationMemberResponse, 0)
+	for rows.Next() {
+		var member models.OrganizationMemberResponse
+		var joinedAt sql.NullTime
+		if err := rows.Scan(
+			&member.UserID,
+			&member.Username,
+			&member.FirstName,
+			&member.LastName,
+			&member.Email,
+			&joinedAt,
+			pq.Array(&member.Roles),
+		); err != nil {
+			log.Printf("Error scanning organization member: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization members"})
+			return
+		}
+		if joinedAt.Valid {
+			member.JoinedAt = joinedAt.Time.Format("2006-01-02")
+		}
+		members = append(members, member)
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddOrganizationMember adds an existing user to the organization
+func (h *OrganizationHandler) AddOrganizationMember(c *gin.Context) {
+	orgID, ok := h.authorizeOrganizationAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var userExists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", req.UserID).Scan(&userExists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user existence"})
+		return
+	}
+	if !userExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO organization_members (organization_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (organization_id, user_id) DO NOTHING
+	`, orgID, req.UserID)
+	if err != nil {
+		log.Printf("Error adding organization member: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add organization member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Member added successfully"})
+}
+
+// RemoveOrganizationMember removes a user from the organization together
+// with the global roles they held in it
+func (h *OrganizationHandler) RemoveOrganizationMember(c *gin.Context) {
+	orgID, ok := h.authorizeOrganizationAdmin(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		DELETE FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2
+	`, orgID, memberID)
+	if err != nil {
+		log.Printf("Error removing organization member: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove organization member"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify removal"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this organization"})
+		return
+	}
+
+	if _, err = tx.Exec(`
+		DELETE FROM user_roles
+		WHERE organization_id = $1 AND user_id = $2
+	`, orgID, memberID); err != nil {
+		log.Printf("Error removing organization roles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove organization member"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// authorizeOrganizationAdmin parses the organization ID from the URL and
+// checks that the caller is an Admin of it or a super admin. It writes the
+// error response itself.
+func (h *OrganizationHandler) authorizeOrganizationAdmin(c *gin.Context) (int, bool) {
+	orgID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return 0, false
+	}
+
+	var exists bool
+	err = h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM organizations WHERE id = $1)", orgID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify organization"})
+		return 0, false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return 0, false
+	}
+
+	isAdmin, err := hasGlobalRole(h.db, c.GetInt("userID"), orgID, "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify admin status"})
+		return 0, false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins of this organization can manage its members"})
+		return 0, false
+	}
+
+	return orgID, true
+}
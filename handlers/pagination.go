@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor points at the last row of a page. Lists are ordered by
+// (created_at, id), posts by pinned and pin order first and search results by
+// rank first. Clients get it as an opaque string and pass it back as cursor to
+// fetch the next page.
+type pageCursor struct {
+	Pinned    bool      `json:"p,omitempty"`
+	PinOrder  int       `json:"o,omitempty"`
+	Rank      float32   `json:"r,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+func (pc pageCursor) encode() string {
+	data, _ := json.Marshal(pc)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageCursor(s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var pc pageCursor
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return nil, err
+	}
+	return &pc, nil
+}
+
+// pageQuery is the limit and cursor of a paginated list request
+type pageQuery struct {
+	Limit int
+	After *pageCursor
+}
+
+// parsePageQuery reads the limit and cursor query parameters. It writes the
+// error response and returns false when they are invalid.
+func parsePageQuery(c *gin.Context) (pageQuery, bool) {
+	page := pageQuery{Limit: defaultPageLimit}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxPageLimit)})
+			return page, false
+		}
+		page.Limit = n
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := decodePageCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return page, false
+		}
+		page.After = after
+	}
+
+	return page, true
+}
+
+// keyset returns the condition selecting the rows after the cursor for a list
+// ordered by the created_at and id columns, and the args with the cursor's
+// values appended. Without a cursor the condition is empty.
+func (p pageQuery) keyset(createdAtColumn, idColumn string, ascending bool, args []interface{}) (string, []interface{}) {
+	if p.After == nil {
+		return "", args
+	}
+
+	op := "<"
+	if ascending {
+		op = ">"
+	}
+	condition := fmt.Sprintf(" AND (%s, %s) %s ($%d, $%d)", createdAtColumn, idColumn, op, len(args)+1, len(args)+2)
+	return condition, append(args, p.After.CreatedAt, p.After.ID)
+}
+
+// limit returns the LIMIT clause. One row more than the page is fetched to
+// know whether there is a next page.
+func (p pageQuery) limit(args []interface{}) (string, []interface{}) {
+	return fmt.Sprintf(" LIMIT $%d", len(args)+1), append(args, p.Limit+1)
+}
+
+// trimPage drops the extra row fetched by limit and returns the cursor of
+// the page's last row, or nil on the last page.
+func trimPage[T any](items []T, limit int, cursorAt func(i int) pageCursor) ([]T, *string) {
+	if len(items) <= limit {
+		return items, nil
+	}
+
+	next := cursorAt(limit - 1).encode()
+	return items[:limit], &next
+}
+
+// pageResponse is the body of every paginated list
+func pageResponse(items interface{}, nextCursor *string) gin.H {
+	return gin.H{
+		"items":       items,
+		"next_cursor": nextCursor,
+	}
+}
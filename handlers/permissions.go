@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"github.com/lib/pq"
+)
+
+// hasGlobalRole reports whether the user holds any of the given global roles
+// within the organization. Super admins hold every role in every organization.
+func hasGlobalRole(db queryRower, userID, orgID int, roles ...string) (bool, error) {
+	var hasRole bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users WHERE id = $1 AND is_super_admin
+		) OR EXISTS (
+			SELECT 1 FROM user_roles ur
+			JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = $1
+			AND ur.organization_id = $2
+			AND r.role = ANY($3)
+		)
+	`, userID, orgID, pq.Array(roles)).Scan(&hasRole)
+
+	return hasRole, err
+}
+
+// isSquadLeader reports whether the user is an approved member and Head
+// Unicorn of the squad
+func isSquadLeader(db queryRower, userID, squadID int) (bool, error) {
+	var isLeader bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM user_squad_roles usr
+			JOIN roles r ON r.id = usr.role_id
+			JOIN user_squads us ON us.user_id = usr.user_id AND us.squad_id = usr.squad_id
+			WHERE usr.user_id = $1
+			AND usr.squad_id = $2
+			AND us.status = 'Approved'
+			AND r.role = 'Head Unicorn'
+		)
+	`, userID, squadID).Scan(&isLeader)
+
+	return isLeader, err
+}
+
+// isCountryAdmin reports whether the user is an admin of the country
+func isCountryAdmin(db queryRower, userID, countryID int) (bool, error) {
+	var isAdmin bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM country_admins
+			WHERE user_id = $1 AND country_id = $2
+		)
+	`, userID, countryID).Scan(&isAdmin)
+
+	return isAdmin, err
+}
+
+// isCountryAdminOfAll reports whether the user is an admin of every given
+// country. An empty list is never covered, so country admins cannot manage
+// resources that are not tied to a country.
+func isCountryAdminOfAll(db queryRower, userID int, countryIDs []int) (bool, error) {
+	if len(countryIDs) == 0 {
+		return false, nil
+	}
+
+	var missing int
+	err := db.QueryRow(`
+		SELECT COUNT(*)
+		FROM UNNEST($2::INTEGER[]) AS requested(country_id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM country_admins ca
+			WHERE ca.user_id = $1 AND ca.country_id = requested.country_id
+		)
+	`, userID, pq.Array(countryIDs)).Scan(&missing)
+
+	return err == nil && missing == 0, err
+}
+
+// isSquadCountryAdmin reports whether the user is an admin of the squad's country
+func isSquadCountryAdmin(db queryRower, userID, squadID int) (bool, error) {
+	var isAdmin bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM squads s
+			JOIN country_admins ca ON ca.country_id = s.country_id
+			WHERE ca.user_id = $1 AND s.id = $2
+		)
+	`, userID, squadID).Scan(&isAdmin)
+
+	return isAdmin, err
+}
+
+// isSquadCountryAdminOfAll reports whether the user is an admin of the
+// country of every given squad. Squads without a country are never covered.
+func isSquadCountryAdminOfAll(db queryRower, userID int, squadIDs []int) (bool, error) {
+	var missing int
+	err := db.QueryRow(`
+		SELECT COUNT(*)
+		FROM UNNEST($2::INTEGER[]) AS requested(squad_id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM squads s
+			JOIN country_admins ca ON ca.country_id = s.country_id
+			WHERE ca.user_id = $1 AND s.id = requested.squad_id
+		)
+	`, userID, pq.Array(squadIDs)).Scan(&missing)
+
+	return err == nil && missing == 0, err
+}
+
+// canManageSquad reports whether the user may manage the squad's details and
+// members: global Admins and Head Unicorns, admins of the squad's country, or
+// a Head Unicorn of the squad
+func canManageSquad(db queryRower, userID, orgID, squadID int) (bool, error) {
+	allowed, err := hasGlobalRole(db, userID, orgID, "Admin", "Head Unicorn")
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	allowed, err = isSquadCountryAdmin(db, userID, squadID)
+	if err != nil || allowed {
+		return allowed, err
+	}
+
+	return isSquadLeader(db, userID, squadID)
+}
+
+// isChatboardModerator reports whether the user can moderate the chatboard's
+// content: organization Admins, the board's owner and holders of an Admin or
+// Moderator role the board is shared with.
+func isChatboardModerator(db queryRower, userID, orgID, chatboardID int) (bool, error) {
+	var isModerator bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users WHERE id = $1 AND is_super_admin
+		) OR EXISTS (
+			SELECT 1 FROM user_roles ur
+			JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = $1
+			AND ur.organization_id = $2
+			AND r.role = 'Admin'
+		) OR EXISTS (
+			SELECT 1 FROM chatboards
+			WHERE id = $3 AND organization_id = $2 AND creator_id = $1
+		) OR EXISTS (
+			SELECT 1 FROM chatboard_roles cbr
+			JOIN user_roles ur ON ur.role_id = cbr.role_id
+			JOIN roles r ON r.id = ur.role_id
+			WHERE cbr.chatboard_id = $3
+			AND ur.user_id = $1
+			AND ur.organization_id = $2
+			AND r.role IN ('Admin', 'Moderator')
+		)
+	`, userID, orgID, chatboardID).Scan(&isModerator)
+
+	return isModerator, err
+}
+
+// moderatedChatboardIDs returns the organization's chatboards the user
+// moderates through ownership or a shared Admin or Moderator role. Admins
+// moderate every chatboard and are better checked with hasGlobalRole.
+func moderatedChatboardIDs(db querier, userID, orgID int) ([]int, error) {
+	rows, err := db.Query(`
+		SELECT cb.id FROM chatboards cb
+		WHERE cb.organization_id = $2
+		AND (cb.creator_id = $1 OR EXISTS (
+			SELECT 1 FROM chatboard_roles cbr
+			JOIN user_roles ur ON ur.role_id = cbr.role_id
+			JOIN roles r ON r.id = ur.role_id
+			WHERE cbr.chatboard_id = cb.id
+			AND ur.user_id = $1
+			AND ur.organization_id = $2
+			AND r.role IN ('Admin', 'Moderator')
+		))
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// pinTarget is the post from the path of a pin request
+type pinTarget struct {
+	ID          int
+	ChatboardID int
+	Pinned      bool
+}
+
+// getPinTarget loads the post from the path and checks that the caller
+// moderates its chatboard. Removed and held posts can't be pinned. It writes
+// the error response and returns false otherwise.
+func (h *PostHandler) getPinTarget(c *gin.Context) (pinTarget, bool) {
+	var post pinTarget
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return post, false
+	}
+
+	err = h.db.QueryRow(`
+        SELECT p.id, p.chatboard_id, COALESCE(p.pinned, FALSE)
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+        AND p.deleted_at IS NULL AND p.held_at IS NULL AND p.status = 'published'
+    `, postID, c.GetInt("orgID")).Scan(&post.ID, &post.ChatboardID, &post.Pinned)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return post, false
+	} else if err != nil {
+		log.Printf("Error fetching post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return post, false
+	}
+
+	if !h.authorizePinning(c, post.ChatboardID) {
+		return post, false
+	}
+	return post, true
+}
+
+// authorizePinning checks that the chatboard isn't archived and that the
+// caller moderates it. It writes the error response and returns false
+// otherwise.
+func (h *PostHandler) authorizePinning(c *gin.Context, chatboardID int) bool {
+	archived, err := isChatboardArchived(h.db, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard"})
+		return false
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return false
+	}
+
+	isModerator, err := isChatboardModerator(h.db, c.GetInt("userID"), c.GetInt("orgID"), chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false
+	}
+	if !isModerator {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can pin and unpin posts"})
+		return false
+	}
+	return true
+}
+
+// lockPins locks the chatboard's pins for the rest of the transaction and
+// returns the pinned posts in order
+func lockPins(tx *sql.Tx, chatboardID int) ([]int, error) {
+	if _, err := tx.Exec("SELECT 1 FROM chatboards WHERE id = $1 FOR UPDATE", chatboardID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+        SELECT id FROM posts
+        WHERE chatboard_id = $1 AND pinned
+        ORDER BY pin_order, pinned_at, id
+    `, chatboardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// savePinOrder numbers the pinned posts from 1 in the given order
+func savePinOrder(tx *sql.Tx, postIDs []int) error {
+	_, err := tx.Exec(`
+        UPDATE posts p SET pin_order = o.position
+        FROM UNNEST($1::INTEGER[]) WITH ORDINALITY AS o(id, position)
+        WHERE p.id = o.id AND p.pin_order <> o.position
+    `, pq.Array(postIDs))
+	return err
+}
+
+// PinPost pins a post, or updates the expiry and position of a pinned one.
+// The body is optional: {"expires_at": "...", "position": 1}.
+func (h *PostHandler) PinPost(c *gin.Context) {
+	post, ok := h.getPinTarget(c)
+	if !ok {
+		return
+	}
+
+	var req models.PinPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	if req.Position != nil && *req.Position < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "position must be at least 1"})
+		return
+	}
+
+	h.pin(c, post, req)
+}
+
+func (h *PostHandler) pin(c *gin.Context, post pinTarget, req models.PinPostRequest) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+	defer tx.Rollback()
+
+	pinned, err := lockPins(tx, post.ChatboardID)
+	if err != nil {
+		log.Printf("Error fetching pinned posts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+
+	// Take the post out of the order, remembering its place
+	position := -1
+	others := make([]int, 0, len(pinned))
+	for i, id := range pinned {
+		if id == post.ID {
+			position = i
+			continue
+		}
+		others = append(others, id)
+	}
+	wasPinned := position >= 0
+
+	if !wasPinned && len(others) >= h.maxPinned {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      fmt.Sprintf("A chatboard can have at most %d pinned posts; unpin one first", h.maxPinned),
+			"max_pinned": h.maxPinned,
+		})
+		return
+	}
+
+	if req.Position != nil {
+		position = *req.Position - 1
+	}
+	if position < 0 || position > len(others) {
+		position = len(others)
+	}
+	order := append(append(append([]int{}, others[:position]...), post.ID), others[position:]...)
+
+	_, err = tx.Exec(`
+        UPDATE posts
+        SET pinned = TRUE,
+            pinned_at = CASE WHEN pinned THEN pinned_at ELSE NOW() END,
+            pinned_by = CASE WHEN pinned THEN pinned_by ELSE $2 END,
+            pin_expires_at = $3
+        WHERE id = $1
+    `, post.ID, c.GetInt("userID"), req.ExpiresAt)
+	if err == nil {
+		err = savePinOrder(tx, order)
+	}
+	if err != nil {
+		log.Printf("Error pinning post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+
+	pinnedNow := true
+	if err := realtime.Publish(tx, realtime.Event{
+		Type:        realtime.EventPostPinned,
+		ChatboardID: post.ChatboardID,
+		PostID:      post.ID,
+		Pinned:      &pinnedNow,
+	}); err != nil {
+		log.Printf("Error publishing pin event: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing pin: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Post pinned successfully",
+		"pinned":         true,
+		"position":       position + 1,
+		"pin_expires_at": req.ExpiresAt,
+	})
+}
+
+// UnpinPost unpins a post. Unpinning a post that isn't pinned succeeds.
+func (h *PostHandler) UnpinPost(c *gin.Context) {
+	post, ok := h.getPinTarget(c)
+	if !ok {
+		return
+	}
+
+	h.unpin(c, post)
+}
+
+func (h *PostHandler) unpin(c *gin.Context, post pinTarget) {
+	result, err := h.db.Exec(`
+        UPDATE posts
+        SET pinned = FALSE, pin_order = 0, pinned_at = NULL, pinned_by = NULL, pin_expires_at = NULL
+        WHERE id = $1 AND pinned
+    `, post.ID)
+	if err != nil {
+		log.Printf("Error unpinning post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin post"})
+		return
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		pinned := false
+		if err := realtime.Publish(h.db, realtime.Event{
+			Type:        realtime.EventPostPinned,
+			ChatboardID: post.ChatboardID,
+			PostID:      post.ID,
+			Pinned:      &pinned,
+		}); err != nil {
+			log.Printf("Error publishing pin event: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post unpinned successfully",
+		"pinned":  false,
+	})
+}
+
+// ReorderPins sets the order of a chatboard's pinned posts. post_ids must list
+// every pinned post exactly once, top first.
+func (h *PostHandler) ReorderPins(c *gin.Context) {
+	chatboardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+		return
+	}
+
+	var req models.ReorderPinsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	err = h.db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM chatboards WHERE id = $1 AND organization_id = $2)
+    `, chatboardID, c.GetInt("orgID")).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chatboard not found"})
+		return
+	}
+
+	if !h.authorizePinning(c, chatboardID) {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder pinned posts"})
+		return
+	}
+	defer tx.Rollback()
+
+	pinned, err := lockPins(tx, chatboardID)
+	if err != nil {
+		log.Printf("Error fetching pinned posts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder pinned posts"})
+		return
+	}
+
+	remaining := make(map[int]bool, len(pinned))
+	for _, id := range pinned {
+		remaining[id] = true
+	}
+	for _, id := range req.PostIDs {
+		if !remaining[id] {
+			break
+		}
+		delete(remaining, id)
+	}
+	if len(req.PostIDs) != len(pinned) || len(remaining) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "post_ids must list every pinned post of the chatboard exactly once",
+			"pinned": pinned,
+		})
+		return
+	}
+
+	if err := savePinOrder(tx, req.PostIDs); err != nil {
+		log.Printf("Error reordering pinned posts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder pinned posts"})
+		return
+	}
+
+	if err := realtime.Publish(tx, realtime.Event{
+		Type:        realtime.EventPinsReordered,
+		ChatboardID: chatboardID,
+	}); err != nil {
+		log.Printf("Error publishing pin event: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing pin order: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder pinned posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Pinned posts reordered successfully",
+		"post_ids": req.PostIDs,
+	})
+}
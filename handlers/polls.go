@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	maxPollOptionLength = 200 // matches poll_options.text
+)
+
+type PollHandler struct {
+	db       *sql.DB
+	voterKey []byte
+}
+
+func NewPollHandler(db *sql.DB) *PollHandler {
+	return &PollHandler{db: db, voterKey: pollVoterKey()}
+}
+
+// pollVoterKey is the key voter hashes of anonymous polls are made with,
+// POLL_VOTER_SECRET, which main requires to be set apart from JWT_SECRET.
+// Changing it forgets which options voters picked in anonymous polls, not
+// the votes.
+func pollVoterKey() []byte {
+	return []byte(os.Getenv("POLL_VOTER_SECRET"))
+}
+
+// pollVoterHash identifies a voter's votes on an anonymous poll to the
+// server without storing who they are
+func pollVoterHash(key []byte, pollID, userID int) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "poll:%d:user:%d", pollID, userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomUUID returns a random (version 4) UUID
+func randomUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// validatePoll trims the options in place and returns what's wrong with the
+// poll, or an empty string
+func validatePoll(req *models.CreatePollRequest) string {
+	if len(req.Options) < minPollOptions || len(req.Options) > maxPollOptions {
+		return fmt.Sprintf("A poll needs between %d and %d options", minPollOptions, maxPollOptions)
+	}
+
+	seen := make(map[string]bool, len(req.Options))
+	for i, option := range req.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return "Poll options can't be empty"
+		}
+		if len([]rune(option)) > maxPollOptionLength {
+			return fmt.Sprintf("Poll options can be at most %d characters", maxPollOptionLength)
+		}
+		if seen[strings.ToLower(option)] {
+			return "Poll options must be different"
+		}
+		seen[strings.ToLower(option)] = true
+		req.Options[i] = option
+	}
+
+	if req.ClosesAt != nil && !req.ClosesAt.After(time.Now()) {
+		return "closes_at must be in the future"
+	}
+	return ""
+}
+
+// createPoll adds the poll to the post as part of the transaction
+func createPoll(tx *sql.Tx, postID int, req *models.CreatePollRequest) error {
+	var pollID int
+	err := tx.QueryRow(`
+        INSERT INTO polls (post_id, multiple_choice, anonymous, closes_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, postID, req.MultipleChoice, req.Anonymous, req.ClosesAt).Scan(&pollID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO poll_options (poll_id, position, text)
+        SELECT $1, o.position, o.text
+        FROM UNNEST($2::TEXT[]) WITH ORDINALITY AS o(text, position)
+    `, pollID, pq.Array(req.Options))
+	return err
+}
+
+// pollTarget is the poll of the post from the path
+type pollTarget struct {
+	ID          int
+	PostID      int
+	ChatboardID int
+	AuthorID    int
+	Multiple    bool
+	Anonymous   bool
+	Closed      bool
+}
+
+// getPollTarget loads the poll of the post from the path and checks that the
+// caller can see the chatboard and that it isn't archived. It writes the error
+// response and returns false otherwise.
+func (h *PollHandler) getPollTarget(c *gin.Context) (pollTarget, bool) {
+	var poll pollTarget
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return poll, false
+	}
+
+	err = h.db.QueryRow(`
+        SELECT pl.id, p.id, p.chatboard_id, p.user_id, pl.multiple_choice, pl.anonymous,
+               COALESCE(pl.closes_at <= NOW(), FALSE)
+        FROM polls pl
+        JOIN posts p ON p.id = pl.post_id
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+        AND p.deleted_at IS NULL AND p.held_at IS NULL AND p.status = 'published'
+    `, postID, orgID).Scan(&poll.ID, &poll.PostID, &poll.ChatboardID, &poll.AuthorID,
+		&poll.Multiple, &poll.Anonymous, &poll.Closed)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Poll not found"})
+		return poll, false
+	} else if err != nil {
+		log.Printf("Error fetching poll: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch poll"})
+		return poll, false
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, poll.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return poll, false
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return poll, false
+	}
+
+	archived, err := isChatboardArchived(h.db, poll.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch poll"})
+		return poll, false
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return poll, false
+	}
+
+	return poll, true
+}
+
+// Vote casts the caller's vote on the poll of a post. Every member votes once
+// and can't change their vote afterwards.
+func (h *PollHandler) Vote(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	poll, ok := h.getPollTarget(c)
+	if !ok {
+		return
+	}
+
+	var req models.VotePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	optionIDs := uniqueIDs(req.OptionIDs)
+	if len(optionIDs) == 0 || (!poll.Multiple && len(optionIDs) > 1) {
+		if poll.Multiple {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Choose at least one option"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Choose exactly one option"})
+		}
+		return
+	}
+
+	if poll.Closed {
+		c.JSON(http.StatusConflict, gin.H{"error": "This poll is closed"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) {
+		return
+	}
+
+	var matching int
+	err := h.db.QueryRow(`
+        SELECT COUNT(*) FROM poll_options WHERE poll_id = $1 AND id = ANY($2)
+    `, poll.ID, pq.Array(optionIDs)).Scan(&matching)
+	if err != nil {
+		log.Printf("Error fetching poll options: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+	if matching != len(optionIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "option_ids must be options of this poll"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+	defer tx.Rollback()
+
+	// The ballot enforces one vote per member, also for anonymous polls
+	result, err := tx.Exec(`
+        INSERT INTO poll_ballots (poll_id, user_id)
+        VALUES ($1, $2)
+        ON CONFLICT DO NOTHING
+    `, poll.ID, userID)
+	if err != nil {
+		log.Printf("Error recording ballot: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already voted on this poll"})
+		return
+	}
+
+	if poll.Anonymous {
+		// Random keys keep the votes from being ordered like the ballots
+		voteIDs := make([]string, len(optionIDs))
+		for i := range voteIDs {
+			if voteIDs[i], err = randomUUID(); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			_, err = tx.Exec(`
+                INSERT INTO poll_anonymous_votes (id, poll_id, option_id, voter_hash)
+                SELECT v.id::UUID, $1, v.option_id, $4
+                FROM UNNEST($2::TEXT[], $3::INTEGER[]) AS v(id, option_id)
+            `, poll.ID, pq.Array(voteIDs), pq.Array(optionIDs), pollVoterHash(h.voterKey, poll.ID, userID))
+		}
+	} else {
+		_, err = tx.Exec(`
+            INSERT INTO poll_votes (poll_id, option_id, user_id)
+            SELECT $1, UNNEST($2::INTEGER[]), $3
+        `, poll.ID, pq.Array(optionIDs), userID)
+	}
+	if err != nil {
+		log.Printf("Error recording vote: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	if err := realtime.Publish(tx, realtime.Event{
+		Type:        realtime.EventPollVoted,
+		ChatboardID: poll.ChatboardID,
+		PostID:      poll.PostID,
+	}); err != nil {
+		log.Printf("Error publishing poll event: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing vote: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	h.respondWithPoll(c, poll.PostID)
+}
+
+// ClosePoll closes the poll of a post before its close date, or when it has
+// none. Only the post's author and the chatboard's moderators can close it.
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	poll, ok := h.getPollTarget(c)
+	if !ok {
+		return
+	}
+
+	if poll.AuthorID != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, c.GetInt("orgID"), poll.ChatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author and moderators can close this poll"})
+			return
+		}
+	}
+
+	if !poll.Closed {
+		_, err := h.db.Exec("UPDATE polls SET closes_at = NOW() WHERE id = $1", poll.ID)
+		if err != nil {
+			log.Printf("Error closing poll: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close poll"})
+			return
+		}
+
+		if err := realtime.Publish(h.db, realtime.Event{
+			Type:        realtime.EventPollClosed,
+			ChatboardID: poll.ChatboardID,
+			PostID:      poll.PostID,
+		}); err != nil {
+			log.Printf("Error publishing poll event: %v", err)
+		}
+	}
+
+	h.respondWithPoll(c, poll.PostID)
+}
+
+func (h *PollHandler) respondWithPoll(c *gin.Context, postID int) {
+	polls, err := loadPolls(h.db, []int{postID}, c.GetInt("userID"), h.voterKey)
+	if err != nil {
+		log.Printf("Error fetching poll: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch poll"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"poll": polls[postID]})
+}
+
+// loadPolls returns the polls of the posts as the user sees them, keyed by
+// post ID. Posts without a poll are left out. Vote counts, and the voters of
+// polls that aren't anonymous, are only filled in once the user has voted or
+// the poll has closed. voterKey finds the user's own votes on anonymous
+// polls.
+func loadPolls(db querier, postIDs []int, userID int, voterKey []byte) (map[int]*models.Poll, error) {
+	polls := make(map[int]*models.Poll)
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+
+	rows, err := db.Query(`
+        SELECT pl.id, pl.post_id, pl.multiple_choice, pl.anonymous, pl.closes_at,
+               COALESCE(pl.closes_at <= NOW(), FALSE),
+               EXISTS (SELECT 1 FROM poll_ballots b WHERE b.poll_id = pl.id AND b.user_id = $2),
+               (SELECT COUNT(*) FROM poll_ballots b WHERE b.poll_id = pl.id)
+        FROM polls pl
+        WHERE pl.post_id = ANY($1)
+    `, pq.Array(postIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*models.Poll)
+	var visibleIDs []int
+	var anonymousIDs []int
+	var voterHashes []string
+	for rows.Next() {
+		var postID, voterCount int
+		var closesAt sql.NullTime
+		poll := &models.Poll{Options: []models.PollOption{}}
+		if err := rows.Scan(&poll.ID, &postID, &poll.MultipleChoice, &poll.Anonymous, &closesAt,
+			&poll.Closed, &poll.Voted, &voterCount); err != nil {
+			return nil, err
+		}
+		if closesAt.Valid {
+			poll.ClosesAt = &closesAt.Time
+		}
+		poll.ResultsVisible = poll.Voted || poll.Closed
+		if poll.ResultsVisible {
+			poll.VoterCount = &voterCount
+			visibleIDs = append(visibleIDs, poll.ID)
+		}
+		if poll.Anonymous && poll.Voted {
+			anonymousIDs = append(anonymousIDs, poll.ID)
+			voterHashes = append(voterHashes, pollVoterHash(voterKey, poll.ID, userID))
+		}
+		polls[postID] = poll
+		byID[poll.ID] = poll
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(byID) == 0 {
+		return polls, nil
+	}
+
+	pollIDs := make([]int, 0, len(byID))
+	for id := range byID {
+		pollIDs = append(pollIDs, id)
+	}
+
+	rows, err = db.Query(`
+        SELECT o.poll_id, o.id, o.text,
+               (SELECT COUNT(*) FROM poll_votes v WHERE v.option_id = o.id) +
+               (SELECT COUNT(*) FROM poll_anonymous_votes av WHERE av.option_id = o.id)
+        FROM poll_options o
+        WHERE o.poll_id = ANY($1)
+        ORDER BY o.poll_id, o.position
+    `, pq.Array(pollIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pollID, votes int
+		var option models.PollOption
+		if err := rows.Scan(&pollID, &option.ID, &option.Text, &votes); err != nil {
+			return nil, err
+		}
+		poll := byID[pollID]
+		if poll.ResultsVisible {
+			option.Votes = &votes
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The caller's own choice on anonymous polls they voted on
+	if len(anonymousIDs) > 0 {
+		rows, err = db.Query(`
+            SELECT av.poll_id, av.option_id
+            FROM poll_anonymous_votes av
+            JOIN UNNEST($1::INTEGER[], $2::TEXT[]) AS me(poll_id, voter_hash)
+                ON me.poll_id = av.poll_id AND me.voter_hash = av.voter_hash
+        `, pq.Array(anonymousIDs), pq.Array(voterHashes))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var pollID, optionID int
+			if err := rows.Scan(&pollID, &optionID); err != nil {
+				return nil, err
+			}
+			byID[pollID].MyOptionIDs = append(byID[pollID].MyOptionIDs, optionID)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(visibleIDs) == 0 {
+		return polls, nil
+	}
+
+	// Who voted for what, for the visible polls that aren't anonymous
+	rows, err = db.Query(`
+        SELECT v.poll_id, v.option_id, u.id, u.username
+        FROM poll_votes v
+        JOIN users u ON u.id = v.user_id
+        WHERE v.poll_id = ANY($1)
+        ORDER BY v.id
+    `, pq.Array(visibleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pollID, optionID int
+		var voter models.PollVoter
+		var username sql.NullString
+		if err := rows.Scan(&pollID, &optionID, &voter.UserID, &username); err != nil {
+			return nil, err
+		}
+		if username.Valid {
+			voter.Username = &username.String
+		}
+
+		poll := byID[pollID]
+		if poll.Anonymous {
+			continue
+		}
+		for i := range poll.Options {
+			if poll.Options[i].ID == optionID {
+				poll.Options[i].Voters = append(poll.Options[i].Voters, voter)
+			}
+		}
+		if voter.UserID == userID {
+			poll.MyOptionIDs = append(poll.MyOptionIDs, optionID)
+		}
+	}
+
+	return polls, rows.Err()
+}
@@ -2,21 +2,62 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 	"unicorn_app_backend/models" // replace with your actual project name
+	"unicorn_app_backend/realtime"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
 
+// defaultPostEditWindow is how long authors can edit their posts and
+// comments unless POST_EDIT_WINDOW (a Go duration like "30m") says otherwise
+const defaultPostEditWindow = 15 * time.Minute
+
+// defaultMaxPinnedPosts is how many posts a chatboard can have pinned unless
+// MAX_PINNED_POSTS says otherwise
+const defaultMaxPinnedPosts = 5
+
 type PostHandler struct {
-	db *sql.DB
+	db         *sql.DB
+	editWindow time.Duration
+	maxPinned  int
+	voterKey   []byte
 }
 
 func NewPostHandler(db *sql.DB) *PostHandler {
-	return &PostHandler{db: db}
+	return &PostHandler{db: db, editWindow: postEditWindow(), maxPinned: maxPinnedPosts(), voterKey: pollVoterKey()}
+}
+
+func postEditWindow() time.Duration {
+	value := os.Getenv("POST_EDIT_WINDOW")
+	if value == "" {
+		return defaultPostEditWindow
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window < 0 {
+		log.Printf("Invalid POST_EDIT_WINDOW %q, using %s", value, defaultPostEditWindow)
+		return defaultPostEditWindow
+	}
+	return window
+}
+
+func maxPinnedPosts() int {
+	value := os.Getenv("MAX_PINNED_POSTS")
+	if value == "" {
+		return defaultMaxPinnedPosts
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Printf("Invalid MAX_PINNED_POSTS %q, using %d", value, defaultMaxPinnedPosts)
+		return defaultMaxPinnedPosts
+	}
+	return n
 }
 
 func (h *PostHandler) CreatePost(c *gin.Context) {
@@ -25,42 +66,95 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 
 	// Bind the request body
 	var input struct {
-		ChatboardID int    `json:"chatboard_id" binding:"required"`
-		Title       string `json:"title" binding:"required"`
-		Content     string `json:"content" binding:"required"`
+		ChatboardID int                       `json:"chatboard_id" binding:"required"`
+		Title       string                    `json:"title" binding:"required"`
+		Content     string                    `json:"content" binding:"required"`
+		Poll        *models.CreatePollRequest `json:"poll"`
+		Draft       bool                      `json:"draft"`
+		PublishAt   *time.Time                `json:"publish_at"` // Schedules the post
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.Poll != nil {
+		if msg := validatePoll(input.Poll); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+	if input.PublishAt != nil && !input.PublishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "publish_at must be in the future"})
+		return
+	}
+
+	status := postPublished
+	if input.PublishAt != nil {
+		status = postScheduled
+	} else if input.Draft {
+		status = postDraft
+	}
+	published := status == postPublished
 
-	// First, verify that the user has access to this chatboard
-	var count int
-	err := h.db.QueryRow(`
-        SELECT COUNT(1) FROM chatboard_squads cs
-        JOIN user_squads us ON cs.squad_id = us.squad_id
-        WHERE cs.chatboard_id = $1 AND us.user_id = $2
-    `, input.ChatboardID, userID).Scan(&count)
+	// Banned members can't access the chatboard, so tell them why first
+	if !ensureNotRestricted(c, h.db, input.ChatboardID) {
+		return
+	}
 
+	// Verify that the user has access to this chatboard
+	hasAccess, err := canAccessChatboard(h.db, userID, c.GetInt("orgID"), input.ChatboardID)
 	if err != nil {
 		log.Printf("Error checking chatboard access: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboard access"})
 		return
 	}
 
-	if count == 0 {
+	if !hasAccess {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
 		return
 	}
 
-	// Create the post
+	archived, err := isChatboardArchived(h.db, input.ChatboardID)
+	if err != nil {
+		log.Printf("Error checking chatboard status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboard access"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) || (published && !ensureSlowMode(c, h.db, input.ChatboardID)) {
+		return
+	}
+
+	texts := []string{input.Title, input.Content}
+	if input.Poll != nil {
+		texts = append(texts, input.Poll.Options...)
+	}
+	heldReasons, ok := screenContent(c, h.db, input.ChatboardID, texts...)
+	if !ok {
+		return
+	}
+	// Drafts keep the reasons and are held when they are published
+	held := heldReasons.Valid && published
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Create the post, held for a moderator when the content filter flagged it
 	var postID int
-	err = h.db.QueryRow(`
-        INSERT INTO posts (chatboard_id, user_id, title, content, created_at)
-        VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+	err = tx.QueryRow(`
+        INSERT INTO posts (chatboard_id, user_id, title, content, created_at, held_at, held_reasons, status, publish_at)
+        VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CASE WHEN $5 THEN CURRENT_TIMESTAMP END, $6, $7, $8)
         RETURNING id
-    `, input.ChatboardID, userID, input.Title, input.Content).Scan(&postID)
+    `, input.ChatboardID, userID, input.Title, input.Content, held, heldReasons, status, input.PublishAt).Scan(&postID)
 
 	if err != nil {
 		log.Printf("Error creating post: %v", err)
@@ -68,6 +162,35 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
+	// Mentions in drafts notify when the post is published
+	if !h.saveMentions(c, tx, input.ChatboardID, postID, input.Content, held || !published) {
+		return
+	}
+
+	if input.Poll != nil {
+		if err := createPoll(tx, postID, input.Poll); err != nil {
+			log.Printf("Error creating poll: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+			return
+		}
+	}
+
+	if published && !held {
+		if err := realtime.Publish(tx, realtime.Event{
+			Type:        realtime.EventPostCreated,
+			ChatboardID: input.ChatboardID,
+			PostID:      postID,
+		}); err != nil {
+			log.Printf("Error publishing post event: %v", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	// Fetch the created post
 	var post models.Post
 	err = h.db.QueryRow(`
@@ -100,52 +223,65 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
+	mentions, err := loadMentions(h.db, postTarget, []int{postID})
+	if err != nil {
+		log.Printf("Error fetching post mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Post created but failed to fetch details"})
+		return
+	}
+	post.Mentions = mentions[postID]
+	post.Held = held
+
+	polls, err := loadPolls(h.db, []int{postID}, userID, h.voterKey)
+	if err != nil {
+		log.Printf("Error fetching post poll: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Post created but failed to fetch details"})
+		return
+	}
+	post.Poll = polls[postID]
+	post.Status = status
+	post.PublishAt = input.PublishAt
+
+	// Held posts wait for a moderator before anyone else sees them
+	if held {
+		c.JSON(http.StatusAccepted, post)
+		return
+	}
 	c.JSON(http.StatusCreated, post)
 }
 
+// saveMentions stores the @mentions in a post's content and notifies the
+// newly mentioned users, as part of the transaction. Mentions in held posts
+// notify once a moderator approves the post. It writes the error response and
+// returns false on failure.
+func (h *PostHandler) saveMentions(c *gin.Context, tx mentionStore, chatboardID, postID int, content string, held bool) bool {
+	userID := c.GetInt("userID")
+
+	mentioned, err := saveMentions(tx, c.GetInt("orgID"), chatboardID, userID, postTarget, postID, content)
+	if err == nil && !held {
+		err = notifyMentions(tx, c.GetInt("orgID"), mentioned, userID, "a post", gin.H{
+			"chatboard_id": chatboardID,
+			"post_id":      postID,
+		})
+	}
+	if err != nil {
+		log.Printf("Error saving post mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mentions"})
+		return false
+	}
+	return true
+}
+
 func (h *PostHandler) GetPosts(c *gin.Context) {
 	userID := c.GetInt("userID")
-	chatboardID := c.Query("chatboard_id")
-	if chatboardID == "" {
+	chatboardID, err := strconv.Atoi(c.Query("chatboard_id"))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Chatboard ID is required"})
 		return
 	}
 
-	// Check if user has access to the chatboard through any of the access methods (squads, roles, or countries)
-	var hasAccess bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1
-            FROM chatboards cb
-            WHERE cb.id = $1
-            AND (
-                -- Check squad access
-                EXISTS (
-                    SELECT 1 FROM chatboard_squads cs
-                    JOIN user_squads us ON cs.squad_id = us.squad_id
-                    WHERE cs.chatboard_id = cb.id
-                    AND us.user_id = $2
-                    AND us.status = 'active'
-                )
-                OR
-                -- Check role access
-                EXISTS (
-                    SELECT 1 FROM chatboard_roles cr
-                    JOIN user_roles ur ON cr.role_id = ur.role_id
-                    WHERE cr.chatboard_id = cb.id
-                    AND ur.user_id = $2
-                )
-                OR
-                -- Check country access
-                EXISTS (
-                    SELECT 1 FROM chatboard_countries cc
-                    JOIN user_countries uc ON cc.country_id = uc.country_id
-                    WHERE cc.chatboard_id = cb.id
-                    AND uc.user_id = $2
-                )
-            )
-    )`, chatboardID, userID).Scan(&hasAccess)
-
+	// Check if user has access to the chatboard
+	hasAccess, err := canAccessChatboard(h.db, userID, c.GetInt("orgID"), chatboardID)
 	if err != nil {
 		log.Printf("Error checking access: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
@@ -157,8 +293,20 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 		return
 	}
 
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	// Held posts are only shown to their authors and the moderators
+	isModerator, err := isChatboardModerator(h.db, userID, c.GetInt("orgID"), chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+
 	// Get posts with user roles in the chatboard
-	rows, err := h.db.Query(`
+	query := `
         SELECT 
             p.id,
             p.title,
@@ -169,18 +317,54 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
             COALESCE(
                 ARRAY_AGG(DISTINCT r.role) FILTER (WHERE r.role IS NOT NULL),
                 ARRAY[]::VARCHAR[]
-            ) as user_roles
+            ) as user_roles,
+            p.user_id <> $2 AND p.created_at > COALESCE(rd.last_read_at, '-infinity') as unread,
+            EXISTS (
+                SELECT 1 FROM comments cm
+                WHERE cm.post_id = p.id
+                AND cm.user_id <> $2
+                AND cm.deleted_at IS NULL
+                AND cm.held_at IS NULL
+                AND cm.created_at > COALESCE(GREATEST(rd.last_read_at, prd.last_read_at), '-infinity')
+            ) as has_new_comments,
+            p.edited_at,
+            p.deleted_at IS NOT NULL as removed,
+            p.user_id,
+            u.avatar_preset,
+            u.avatar_image_key,
+            p.held_at IS NOT NULL as held,
+            p.pin_order,
+            p.pin_expires_at,
+            p.announcement_id,
+            ar.acknowledged_at IS NOT NULL as acknowledged
         FROM posts p
         JOIN users u ON p.user_id = u.id
+        LEFT JOIN chatboard_reads rd ON rd.chatboard_id = p.chatboard_id AND rd.user_id = $2
+        LEFT JOIN post_reads prd ON prd.post_id = p.id AND prd.user_id = $2
+        LEFT JOIN announcement_recipients ar ON ar.announcement_id = p.announcement_id AND ar.user_id = $2
         LEFT JOIN user_squad_roles usr ON usr.user_id = u.id
         LEFT JOIN roles r ON r.id = usr.role_id
         LEFT JOIN chatboard_roles cr ON cr.role_id = r.id AND cr.chatboard_id = p.chatboard_id
         LEFT JOIN chatboard_squads cs ON cs.chatboard_id = p.chatboard_id
         LEFT JOIN user_squads us ON us.squad_id = cs.squad_id AND us.user_id = u.id
-        WHERE p.chatboard_id = $1
-        GROUP BY p.id, p.title, p.content, p.pinned, p.created_at, u.username
-        ORDER BY p.pinned DESC, p.created_at DESC`,
-		chatboardID)
+        WHERE p.chatboard_id = $1 AND p.status = 'published'
+        AND (p.held_at IS NULL OR p.user_id = $2 OR $3)`
+	args := []interface{}{chatboardID, userID, isModerator}
+
+	// Pinned posts come first in their pin order, so the cursor also
+	// remembers whether and where the page ended among them
+	if page.After != nil {
+		query += " AND (p.pinned, -p.pin_order, p.created_at, p.id) < ($4, $5, $6, $7)"
+		args = append(args, page.After.Pinned, -page.After.PinOrder, page.After.CreatedAt, page.After.ID)
+	}
+
+	query += `
+        GROUP BY p.id, p.title, p.content, p.pinned, p.created_at, u.username, rd.last_read_at, prd.last_read_at, p.edited_at, p.deleted_at, u.avatar_preset, u.avatar_image_key, p.held_at, p.pin_order, p.pin_expires_at, ar.acknowledged_at
+        ORDER BY p.pinned DESC, p.pin_order, p.created_at DESC, p.id DESC`
+	limit, args := page.limit(args)
+	query += limit
+
+	rows, err := h.db.Query(query, args...)
 	if err != nil {
 		log.Printf("Error fetching posts: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
@@ -188,16 +372,29 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var posts []gin.H
+	posts := make([]gin.H, 0)
+	var cursors []pageCursor
 	for rows.Next() {
 		var (
-			id        int
-			title     string
-			content   string
-			pinned    bool
-			createdAt time.Time
-			username  sql.NullString
-			roles     []string
+			id         int
+			title      string
+			content    string
+			pinned     bool
+			createdAt  time.Time
+			username   sql.NullString
+			roles      []string
+			unread     bool
+			newReplies bool
+			editedAt   sql.NullTime
+			removed    bool
+			authorID   int
+			preset     sql.NullString
+			imageKey   sql.NullString
+			held       bool
+			pinOrder   int
+			pinExpires sql.NullTime
+			announceID sql.NullInt64
+			acked      bool
 		)
 
 		err := rows.Scan(
@@ -208,6 +405,18 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 			&createdAt,
 			&username,
 			pq.Array(&roles),
+			&unread,
+			&newReplies,
+			&editedAt,
+			&removed,
+			&authorID,
+			&preset,
+			&imageKey,
+			&held,
+			&pinOrder,
+			&pinExpires,
+			&announceID,
+			&acked,
 		)
 		if err != nil {
 			log.Printf("Error scanning post: %v", err)
@@ -215,17 +424,40 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 		}
 
 		post := gin.H{
-			"id":         id,
-			"title":      title,
-			"content":    content,
-			"pinned":     pinned,
-			"created_at": createdAt,
+			"id":               id,
+			"title":            title,
+			"content":          content,
+			"pinned":           pinned,
+			"created_at":       createdAt,
+			"unread":           unread,
+			"has_new_comments": newReplies,
+			"edited":           editedAt.Valid,
+			"removed":          removed,
+			"held":             held,
 			"author": gin.H{
-				"username": username.String,
-				"roles":    roles,
+				"username":   username.String,
+				"roles":      roles,
+				"avatar_url": avatarURL(authorID, preset, imageKey),
 			},
 		}
+		if editedAt.Valid {
+			post["edited_at"] = editedAt.Time
+		}
+		if pinExpires.Valid {
+			post["pin_expires_at"] = pinExpires.Time
+		}
+		// Announcements ask their recipients to acknowledge them
+		if announceID.Valid {
+			post["announcement_id"] = announceID.Int64
+			post["acknowledged"] = acked
+		}
+		// Removed posts keep their place in the list without their content
+		if removed {
+			post["title"] = ""
+			post["content"] = ""
+		}
 		posts = append(posts, post)
+		cursors = append(cursors, pageCursor{Pinned: pinned, PinOrder: pinOrder, CreatedAt: createdAt, ID: id})
 	}
 
 	if err = rows.Err(); err != nil {
@@ -234,22 +466,68 @@ func (h *PostHandler) GetPosts(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, posts)
+	posts, next := trimPage(posts, page.Limit, func(i int) pageCursor { return cursors[i] })
+
+	postIDs := make([]int, len(posts))
+	for i := range posts {
+		postIDs[i] = cursors[i].ID
+	}
+	reactions, err := loadReactions(h.db, postTarget, postIDs, userID)
+	if err != nil {
+		log.Printf("Error fetching post reactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	attachments, err := loadAttachments(h.db, postIDs)
+	if err != nil {
+		log.Printf("Error fetching post attachments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	mentions, err := loadMentions(h.db, postTarget, postIDs)
+	if err != nil {
+		log.Printf("Error fetching post mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	polls, err := loadPolls(h.db, postIDs, userID, h.voterKey)
+	if err != nil {
+		log.Printf("Error fetching post polls: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	for i, post := range posts {
+		post["reactions"] = reactions[postIDs[i]]
+		post["attachments"] = attachments[postIDs[i]]
+		post["mentions"] = mentions[postIDs[i]]
+		post["poll"] = polls[postIDs[i]]
+		if post["removed"] == true {
+			post["attachments"] = []models.AttachmentResponse{}
+			post["mentions"] = []models.Mention{}
+			post["poll"] = nil
+		}
+	}
+
+	c.JSON(http.StatusOK, pageResponse(posts, next))
 }
 
-func (h *PostHandler) TogglePin(c *gin.Context) {
+// MarkPostRead advances the user's read marker of the post's comments to now
+func (h *PostHandler) MarkPostRead(c *gin.Context) {
 	userID := c.GetInt("userID")
-	postID := c.Param("id")
 
-	// First, get the chatboard ID and current pin status for this post
-	var chatboardID int
-	var currentPinned bool
-	err := h.db.QueryRow(`
-        SELECT chatboard_id, pinned 
-        FROM posts 
-        WHERE id = $1
-    `, postID).Scan(&chatboardID, &currentPinned)
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
 
+	var chatboardID int
+	err = h.db.QueryRow(`
+        SELECT p.chatboard_id
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+    `, postID, c.GetInt("orgID")).Scan(&chatboardID)
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
@@ -258,45 +536,469 @@ func (h *PostHandler) TogglePin(c *gin.Context) {
 		return
 	}
 
-	// Check if user has admin/moderator role in this chatboard
-	var hasPermission bool
+	hasAccess, err := canAccessChatboard(h.db, userID, c.GetInt("orgID"), chatboardID)
+	if err != nil {
+		log.Printf("Error checking access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	var lastReadAt time.Time
+	err = h.db.QueryRow(`
+        INSERT INTO post_reads (user_id, post_id, last_read_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (user_id, post_id)
+        DO UPDATE SET last_read_at = GREATEST(post_reads.last_read_at, EXCLUDED.last_read_at)
+        RETURNING last_read_at
+    `, userID, postID).Scan(&lastReadAt)
+	if err != nil {
+		log.Printf("Error updating read marker: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark post as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post_id":      postID,
+		"last_read_at": lastReadAt,
+	})
+}
+
+// TogglePin unpins a pinned post and pins any other at the end of the pins.
+// Older clients use it; PinPost and UnpinPost can be retried safely.
+func (h *PostHandler) TogglePin(c *gin.Context) {
+	post, ok := h.getPinTarget(c)
+	if !ok {
+		return
+	}
+
+	if post.Pinned {
+		h.unpin(c, post)
+		return
+	}
+	h.pin(c, post, models.PinPostRequest{})
+}
+
+// postState is what editing, deleting and restoring a post needs to know
+type postState struct {
+	ID          int
+	ChatboardID int
+	AuthorID    int
+	CreatedAt   time.Time
+	Deleted     bool
+	Held        bool
+	Status      string
+}
+
+// getPostState loads the post from the path within the caller's
+// organization. Drafts and scheduled posts are only found for their author.
+// It writes the error response and returns false when the post cannot be
+// loaded.
+func (h *PostHandler) getPostState(c *gin.Context) (postState, bool) {
+	var post postState
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return post, false
+	}
+
 	err = h.db.QueryRow(`
-        SELECT EXISTS (
-            SELECT 1 
-            FROM chatboard_roles cbr
-            JOIN user_roles ur ON ur.role_id = cbr.role_id
-            JOIN roles r ON r.id = ur.role_id
-            WHERE cbr.chatboard_id = $1 
-            AND ur.user_id = $2
-            AND r.role IN ('Admin', 'Moderator')
-        )
-    `, chatboardID, userID).Scan(&hasPermission)
+        SELECT p.id, p.chatboard_id, p.user_id, p.created_at, p.deleted_at IS NOT NULL, p.held_at IS NOT NULL, p.status
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+        AND (p.status = 'published' OR p.user_id = $3)
+    `, postID, c.GetInt("orgID"), c.GetInt("userID")).Scan(&post.ID, &post.ChatboardID, &post.AuthorID, &post.CreatedAt, &post.Deleted, &post.Held, &post.Status)
 
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return post, false
+	} else if err != nil {
+		log.Printf("Error fetching post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return post, false
+	}
+
+	return post, true
+}
+
+// UpdatePost lets authors change their post within the edit window. The
+// previous version is kept in post_revisions. Drafts and scheduled posts can
+// be changed until they are published, without keeping revisions.
+func (h *PostHandler) UpdatePost(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	post, ok := h.getPostState(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Title == nil && req.Content == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+	if (req.Title != nil && *req.Title == "") || (req.Content != nil && *req.Content == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title and content cannot be empty"})
+		return
+	}
+
+	if post.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this post"})
+		return
+	}
+	if post.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Removed posts cannot be edited"})
+		return
+	}
+	published := post.Status == postPublished
+	if published && time.Since(post.CreatedAt) > h.editWindow {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Posts can only be edited within %s of posting", h.editWindow)})
+		return
+	}
+
+	if !ensureNotRestricted(c, h.db, post.ChatboardID) {
+		return
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, userID, c.GetInt("orgID"), post.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	archived, err := isChatboardArchived(h.db, post.ChatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) {
+		return
+	}
+
+	// Published posts are screened for what the edit changes. Drafts keep
+	// the reasons of their current text only, so the whole post is screened
+	// again.
+	var texts []string
+	if published {
+		if req.Title != nil {
+			texts = append(texts, *req.Title)
+		}
+		if req.Content != nil {
+			texts = append(texts, *req.Content)
+		}
+	} else {
+		var title, content string
+		var options pq.StringArray
+		err = h.db.QueryRow(`
+            SELECT p.title, p.content,
+                ARRAY(
+                    SELECT o.text FROM polls pl
+                    JOIN poll_options o ON o.poll_id = pl.id
+                    WHERE pl.post_id = p.id
+                    ORDER BY o.position
+                )
+            FROM posts p
+            WHERE p.id = $1
+        `, post.ID).Scan(&title, &content, &options)
+		if err != nil {
+			log.Printf("Error fetching post: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+			return
+		}
+		if req.Title != nil {
+			title = *req.Title
+		}
+		if req.Content != nil {
+			content = *req.Content
+		}
+		texts = append([]string{title, content}, options...)
+	}
+	heldReasons, ok := screenContent(c, h.db, post.ChatboardID, texts...)
+	if !ok {
+		return
+	}
+	// Held posts stay held until a moderator reviews them. Drafts keep the
+	// reasons and are held when they are published.
+	held := post.Held || (heldReasons.Valid && published)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Keep the version being replaced. Nobody else has seen a draft.
+	if published {
+		_, err = tx.Exec(`
+            INSERT INTO post_revisions (post_id, title, content, edited_by)
+            SELECT id, title, content, $2 FROM posts WHERE id = $1
+        `, post.ID, userID)
+		if err != nil {
+			log.Printf("Error saving post revision: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+			return
+		}
+	}
+
+	var updated models.Post
+	var editedAt sql.NullTime
+	var publishAt sql.NullTime
+	err = tx.QueryRow(`
+        UPDATE posts
+        SET title = COALESCE($1, title),
+            content = COALESCE($2, content),
+            edited_at = CASE WHEN $6 THEN NOW() ELSE edited_at END,
+            held_at = CASE WHEN $4 THEN COALESCE(held_at, NOW()) ELSE held_at END,
+            held_reasons = CASE WHEN $6 THEN COALESCE($5, held_reasons) ELSE $5 END
+        WHERE id = $3
+        RETURNING id, title, content, pinned, created_at, edited_at, status, publish_at
+    `, req.Title, req.Content, post.ID, heldReasons.Valid && published, heldReasons, published).Scan(
+		&updated.ID,
+		&updated.Title,
+		&updated.Content,
+		&updated.Pinned,
+		&updated.CreatedAt,
+		&editedAt,
+		&updated.Status,
+		&publishAt,
+	)
+	if err != nil {
+		log.Printf("Error updating post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+
+	// Mentions move with the edited text, only new ones notify
+	if req.Content != nil && !h.saveMentions(c, tx, post.ChatboardID, post.ID, updated.Content, held || !published) {
+		return
+	}
+
+	// Nobody else has seen a held post, and one held by this edit disappears
+	// for them
+	if published && !post.Held {
+		eventType := realtime.EventPostUpdated
+		if held {
+			eventType = realtime.EventPostRemoved
+		}
+		if err := realtime.Publish(tx, realtime.Event{
+			Type:        eventType,
+			ChatboardID: post.ChatboardID,
+			PostID:      post.ID,
+		}); err != nil {
+			log.Printf("Error publishing post event: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	mentions, err := loadMentions(h.db, postTarget, []int{updated.ID})
+	if err != nil {
+		log.Printf("Error fetching post mentions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	response := gin.H{
+		"id":         updated.ID,
+		"title":      updated.Title,
+		"content":    updated.Content,
+		"pinned":     updated.Pinned,
+		"created_at": updated.CreatedAt,
+		"edited":     editedAt.Valid,
+		"edited_at":  nil,
+		"mentions":   mentions[updated.ID],
+		"held":       held,
+		"status":     updated.Status,
+		"publish_at": nil,
+	}
+	if editedAt.Valid {
+		response["edited_at"] = editedAt.Time
+	}
+	if publishAt.Valid {
+		response["publish_at"] = publishAt.Time
+	}
+
+	status := http.StatusOK
+	if held {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, response)
+}
+
+// DeletePost removes a post for its author or the chatboard's moderators. The
+// post stays in the database and shows as removed until an Admin restores it.
+// Deleting a draft or scheduled post cancels it; nobody else ever sees it.
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	post, ok := h.getPostState(c)
+	if !ok {
+		return
+	}
+
+	if post.AuthorID != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, orgID, post.ChatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author and moderators can remove this post"})
+			return
+		}
+	}
+
+	if post.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is already removed"})
+		return
+	}
+
+	_, err := h.db.Exec(`
+        UPDATE posts SET deleted_at = NOW(), deleted_by = $2,
+            pinned = FALSE, pin_order = 0, pinned_at = NULL, pinned_by = NULL, pin_expires_at = NULL
+        WHERE id = $1
+    `, post.ID, userID)
+	if err != nil {
+		log.Printf("Error removing post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove post"})
+		return
+	}
+
+	if post.Status != postPublished {
+		c.JSON(http.StatusOK, gin.H{"message": "Draft deleted successfully"})
+		return
+	}
+
+	if err := realtime.Publish(h.db, realtime.Event{
+		Type:        realtime.EventPostRemoved,
+		ChatboardID: post.ChatboardID,
+		PostID:      post.ID,
+	}); err != nil {
+		log.Printf("Error publishing post event: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post removed successfully"})
+}
+
+// RestorePost brings back a removed post. Only Admins can restore posts.
+func (h *PostHandler) RestorePost(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	post, ok := h.getPostState(c)
+	if !ok {
+		return
+	}
+
+	isAdmin, err := hasGlobalRole(h.db, userID, c.GetInt("orgID"), "Admin")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
 		return
 	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can restore posts"})
+		return
+	}
+
+	if !post.Deleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is not removed"})
+		return
+	}
+
+	_, err = h.db.Exec("UPDATE posts SET deleted_at = NULL, deleted_by = NULL WHERE id = $1", post.ID)
+	if err != nil {
+		log.Printf("Error restoring post: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore post"})
+		return
+	}
 
-	if !hasPermission {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and moderators can pin/unpin posts"})
+	if err := realtime.Publish(h.db, realtime.Event{
+		Type:        realtime.EventPostRestored,
+		ChatboardID: post.ChatboardID,
+		PostID:      post.ID,
+	}); err != nil {
+		log.Printf("Error publishing post event: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post restored successfully"})
+}
+
+// GetPostRevisions lists the previous versions of a post, newest first. The
+// author and the chatboard's moderators can see them.
+func (h *PostHandler) GetPostRevisions(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	post, ok := h.getPostState(c)
+	if !ok {
 		return
 	}
 
-	// Toggle the pin status
-	newPinned := !currentPinned
-	_, err = h.db.Exec(`
-        UPDATE posts 
-        SET pinned = $1 
-        WHERE id = $2
-    `, newPinned, postID)
+	if post.AuthorID != userID {
+		isModerator, err := isChatboardModerator(h.db, userID, c.GetInt("orgID"), post.ChatboardID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isModerator {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author and moderators can see revisions"})
+			return
+		}
+	}
 
+	rows, err := h.db.Query(`
+        SELECT id, title, content, edited_by, created_at
+        FROM post_revisions
+        WHERE post_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, post.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pin status"})
+		log.Printf("Error fetching post revisions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
 		return
 	}
+	defer rows.Close()
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Pin status updated successfully",
-		"pinned":  newPinned,
-	})
+	revisions := make([]models.PostRevisionResponse, 0)
+	for rows.Next() {
+		var revision models.PostRevisionResponse
+		var editedBy sql.NullInt64
+		if err := rows.Scan(&revision.ID, &revision.Title, &revision.Content, &editedBy, &revision.CreatedAt); err != nil {
+			log.Printf("Error scanning post revision: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+			return
+		}
+		if editedBy.Valid {
+			id := int(editedBy.Int64)
+			revision.EditedBy = &id
+		}
+		revisions = append(revisions, revision)
+	}
+
+	c.JSON(http.StatusOK, revisions)
 }
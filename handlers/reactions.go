@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// defaultReactionEmoji are the allowed reactions unless REACTION_EMOJI lists
+// others, separated by commas
+var defaultReactionEmoji = []string{"👍", "❤️", "😂", "😮", "😢", "🎉"}
+
+// contentTarget is the column reactions and mentions use to point at a post
+// or a comment
+type contentTarget string
+
+const (
+	postTarget    contentTarget = "post_id"
+	commentTarget contentTarget = "comment_id"
+)
+
+type ReactionHandler struct {
+	db    *sql.DB
+	emoji []string
+}
+
+func NewReactionHandler(db *sql.DB) *ReactionHandler {
+	return &ReactionHandler{db: db, emoji: reactionEmoji()}
+}
+
+func reactionEmoji() []string {
+	value := os.Getenv("REACTION_EMOJI")
+	if value == "" {
+		return defaultReactionEmoji
+	}
+
+	var emoji []string
+	for _, e := range strings.Split(value, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			emoji = append(emoji, e)
+		}
+	}
+	if len(emoji) == 0 {
+		return defaultReactionEmoji
+	}
+	return emoji
+}
+
+func (h *ReactionHandler) isAllowed(emoji string) bool {
+	for _, e := range h.emoji {
+		if e == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// GetReactionEmoji lists the emoji users can react with
+func (h *ReactionHandler) GetReactionEmoji(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"emoji": h.emoji})
+}
+
+// TogglePostReaction adds the caller's reaction to a post, or takes it back
+// when they already reacted with that emoji
+func (h *ReactionHandler) TogglePostReaction(c *gin.Context) {
+	h.toggleReaction(c, postTarget, `
+        SELECT p.id, p.chatboard_id
+        FROM posts p
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE p.id = $1 AND cb.organization_id = $2
+        AND p.deleted_at IS NULL AND p.held_at IS NULL AND p.status = 'published'`)
+}
+
+// ToggleCommentReaction adds the caller's reaction to a comment, or takes it
+// back when they already reacted with that emoji
+func (h *ReactionHandler) ToggleCommentReaction(c *gin.Context) {
+	h.toggleReaction(c, commentTarget, `
+        SELECT cm.post_id, p.chatboard_id
+        FROM comments cm
+        JOIN posts p ON p.id = cm.post_id
+        JOIN chatboards cb ON cb.id = p.chatboard_id
+        WHERE cm.id = $1 AND cb.organization_id = $2
+        AND cm.deleted_at IS NULL AND cm.held_at IS NULL
+        AND p.deleted_at IS NULL AND p.held_at IS NULL`)
+}
+
+// toggleReaction toggles a reaction on the target from the path. lookup
+// selects the target's post and chatboard by target ID ($1) and organization
+// ID ($2).
+func (h *ReactionHandler) toggleReaction(c *gin.Context, target contentTarget, lookup string) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req models.ToggleReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.isAllowed(req.Emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reaction not allowed", "allowed": h.emoji})
+		return
+	}
+
+	var postID, chatboardID int
+	err = h.db.QueryRow(lookup, targetID, orgID).Scan(&postID, &chatboardID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching reaction target: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction target"})
+		return
+	}
+
+	hasAccess, err := canAccessChatboard(h.db, userID, orgID, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
+
+	archived, err := isChatboardArchived(h.db, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reaction target"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This chatboard is archived and read-only"})
+		return
+	}
+
+	if !ensureNotSuspended(c, h.db) {
+		return
+	}
+
+	// target is one of the constants above, never user input
+	result, err := h.db.Exec(`
+        DELETE FROM reactions
+        WHERE `+string(target)+` = $1 AND user_id = $2 AND emoji = $3
+    `, targetID, userID, req.Emoji)
+	if err != nil {
+		log.Printf("Error removing reaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	reacted := removed == 0
+	if reacted {
+		_, err = h.db.Exec(`
+            INSERT INTO reactions (`+string(target)+`, user_id, emoji)
+            VALUES ($1, $2, $3)
+            ON CONFLICT DO NOTHING
+        `, targetID, userID, req.Emoji)
+		if err != nil {
+			log.Printf("Error adding reaction: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+			return
+		}
+	}
+
+	event := realtime.Event{Type: realtime.EventReactionsChanged, ChatboardID: chatboardID, PostID: postID}
+	if target == commentTarget {
+		event.CommentID = targetID
+	}
+	if err := realtime.Publish(h.db, event); err != nil {
+		log.Printf("Error publishing reaction event: %v", err)
+	}
+
+	summaries, err := loadReactions(h.db, target, []int{targetID}, userID)
+	if err != nil {
+		log.Printf("Error fetching reactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reacted":   reacted,
+		"reactions": summaries[targetID],
+	})
+}
+
+// loadReactions returns the reactions of each of the posts or comments with
+// one query. Every ID gets a list, empty when nobody reacted.
+func loadReactions(db querier, target contentTarget, ids []int, userID int) (map[int][]models.ReactionSummary, error) {
+	reactions := make(map[int][]models.ReactionSummary, len(ids))
+	for _, id := range ids {
+		reactions[id] = make([]models.ReactionSummary, 0)
+	}
+	if len(ids) == 0 {
+		return reactions, nil
+	}
+
+	rows, err := db.Query(`
+        SELECT `+string(target)+`, emoji, COUNT(*), BOOL_OR(user_id = $2)
+        FROM reactions
+        WHERE `+string(target)+` = ANY($1)
+        GROUP BY `+string(target)+`, emoji
+        ORDER BY MIN(created_at)
+    `, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var summary models.ReactionSummary
+		if err := rows.Scan(&id, &summary.Emoji, &summary.Count, &summary.Reacted); err != nil {
+			return nil, err
+		}
+		reactions[id] = append(reactions[id], summary)
+	}
+
+	return reactions, rows.Err()
+}
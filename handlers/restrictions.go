@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	restrictionBan  = "ban"
+	restrictionMute = "mute"
+
+	maxRestrictionHours = 24 * 365
+	maxSlowModeSeconds  = 6 * 60 * 60
+)
+
+type RestrictionHandler struct {
+	db *sql.DB
+}
+
+func NewRestrictionHandler(db *sql.DB) *RestrictionHandler {
+	return &RestrictionHandler{db: db}
+}
+
+// ensureNotRestricted checks that the caller isn't banned or muted on the
+// chatboard. It writes the error response and returns false when they are.
+func ensureNotRestricted(c *gin.Context, db queryRower, chatboardID int) bool {
+	var kind string
+	var expiresAt sql.NullTime
+	err := db.QueryRow(`
+        SELECT kind, expires_at FROM chatboard_restrictions
+        WHERE chatboard_id = $1 AND user_id = $2
+        AND (expires_at IS NULL OR expires_at > NOW())
+        ORDER BY kind = 'ban' DESC
+        LIMIT 1
+    `, chatboardID, c.GetInt("userID")).Scan(&kind, &expiresAt)
+	if err == sql.ErrNoRows {
+		return true
+	} else if err != nil {
+		log.Printf("Error checking chatboard restrictions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false
+	}
+
+	message := "You are muted on this chatboard"
+	if kind == restrictionBan {
+		message = "You are banned from this chatboard"
+	}
+	response := gin.H{"error": message, "restriction": kind, "expires_at": nil}
+	if expiresAt.Valid {
+		response["expires_at"] = expiresAt.Time
+	}
+	c.JSON(http.StatusForbidden, response)
+	return false
+}
+
+// ensureSlowMode checks that the caller waited out the chatboard's slow mode
+// since their last post or comment there. Moderators are exempt. It writes
+// the error response and returns false when they have to wait.
+func ensureSlowMode(c *gin.Context, db queryRower, chatboardID int) bool {
+	userID := c.GetInt("userID")
+
+	// Removed and held posts and comments count too, so deleting the last
+	// one doesn't skip the wait. Drafts don't, until they are published.
+	var wait sql.NullInt64
+	err := db.QueryRow(`
+        SELECT CEIL(EXTRACT(EPOCH FROM GREATEST(
+            (SELECT MAX(created_at) FROM posts WHERE chatboard_id = $1 AND user_id = $2 AND status = 'published'),
+            (SELECT MAX(cm.created_at) FROM comments cm
+             JOIN posts p ON p.id = cm.post_id
+             WHERE p.chatboard_id = $1 AND cm.user_id = $2)
+        ) + MAKE_INTERVAL(secs => cb.slow_mode_seconds) - NOW()))::INTEGER
+        FROM chatboards cb
+        WHERE cb.id = $1 AND cb.slow_mode_seconds > 0
+    `, chatboardID, userID).Scan(&wait)
+	if err == sql.ErrNoRows || (err == nil && (!wait.Valid || wait.Int64 <= 0)) {
+		return true
+	} else if err != nil {
+		log.Printf("Error checking slow mode: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false
+	}
+
+	isModerator, err := isChatboardModerator(db, userID, c.GetInt("orgID"), chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return false
+	}
+	if isModerator {
+		return true
+	}
+
+	c.Header("Retry-After", strconv.FormatInt(wait.Int64, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("Slow mode is on; you can post again in %d seconds", wait.Int64),
+		"retry_after": wait.Int64,
+	})
+	return false
+}
+
+// authorizeModerator checks that the chatboard from the path is in the
+// caller's organization and that they moderate it. It writes the error
+// response and returns false otherwise.
+func (h *RestrictionHandler) authorizeModerator(c *gin.Context) (chatboardID int, title string, ok bool) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	chatboardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+		return 0, "", false
+	}
+
+	err = h.db.QueryRow(`
+        SELECT title FROM chatboards
+        WHERE id = $1 AND organization_id = $2
+    `, chatboardID, orgID).Scan(&title)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chatboard not found"})
+		return 0, "", false
+	} else if err != nil {
+		log.Printf("Error fetching chatboard: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard"})
+		return 0, "", false
+	}
+
+	isModerator, err := isChatboardModerator(h.db, userID, orgID, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return 0, "", false
+	}
+	if !isModerator {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can manage this chatboard's members"})
+		return 0, "", false
+	}
+
+	return chatboardID, title, true
+}
+
+// GetRestrictions lists the chatboard's active bans and mutes, newest first.
+// Pass kind=ban or kind=mute to see one of them.
+func (h *RestrictionHandler) GetRestrictions(c *gin.Context) {
+	chatboardID, _, ok := h.authorizeModerator(c)
+	if !ok {
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && kind != restrictionBan && kind != restrictionMute {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be ban or mute"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+        SELECT r.id, r.chatboard_id, r.user_id, COALESCE(u.username, ''), r.kind, r.reason,
+               r.expires_at, r.created_by, r.created_at
+        FROM chatboard_restrictions r
+        JOIN users u ON u.id = r.user_id
+        WHERE r.chatboard_id = $1
+        AND (r.expires_at IS NULL OR r.expires_at > NOW())
+        AND ($2 = '' OR r.kind = $2)
+        ORDER BY r.created_at DESC, r.id DESC
+    `, chatboardID, kind)
+	if err != nil {
+		log.Printf("Error fetching chatboard restrictions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch restrictions"})
+		return
+	}
+	defer rows.Close()
+
+	restrictions := make([]models.ChatboardRestriction, 0)
+	for rows.Next() {
+		restriction, err := scanRestriction(rows)
+		if err != nil {
+			log.Printf("Error scanning chatboard restriction: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch restrictions"})
+			return
+		}
+		restrictions = append(restrictions, restriction)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading chatboard restrictions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch restrictions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, restrictions)
+}
+
+func scanRestriction(row interface{ Scan(...interface{}) error }) (models.ChatboardRestriction, error) {
+	var (
+		restriction models.ChatboardRestriction
+		expiresAt   sql.NullTime
+		createdBy   sql.NullInt64
+	)
+	err := row.Scan(
+		&restriction.ID,
+		&restriction.ChatboardID,
+		&restriction.UserID,
+		&restriction.Username,
+		&restriction.Kind,
+		&restriction.Reason,
+		&expiresAt,
+		&createdBy,
+		&restriction.CreatedAt,
+	)
+	if expiresAt.Valid {
+		restriction.ExpiresAt = &expiresAt.Time
+	}
+	restriction.CreatedBy = nullIntPtr(createdBy)
+	return restriction, err
+}
+
+// BanUser keeps a member out of the chatboard
+func (h *RestrictionHandler) BanUser(c *gin.Context) {
+	h.restrict(c, restrictionBan)
+}
+
+// MuteUser lets a member read the chatboard but not post or comment on it
+func (h *RestrictionHandler) MuteUser(c *gin.Context) {
+	h.restrict(c, restrictionMute)
+}
+
+// restrict bans or mutes the member from the request on the chatboard from
+// the path, replacing an earlier restriction of the same kind
+func (h *RestrictionHandler) restrict(c *gin.Context, kind string) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	chatboardID, title, ok := h.authorizeModerator(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateRestrictionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Hours < 0 || req.Hours > maxRestrictionHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("hours must be between 0 and %d", maxRestrictionHours)})
+		return
+	}
+	if req.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot " + kind + " yourself"})
+		return
+	}
+
+	var isMember bool
+	err := h.db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM organization_members
+            WHERE user_id = $1 AND organization_id = $2
+        )
+    `, req.UserID, orgID).Scan(&isMember)
+	if err != nil {
+		log.Printf("Error fetching member: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if !isMember {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	isModerator, err := isChatboardModerator(h.db, req.UserID, orgID, chatboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if isModerator {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Moderators of this chatboard cannot be banned or muted"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + kind + " user"})
+		return
+	}
+	defer tx.Rollback()
+
+	restriction, err := scanRestriction(tx.QueryRow(`
+        INSERT INTO chatboard_restrictions (chatboard_id, user_id, kind, reason, expires_at, created_by)
+        VALUES ($1, $2, $3, $4, CASE WHEN $5::INTEGER > 0 THEN NOW() + MAKE_INTERVAL(hours => $5::INTEGER) END, $6)
+        ON CONFLICT (chatboard_id, user_id, kind) DO UPDATE
+        SET reason = EXCLUDED.reason, expires_at = EXCLUDED.expires_at,
+            created_by = EXCLUDED.created_by, created_at = CURRENT_TIMESTAMP
+        RETURNING id, chatboard_id, user_id, (SELECT COALESCE(username, '') FROM users WHERE id = $2),
+                  kind, reason, expires_at, created_by, created_at
+    `, chatboardID, req.UserID, kind, req.Reason, req.Hours, userID))
+	if err == nil {
+		err = logRestriction(tx, orgID, userID, kind, chatboardID, req.UserID, req.Reason, restriction.ExpiresAt)
+	}
+	if err == nil {
+		message := fmt.Sprintf("You have been muted on %s", title)
+		if kind == restrictionBan {
+			message = fmt.Sprintf("You have been banned from %s", title)
+		}
+		err = createNotification(tx, orgID, req.UserID, "chatboard_"+kind, message, gin.H{
+			"chatboard_id": chatboardID,
+			"reason":       req.Reason,
+			"expires_at":   restriction.ExpiresAt,
+		})
+	}
+	if err != nil {
+		log.Printf("Error restricting user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + kind + " user"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing restriction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + kind + " user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, restriction)
+}
+
+// UnbanUser lifts a member's ban from the chatboard
+func (h *RestrictionHandler) UnbanUser(c *gin.Context) {
+	h.lift(c, restrictionBan)
+}
+
+// UnmuteUser lifts a member's mute on the chatboard
+func (h *RestrictionHandler) UnmuteUser(c *gin.Context) {
+	h.lift(c, restrictionMute)
+}
+
+// lift removes the active restriction of the kind from the member in the path
+func (h *RestrictionHandler) lift(c *gin.Context, kind string) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	chatboardID, title, ok := h.authorizeModerator(c)
+	if !ok {
+		return
+	}
+
+	targetUserID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.LiftRestrictionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift " + kind})
+		return
+	}
+	defer tx.Rollback()
+
+	// Expired restrictions are cleaned up too, but only an active one counts
+	var active bool
+	err = tx.QueryRow(`
+        DELETE FROM chatboard_restrictions
+        WHERE chatboard_id = $1 AND user_id = $2 AND kind = $3
+        RETURNING expires_at IS NULL OR expires_at > NOW()
+    `, chatboardID, targetUserID, kind).Scan(&active)
+	if err == sql.ErrNoRows || (err == nil && !active) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This user has no active " + kind})
+		return
+	}
+	if err == nil {
+		err = logRestriction(tx, orgID, userID, "un"+kind, chatboardID, targetUserID, req.Note, nil)
+	}
+	if err == nil {
+		message := fmt.Sprintf("You are no longer muted on %s", title)
+		if kind == restrictionBan {
+			message = fmt.Sprintf("You are no longer banned from %s", title)
+		}
+		err = createNotification(tx, orgID, targetUserID, "chatboard_un"+kind, message, gin.H{
+			"chatboard_id": chatboardID,
+		})
+	}
+	if err != nil {
+		log.Printf("Error lifting restriction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift " + kind})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing restriction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift " + kind})
+		return
+	}
+
+	message := "User unmuted successfully"
+	if kind == restrictionBan {
+		message = "User unbanned successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// logRestriction records a ban, mute or their lifting in the moderation log
+func logRestriction(tx *sql.Tx, orgID, moderatorID int, action string, chatboardID, userID int, note string, expiresAt *time.Time) error {
+	_, err := tx.Exec(`
+        INSERT INTO moderation_actions (organization_id, moderator_id, action, target_type, chatboard_id, user_id, note, suspended_until)
+        VALUES ($1, $2, $3, 'user', $4, $5, $6, $7)
+    `, orgID, moderatorID, action, chatboardID, userID, note, expiresAt)
+	return err
+}
+
+// SetSlowMode sets how many seconds members wait between their posts and
+// comments on the chatboard; zero turns slow mode off
+func (h *RestrictionHandler) SetSlowMode(c *gin.Context) {
+	chatboardID, _, ok := h.authorizeModerator(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetSlowModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.Seconds < 0 || *req.Seconds > maxSlowModeSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("seconds must be between 0 and %d", maxSlowModeSeconds)})
+		return
+	}
+
+	_, err := h.db.Exec("UPDATE chatboards SET slow_mode_seconds = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", *req.Seconds, chatboardID)
+	if err != nil {
+		log.Printf("Error setting slow mode: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set slow mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Slow mode updated successfully", "slow_mode_seconds": *req.Seconds})
+}
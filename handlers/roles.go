@@ -17,8 +17,14 @@ func NewRoleHandler(db *sql.DB) *RoleHandler {
 	return &RoleHandler{db: db}
 }
 
-// CreateRole handles the creation of a new role
+// CreateRole handles the creation of a new role. Role names are shared by
+// all organizations, so only super admins can add them.
 func (h *RoleHandler) CreateRole(c *gin.Context) {
+	if !c.GetBool("isSuperAdmin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only super admins can create roles"})
+		return
+	}
+
 	var req models.CreateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -64,8 +70,21 @@ func (h *RoleHandler) GetRoles(c *gin.Context) {
 	c.JSON(http.StatusOK, roles)
 }
 
-// AssignGlobalRole handles assigning a global role to a user
+// AssignGlobalRole handles assigning a global role to a user within the
+// caller's organization
 func (h *RoleHandler) AssignGlobalRole(c *gin.Context) {
+	orgID := c.GetInt("orgID")
+
+	isAdmin, err := hasGlobalRole(h.db, c.GetInt("userID"), orgID, "Admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify admin status"})
+		return
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can assign global roles"})
+		return
+	}
+
 	var req models.AssignGlobalRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -80,9 +99,12 @@ func (h *RoleHandler) AssignGlobalRole(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	// Check if user exists
+	// Check if user is a member of the organization
 	var userExists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", req.UserID).Scan(&userExists)
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM organization_members WHERE user_id = $1 AND organization_id = $2)",
+		req.UserID, orgID,
+	).Scan(&userExists)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user existence"})
 		return
@@ -107,12 +129,15 @@ func (h *RoleHandler) AssignGlobalRole(c *gin.Context) {
 	// Insert the global role assignment
 	var response models.GlobalRoleResponse
 	err = tx.QueryRow(`
-		INSERT INTO user_roles (user_id, role_id, created_at)
-		VALUES ($1, $2, CURRENT_DATE)
+		INSERT INTO user_roles (user_id, role_id, organization_id, created_at)
+		VALUES ($1, $2, $3, CURRENT_DATE)
 		RETURNING id, user_id, role_id, created_at`,
-		req.UserID, req.RoleID,
+		req.UserID, req.RoleID, orgID,
 	).Scan(&response.ID, &response.UserID, &response.RoleID, &response.CreatedAt)
-	if err != nil {
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already has this role"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign role"})
 		return
 	}
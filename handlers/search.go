@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"database/sql"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// maxSearchQueryLength bounds the q parameter, in characters
+const maxSearchQueryLength = 200
+
+// Highlighted words are wrapped in private use characters by ts_headline and
+// swapped for <mark> after the text has been HTML escaped, so nothing in a
+// post can inject markup
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+const (
+	snippetOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … "`
+	titleOptions   = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", HighlightAll=true`
+)
+
+type SearchHandler struct {
+	db *sql.DB
+}
+
+func NewSearchHandler(db *sql.DB) *SearchHandler {
+	return &SearchHandler{db: db}
+}
+
+// Search finds posts and comments matching q in the chatboards the caller
+// can see, best matches first. q uses web search syntax: quoted phrases, OR
+// and -excluded words. chatboard_id narrows the search to one chatboard.
+func (h *SearchHandler) Search(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is too long"})
+		return
+	}
+	q = strings.NewReplacer(highlightStart, "", highlightStop, "").Replace(q)
+
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
+	chatboardIDs, err := accessibleChatboardIDs(h.db, userID, orgID)
+	if err != nil {
+		log.Printf("Error checking chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	if value := c.Query("chatboard_id"); value != "" {
+		chatboardID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+			return
+		}
+		accessible := false
+		for _, id := range chatboardIDs {
+			if id == chatboardID {
+				accessible = true
+				break
+			}
+		}
+		if !accessible {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+			return
+		}
+		chatboardIDs = []int{chatboardID}
+	}
+
+	// Words are matched both stemmed as English and as typed for Estonian.
+	// Posts and comments share one ordering, so their IDs are interleaved
+	// into a sort key that stays unique across both.
+	query := `
+        WITH q AS (
+            SELECT websearch_to_tsquery('english', $1) || websearch_to_tsquery('estonian', $1) AS query
+        ),
+        hits AS (
+            SELECT 'post' AS type, p.id, p.id AS post_id, p.chatboard_id, p.title AS post_title,
+                   p.title, p.content AS body, p.user_id, p.created_at,
+                   ts_rank(p.search_vector, q.query) AS rank, p.id * 2 AS sort_key
+            FROM posts p, q
+            WHERE p.search_vector @@ q.query
+            AND p.deleted_at IS NULL AND p.held_at IS NULL AND p.status = 'published'
+            AND p.chatboard_id = ANY($2)
+            UNION ALL
+            SELECT 'comment', cm.id, cm.post_id, p.chatboard_id, p.title,
+                   NULL, cm.comment, cm.user_id, cm.created_at,
+                   ts_rank(cm.search_vector, q.query), cm.id * 2 + 1
+            FROM comments cm
+            JOIN posts p ON p.id = cm.post_id, q
+            WHERE cm.search_vector @@ q.query
+            AND cm.deleted_at IS NULL AND cm.held_at IS NULL
+            AND p.deleted_at IS NULL AND p.held_at IS NULL
+            AND p.chatboard_id = ANY($2)
+        ),
+        matches AS (
+            SELECT * FROM hits
+            WHERE TRUE`
+	args := []interface{}{q, pq.Array(chatboardIDs)}
+
+	if page.After != nil {
+		query += " AND (rank, created_at, sort_key) < ($3::real, $4, $5)"
+		args = append(args, page.After.Rank, page.After.CreatedAt, page.After.ID)
+	}
+	query += " ORDER BY rank DESC, created_at DESC, sort_key DESC"
+	limit, args := page.limit(args)
+	query += limit
+
+	// Headlines are the slow part, so they are only made for the page
+	query += `
+        )
+        SELECT m.type, m.id, m.post_id, m.chatboard_id, m.post_title,
+               COALESCE(ts_headline('english', m.title, q.query, $` + strconv.Itoa(len(args)+1) + `), ''),
+               ts_headline('english', m.body, q.query, $` + strconv.Itoa(len(args)+2) + `),
+               COALESCE(u.username, ''), m.created_at, m.rank, m.sort_key
+        FROM matches m
+        JOIN users u ON u.id = m.user_id, q
+        ORDER BY m.rank DESC, m.created_at DESC, m.sort_key DESC`
+	args = append(args, titleOptions, snippetOptions)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error searching: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+	defer rows.Close()
+
+	results := make([]models.SearchResult, 0)
+	var cursors []pageCursor
+	for rows.Next() {
+		var (
+			result  models.SearchResult
+			rank    float32
+			sortKey int
+		)
+		err := rows.Scan(
+			&result.Type,
+			&result.ID,
+			&result.PostID,
+			&result.ChatboardID,
+			&result.PostTitle,
+			&result.TitleHighlight,
+			&result.Snippet,
+			&result.Author,
+			&result.CreatedAt,
+			&rank,
+			&sortKey,
+		)
+		if err != nil {
+			log.Printf("Error scanning search result: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+			return
+		}
+		result.TitleHighlight = highlightHTML(result.TitleHighlight)
+		result.Snippet = highlightHTML(result.Snippet)
+		results = append(results, result)
+		cursors = append(cursors, pageCursor{Rank: rank, CreatedAt: result.CreatedAt, ID: sortKey})
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading search results: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	results, next := trimPage(results, page.Limit, func(i int) pageCursor { return cursors[i] })
+	c.JSON(http.StatusOK, pageResponse(results, next))
+}
+
+// highlightHTML escapes a ts_headline result and turns its highlight markers
+// into <mark> tags
+func highlightHTML(headline string) string {
+	return strings.NewReplacer(
+		highlightStart, "<mark>",
+		highlightStop, "</mark>",
+	).Replace(html.EscapeString(headline))
+}
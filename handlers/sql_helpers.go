@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx so checks can run
+// inside or outside a transaction
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	queryRower
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// isUniqueViolation reports whether err was caused by a unique constraint
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// nullIntPtr returns the value of a nullable integer column, nil for NULL
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	id := int(n.Int64)
+	return &id
+}
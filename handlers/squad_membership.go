@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"unicorn_app_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// squadReapplyCooldown is how long a rejected or removed user has to wait
+// before applying to the same squad again
+const squadReapplyCooldown = 7 * 24 * time.Hour
+
+// squadTransitions lists the allowed membership status changes. The empty
+// status stands for "no membership yet".
+var squadTransitions = map[string][]string{
+	"":                         {models.SquadStatusPending},
+	models.SquadStatusPending:  {models.SquadStatusApproved, models.SquadStatusRejected},
+	models.SquadStatusApproved: {models.SquadStatusRemoved},
+	models.SquadStatusRejected: {models.SquadStatusPending},
+	models.SquadStatusRemoved:  {models.SquadStatusPending},
+}
+
+var errMembershipNotFound = errors.New("user is not associated with this squad")
+
+var (
+	errRoleNotFound       = errors.New("role not found")
+	errRoleNotRequestable = errors.New("role cannot be requested")
+)
+
+type squadTransitionError struct {
+	from, to string
+}
+
+func (e *squadTransitionError) Error() string {
+	from := e.from
+	if from == "" {
+		from = "no membership"
+	}
+	return fmt.Sprintf("cannot change squad membership from %s to %s", from, e.to)
+}
+
+type squadCooldownError struct {
+	retryAt time.Time
+}
+
+func (e *squadCooldownError) Error() string {
+	return fmt.Sprintf("you can apply to this squad again after %s", e.retryAt.Format("2006-01-02 15:04"))
+}
+
+func canTransitionSquadStatus(from, to string) bool {
+	for _, allowed := range squadTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionSquadMembership moves a user's squad membership to a new status.
+// It is the only place where user_squads.status is changed: it validates the
+// transition, enforces the re-application cooldown, records the change in the
+// history table and notifies the user when someone else made the decision.
+func transitionSquadMembership(tx *sql.Tx, userID, squadID int, to string, actorID int, reason string) error {
+	var from string
+	var retryAt sql.NullTime
+	var onCooldown sql.NullBool
+	err := tx.QueryRow(`
+		SELECT
+			status,
+			decided_at + make_interval(secs => $3),
+			decided_at + make_interval(secs => $3) > CURRENT_TIMESTAMP
+		FROM user_squads
+		WHERE user_id = $1 AND squad_id = $2
+		FOR UPDATE
+	`, userID, squadID, squadReapplyCooldown.Seconds()).Scan(&from, &retryAt, &onCooldown)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if from == "" && to != models.SquadStatusPending {
+		return errMembershipNotFound
+	}
+	if !canTransitionSquadStatus(from, to) {
+		return &squadTransitionError{from: from, to: to}
+	}
+	if to == models.SquadStatusPending && onCooldown.Valid && onCooldown.Bool {
+		return &squadCooldownError{retryAt: retryAt.Time}
+	}
+
+	if from == "" {
+		// There was no row to lock, so a concurrent first request may have
+		// inserted one meanwhile. Then the transition is checked again
+		// against that row.
+		var result sql.Result
+		result, err = tx.Exec(`
+			INSERT INTO user_squads (user_id, squad_id, status, reason)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+			ON CONFLICT (user_id, squad_id) DO NOTHING
+		`, userID, squadID, to, reason)
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				return transitionSquadMembership(tx, userID, squadID, to, actorID, reason)
+			}
+		}
+	} else if to == models.SquadStatusPending {
+		_, err = tx.Exec(`
+			UPDATE user_squads
+			SET status = $3, reason = NULLIF($4, ''), decided_by = NULL, decided_at = NULL,
+				requested_role_id = NULL
+			WHERE user_id = $1 AND squad_id = $2
+		`, userID, squadID, to, reason)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE user_squads
+			SET status = $3, reason = NULLIF($4, ''), decided_by = $5, decided_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND squad_id = $2
+		`, userID, squadID, to, reason, actorID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The requested role is granted with the approval, never before
+	if to == models.SquadStatusApproved {
+		if _, err = tx.Exec(`
+			INSERT INTO user_squad_roles (user_id, squad_id, role_id)
+			SELECT user_id, squad_id, requested_role_id FROM user_squads
+			WHERE user_id = $1 AND squad_id = $2 AND requested_role_id IS NOT NULL
+			ON CONFLICT (user_id, squad_id, role_id) DO NOTHING
+		`, userID, squadID); err != nil {
+			return err
+		}
+	}
+
+	// Squad roles only mean something while the membership is active
+	if to == models.SquadStatusRejected || to == models.SquadStatusRemoved {
+		if _, err = tx.Exec(`
+			DELETE FROM user_squad_roles
+			WHERE user_id = $1 AND squad_id = $2
+		`, userID, squadID); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(`
+		INSERT INTO user_squad_status_history (user_id, squad_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`, userID, squadID, from, to, reason, actorID); err != nil {
+		return err
+	}
+
+	if actorID == userID {
+		return nil
+	}
+
+	var squadName string
+	var orgID int
+	if err = tx.QueryRow("SELECT name, organization_id FROM squads WHERE id = $1", squadID).Scan(&squadName, &orgID); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Your membership in %s is now %s", squadName, to)
+	switch to {
+	case models.SquadStatusApproved:
+		message = fmt.Sprintf("Your request to join %s was approved", squadName)
+	case models.SquadStatusRejected:
+		message = fmt.Sprintf("Your request to join %s was rejected", squadName)
+	case models.SquadStatusRemoved:
+		message = fmt.Sprintf("You were removed from %s", squadName)
+	}
+	if reason != "" {
+		message += ": " + reason
+	}
+
+	return createNotification(tx, orgID, userID, "squad_membership", message, gin.H{
+		"squad_id": squadID,
+		"status":   to,
+		"reason":   reason,
+	})
+}
+
+// requestSquadRole records the squad role a pending applicant asks for. It is
+// granted when a manager approves the membership. Admin is a global role, and
+// Head Unicorns lead the squad, so only the squad's managers hand those out.
+func requestSquadRole(tx *sql.Tx, userID, squadID, roleID int) error {
+	var roleName string
+	err := tx.QueryRow("SELECT role FROM roles WHERE id = $1", roleID).Scan(&roleName)
+	if err == sql.ErrNoRows {
+		return errRoleNotFound
+	} else if err != nil {
+		return err
+	}
+	if roleName == "Admin" || roleName == "Head Unicorn" {
+		return errRoleNotRequestable
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_squads SET requested_role_id = $3
+		WHERE user_id = $1 AND squad_id = $2 AND status = 'Pending'
+	`, userID, squadID, roleID)
+	return err
+}
+
+// respondSquadTransitionError writes the HTTP response for a failed
+// membership transition
+func respondSquadTransitionError(c *gin.Context, err error) {
+	var transitionErr *squadTransitionError
+	var cooldownErr *squadCooldownError
+
+	switch {
+	case errors.Is(err, errMembershipNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not associated with this squad"})
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error()})
+	case errors.As(err, &cooldownErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    cooldownErr.Error(),
+			"retry_at": cooldownErr.retryAt,
+		})
+	default:
+		log.Printf("Error updating squad membership: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update squad membership"})
+	}
+}
+
+// JoinSquad lets the current user apply to a squad. The membership starts as
+// Pending until a squad leader or admin decides on it.
+func (h *SquadHandler) JoinSquad(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	squadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid squad ID"})
+		return
+	}
+
+	var req models.JoinSquadRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var archived bool
+	err = h.db.QueryRow(
+		"SELECT archived_at IS NOT NULL FROM squads WHERE id = $1 AND organization_id = $2",
+		squadID, c.GetInt("orgID"),
+	).Scan(&archived)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify squad"})
+		return
+	}
+	if archived {
+		c.JSON(http.StatusConflict, gin.H{"error": "This squad is archived and does not accept new members"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := transitionSquadMembership(tx, userID, squadID, models.SquadStatusPending, userID, req.Reason); err != nil {
+		respondSquadTransitionError(c, err)
+		return
+	}
+
+	if req.RoleID > 0 {
+		err = requestSquadRole(tx, userID, squadID, req.RoleID)
+		switch {
+		case errors.Is(err, errRoleNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+			return
+		case errors.Is(err, errRoleNotRequestable):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Admin and Head Unicorn roles are assigned by the squad's managers and cannot be requested"})
+			return
+		case err != nil:
+			log.Printf("Error saving requested squad role: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save requested role"})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SquadMembershipResponse{
+		UserID:  userID,
+		SquadID: squadID,
+		Status:  models.SquadStatusPending,
+		Reason:  req.Reason,
+	})
+}
+
+// ApproveSquadMember approves a pending membership
+func (h *SquadHandler) ApproveSquadMember(c *gin.Context) {
+	h.decideMembership(c, models.SquadStatusApproved)
+}
+
+// RejectSquadMember rejects a pending membership
+func (h *SquadHandler) RejectSquadMember(c *gin.Context) {
+	h.decideMembership(c, models.SquadStatusRejected)
+}
+
+// RemoveSquadMember removes an approved member from the squad. The membership
+// row is kept with the Removed status so the history stays intact.
+func (h *SquadHandler) RemoveSquadMember(c *gin.Context) {
+	h.decideMembership(c, models.SquadStatusRemoved)
+}
+
+func (h *SquadHandler) decideMembership(c *gin.Context, status string) {
+	actorID := c.GetInt("userID")
+
+	squadID, ok := h.authorizeSquadManagement(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.SquadDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := transitionSquadMembership(tx, memberID, squadID, status, actorID, req.Reason); err != nil {
+		respondSquadTransitionError(c, err)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SquadMembershipResponse{
+		UserID:    memberID,
+		SquadID:   squadID,
+		Status:    status,
+		Reason:    req.Reason,
+		DecidedBy: &actorID,
+	})
+}
+
+// GetSquadMemberHistory returns the status history of a membership. Members
+// can see their own history, squad managers can see everyone's.
+func (h *SquadHandler) GetSquadMemberHistory(c *gin.Context) {
+	userID := c.GetInt("userID")
+
+	squadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid squad ID"})
+		return
+	}
+	memberID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	exists, err := h.squadExists(c.GetInt("orgID"), squadID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify squad"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return
+	}
+
+	if memberID != userID {
+		hasPermission, err := canManageSquad(h.db, userID, c.GetInt("orgID"), squadID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !hasPermission {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own membership history"})
+			return
+		}
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, from_status, to_status, COALESCE(reason, ''), COALESCE(changed_by, 0), created_at
+		FROM user_squad_status_history
+		WHERE user_id = $1 AND squad_id = $2
+		ORDER BY created_at, id
+	`, memberID, squadID)
+	if err != nil {
+		log.Printf("Error fetching membership history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch membership history"})
+		return
+	}
+	defer rows.Close()
+
+	history := make([]models.SquadStatusHistoryEntry, 0)
+	for rows.Next() {
+		var entry models.SquadStatusHistoryEntry
+		var createdAt sql.NullTime
+		if err := rows.Scan(&entry.ID, &entry.FromStatus, &entry.ToStatus, &entry.Reason, &entry.ChangedBy, &createdAt); err != nil {
+			log.Printf("Error scanning membership history: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch membership history"})
+			return
+		}
+		if createdAt.Valid {
+			entry.CreatedAt = createdAt.Time.Format("2006-01-02 15:04")
+		}
+		history = append(history, entry)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 
 	"unicorn_app_backend/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
 type SquadHandler struct {
@@ -17,18 +21,48 @@ func NewSquadHandler(db *sql.DB) *SquadHandler {
 	return &SquadHandler{db: db}
 }
 
-// CreateSquad handles the creation of a new squad
+// CreateSquad handles the creation of a new squad. Admins and Head Unicorns
+// can create any squad, country admins only squads in their countries.
 func (h *SquadHandler) CreateSquad(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
 	var req models.CreateSquadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	allowed, err := hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn")
+	if err == nil && !allowed && req.CountryID != nil {
+		allowed, err = isCountryAdmin(h.db, userID, *req.CountryID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only Admins, Head Unicorns and the country's admins can create squads"})
+		return
+	}
+
+	if req.CountryID != nil {
+		exists, err := h.countryExists(orgID, *req.CountryID)
+		if err != nil {
+			log.Printf("Error checking country existence: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify country"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Country not found"})
+			return
+		}
+	}
+
 	var squadID int
-	err := h.db.QueryRow(
-		"INSERT INTO squads (name) VALUES ($1) RETURNING id",
-		req.Name,
+	err = h.db.QueryRow(
+		"INSERT INTO squads (name, country_id, organization_id) VALUES ($1, $2, $3) RETURNING id",
+		req.Name, req.CountryID, orgID,
 	).Scan(&squadID)
 
 	if err != nil {
@@ -37,14 +71,27 @@ func (h *SquadHandler) CreateSquad(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, models.SquadResponse{
-		ID:   squadID,
-		Name: req.Name,
+		ID:        squadID,
+		Name:      req.Name,
+		CountryID: req.CountryID,
 	})
 }
 
-// GetSquads handles retrieving all squads
+// GetSquads handles retrieving all squads, hiding archived ones unless
+// include_archived=true is given
 func (h *SquadHandler) GetSquads(c *gin.Context) {
-	rows, err := h.db.Query("SELECT id, name FROM squads")
+	query := `
+		SELECT s.id, s.name, s.country_id, COALESCE(co.name, ''), s.archived_at IS NOT NULL
+		FROM squads s
+		LEFT JOIN countries co ON co.id = s.country_id
+		WHERE s.organization_id = $1
+	`
+	if c.Query("include_archived") != "true" {
+		query += " AND s.archived_at IS NULL"
+	}
+	query += " ORDER BY s.name"
+
+	rows, err := h.db.Query(query, c.GetInt("orgID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch squads"})
 		return
@@ -54,12 +101,501 @@ func (h *SquadHandler) GetSquads(c *gin.Context) {
 	var squads []models.SquadResponse
 	for rows.Next() {
 		var squad models.SquadResponse
-		if err := rows.Scan(&squad.ID, &squad.Name); err != nil {
+		var countryID sql.NullInt64
+		if err := rows.Scan(&squad.ID, &squad.Name, &countryID, &squad.CountryName, &squad.Archived); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan squad"})
 			return
 		}
+		if countryID.Valid {
+			id := int(countryID.Int64)
+			squad.CountryID = &id
+		}
 		squads = append(squads, squad)
 	}
 
 	c.JSON(http.StatusOK, squads)
 }
+
+// GetSquadByID returns a squad with its member counts and leaders
+func (h *SquadHandler) GetSquadByID(c *gin.Context) {
+	squadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid squad ID"})
+		return
+	}
+
+	var squad models.SquadDetailResponse
+	var countryID sql.NullInt64
+	var archivedAt sql.NullTime
+	err = h.db.QueryRow(`
+		SELECT s.id, s.name, s.country_id, COALESCE(co.name, ''), s.archived_at
+		FROM squads s
+		LEFT JOIN countries co ON co.id = s.country_id
+		WHERE s.id = $1 AND s.organization_id = $2
+	`, squadID, c.GetInt("orgID")).Scan(&squad.ID, &squad.Name, &countryID, &squad.CountryName, &archivedAt)
+
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching squad: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch squad"})
+		return
+	}
+
+	if countryID.Valid {
+		id := int(countryID.Int64)
+		squad.CountryID = &id
+	}
+	if archivedAt.Valid {
+		squad.Archived = true
+		squad.ArchivedAt = archivedAt.Time.Format("2006-01-02 15:04")
+	}
+
+	// Count members per status
+	squad.MemberCounts = make(map[string]int)
+	countRows, err := h.db.Query(`
+		SELECT status, COUNT(*)
+		FROM user_squads
+		WHERE squad_id = $1
+		GROUP BY status
+	`, squadID)
+	if err != nil {
+		log.Printf("Error counting squad members: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count squad members"})
+		return
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var status string
+		var count int
+		if err := countRows.Scan(&status, &count); err != nil {
+			log.Printf("Error scanning member count: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count squad members"})
+			return
+		}
+		squad.MemberCounts[status] = count
+		squad.MemberCount += count
+	}
+
+	squad.Leaders, err = h.getSquadMembers(squadID, models.SquadStatusApproved, "Head Unicorn")
+	if err != nil {
+		log.Printf("Error fetching squad leaders: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch squad leaders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, squad)
+}
+
+// UpdateSquad renames a squad or moves it to another country
+func (h *SquadHandler) UpdateSquad(c *gin.Context) {
+	squadID, ok := h.authorizeSquadManagement(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSquadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil && *req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Squad name cannot be empty"})
+		return
+	}
+	if req.CountryID != nil && req.ClearCountry {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot set and clear the country at once"})
+		return
+	}
+
+	// A squad without a country is out of every country admin's reach
+	if req.ClearCountry {
+		isGlobal, err := hasGlobalRole(h.db, c.GetInt("userID"), c.GetInt("orgID"), "Admin", "Head Unicorn")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !isGlobal {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and head unicorns can take a squad out of its country"})
+			return
+		}
+	}
+
+	if req.CountryID != nil {
+		exists, err := h.countryExists(c.GetInt("orgID"), *req.CountryID)
+		if err != nil {
+			log.Printf("Error checking country existence: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify country"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Country not found"})
+			return
+		}
+
+		// Country admins can only move squads between their own countries
+		isGlobal, err := hasGlobalRole(h.db, c.GetInt("userID"), c.GetInt("orgID"), "Admin", "Head Unicorn")
+		if err == nil && !isGlobal {
+			var allowed bool
+			allowed, err = isCountryAdmin(h.db, c.GetInt("userID"), *req.CountryID)
+			if err == nil && !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only move squads to countries you manage"})
+				return
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+	}
+
+	_, err := h.db.Exec(`
+		UPDATE squads
+		SET name = COALESCE($1, name),
+			country_id = CASE WHEN $4 THEN NULL ELSE COALESCE($2, country_id) END
+		WHERE id = $3
+	`, req.Name, req.CountryID, squadID, req.ClearCountry)
+	if err != nil {
+		log.Printf("Error updating squad: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update squad"})
+		return
+	}
+
+	h.GetSquadByID(c)
+}
+
+// ArchiveSquad hides a squad from listings while keeping its history
+func (h *SquadHandler) ArchiveSquad(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// UnarchiveSquad restores an archived squad
+func (h *SquadHandler) UnarchiveSquad(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *SquadHandler) setArchived(c *gin.Context, archived bool) {
+	squadID, ok := h.authorizeSquadManagement(c)
+	if !ok {
+		return
+	}
+
+	query := "UPDATE squads SET archived_at = CURRENT_TIMESTAMP WHERE id = $1 AND archived_at IS NULL"
+	if !archived {
+		query = "UPDATE squads SET archived_at = NULL WHERE id = $1"
+	}
+
+	if _, err := h.db.Exec(query, squadID); err != nil {
+		log.Printf("Error updating squad archive state: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update squad"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Squad updated successfully",
+		"archived": archived,
+	})
+}
+
+// DeleteSquad permanently removes a squad and its memberships
+func (h *SquadHandler) DeleteSquad(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	squadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid squad ID"})
+		return
+	}
+
+	hasPermission, err := hasGlobalRole(h.db, userID, orgID, "Admin", "Head Unicorn")
+	if err == nil && !hasPermission {
+		hasPermission, err = isSquadCountryAdmin(h.db, userID, squadID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return
+	}
+
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins, head unicorns and country admins can delete squads"})
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM squads WHERE id = $1 AND organization_id = $2", squadID, orgID)
+	if err != nil {
+		log.Printf("Error deleting squad: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete squad"})
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify deletion"})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Squad deleted successfully"})
+}
+
+// GetSquadMembers lists the squad's members, optionally filtered by status and role
+func (h *SquadHandler) GetSquadMembers(c *gin.Context) {
+	squadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid squad ID"})
+		return
+	}
+
+	exists, err := h.squadExists(c.GetInt("orgID"), squadID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify squad"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return
+	}
+
+	// Applicants and rejected members are only shown to the squad's managers
+	status := c.Query("status")
+	if status != models.SquadStatusApproved {
+		canManage, err := canManageSquad(h.db, c.GetInt("userID"), c.GetInt("orgID"), squadID)
+		if err != nil {
+			log.Printf("Error checking squad permissions: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+			return
+		}
+		if !canManage {
+			if status != "" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only squad managers can list members by status"})
+				return
+			}
+			status = models.SquadStatusApproved
+		}
+	}
+
+	members, err := h.getSquadMembers(squadID, status, c.Query("role"))
+	if err != nil {
+		log.Printf("Error fetching squad members: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch squad members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateSquadMemberRole replaces a member's role within the squad
+func (h *SquadHandler) UpdateSquadMemberRole(c *gin.Context) {
+	squadID, ok := h.authorizeSquadManagement(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateSquadMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Admin is a global role and cannot be held within a squad
+	var roleName string
+	err = tx.QueryRow("SELECT role FROM roles WHERE id = $1", req.RoleID).Scan(&roleName)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role existence"})
+		return
+	}
+	if roleName == "Admin" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admin is a global role and cannot be assigned within a squad"})
+		return
+	}
+
+	var status string
+	err = tx.QueryRow(`
+		SELECT status FROM user_squads
+		WHERE squad_id = $1 AND user_id = $2
+		AND status IN ($3, $4)
+	`, squadID, memberID, models.SquadStatusPending, models.SquadStatusApproved).Scan(&status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this squad"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+
+	// Applicants get the role once they are approved
+	if status == models.SquadStatusPending {
+		_, err = tx.Exec(`
+			UPDATE user_squads SET requested_role_id = $3
+			WHERE squad_id = $1 AND user_id = $2
+		`, squadID, memberID, req.RoleID)
+	} else {
+		_, err = tx.Exec(`
+			DELETE FROM user_squad_roles
+			WHERE squad_id = $1 AND user_id = $2
+		`, squadID, memberID)
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT INTO user_squad_roles (user_id, squad_id, role_id)
+				VALUES ($1, $2, $3)
+			`, memberID, squadID, req.RoleID)
+		}
+	}
+	if err != nil {
+		log.Printf("Error assigning squad role: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member role"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Member role updated to %s", roleName),
+		"user_id": memberID,
+		"role":    roleName,
+	})
+}
+
+// authorizeSquadManagement parses the squad ID from the URL and checks that it
+// exists and the caller may manage it. It writes the error response itself.
+func (h *SquadHandler) authorizeSquadManagement(c *gin.Context) (int, bool) {
+	userID := c.GetInt("userID")
+
+	squadID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid squad ID"})
+		return 0, false
+	}
+
+	exists, err := h.squadExists(c.GetInt("orgID"), squadID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify squad"})
+		return 0, false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Squad not found"})
+		return 0, false
+	}
+
+	hasPermission, err := canManageSquad(h.db, userID, c.GetInt("orgID"), squadID)
+	if err != nil {
+		log.Printf("Error checking squad permissions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify permissions"})
+		return 0, false
+	}
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins and head unicorns can manage this squad"})
+		return 0, false
+	}
+
+	return squadID, true
+}
+
+// getSquadMembers fetches squad members with their squad roles. Empty status
+// or role values disable the corresponding filter.
+func (h *SquadHandler) getSquadMembers(squadID int, status, role string) ([]models.SquadMember, error) {
+	query := `
+		SELECT
+			u.id,
+			COALESCE(u.username, ''),
+			u.first_name,
+			u.last_name,
+			us.status,
+			us.created_at,
+			COALESCE(ARRAY_AGG(DISTINCT r.role) FILTER (WHERE r.role IS NOT NULL), ARRAY[]::VARCHAR[]) as roles,
+			rr.role
+		FROM user_squads us
+		JOIN users u ON u.id = us.user_id
+		LEFT JOIN user_squad_roles usr ON usr.user_id = us.user_id AND usr.squad_id = us.squad_id
+		LEFT JOIN roles r ON r.id = usr.role_id
+		LEFT JOIN roles rr ON rr.id = us.requested_role_id
+		WHERE us.squad_id = $1
+	`
+	params := []interface{}{squadID}
+
+	if status != "" {
+		params = append(params, status)
+		query += fmt.Sprintf(" AND us.status = $%d", len(params))
+	}
+
+	query += " GROUP BY u.id, u.username, u.first_name, u.last_name, us.status, us.created_at, rr.role"
+
+	if role != "" {
+		params = append(params, role)
+		query += fmt.Sprintf(" HAVING $%d = ANY(ARRAY_AGG(r.role))", len(params))
+	}
+
+	query += " ORDER BY u.first_name, u.last_name"
+
+	rows, err := h.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]models.SquadMember, 0)
+	for rows.Next() {
+		var member models.SquadMember
+		var joinedAt sql.NullTime
+		var requestedRole sql.NullString
+		if err := rows.Scan(
+			&member.UserID,
+			&member.Username,
+			&member.FirstName,
+			&member.LastName,
+			&member.Status,
+			&joinedAt,
+			pq.Array(&member.Roles),
+			&requestedRole,
+		); err != nil {
+			return nil, err
+		}
+		if requestedRole.Valid {
+			member.RequestedRole = &requestedRole.String
+		}
+		if joinedAt.Valid {
+			member.JoinedAt = joinedAt.Time.Format("2006-01-02")
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (h *SquadHandler) squadExists(orgID, squadID int) (bool, error) {
+	var exists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM squads WHERE id = $1 AND organization_id = $2)", squadID, orgID).Scan(&exists)
+	return exists, err
+}
+
+func (h *SquadHandler) countryExists(orgID, countryID int) (bool, error) {
+	var exists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM countries WHERE id = $1 AND organization_id = $2)", countryID, orgID).Scan(&exists)
+	return exists, err
+}
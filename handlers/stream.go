@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicorn_app_backend/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment so proxies
+// and mobile networks don't close the connection
+const streamKeepAlive = 25 * time.Second
+
+type StreamHandler struct {
+	db     *sql.DB
+	broker *realtime.Broker
+}
+
+func NewStreamHandler(db *sql.DB, broker *realtime.Broker) *StreamHandler {
+	return &StreamHandler{db: db, broker: broker}
+}
+
+// StreamChatboards pushes chatboard events as Server-Sent Events. Clients
+// pick chatboards with chatboard_ids=1,2,3 and get all the chatboards they
+// can access otherwise. Access is checked when the stream opens, so clients
+// should reconnect after their memberships change.
+func (h *StreamHandler) StreamChatboards(c *gin.Context) {
+	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
+
+	accessibleIDs, err := accessibleChatboardIDs(h.db, userID, orgID)
+	if err != nil {
+		log.Printf("Error evaluating chatboard access: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+
+	chatboardIDs := accessibleIDs
+	if requested := c.Query("chatboard_ids"); requested != "" {
+		accessible := make(map[int]bool, len(accessibleIDs))
+		for _, id := range accessibleIDs {
+			accessible[id] = true
+		}
+
+		chatboardIDs = nil
+		for _, part := range strings.Split(requested, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+				return
+			}
+			if !accessible[id] {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to chatboard " + strconv.Itoa(id)})
+				return
+			}
+			chatboardIDs = append(chatboardIDs, id)
+		}
+	}
+
+	sub := h.broker.Subscribe(chatboardIDs)
+	defer h.broker.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Tell the client which chatboards it is subscribed to
+	c.SSEvent("subscribed", gin.H{"chatboard_ids": chatboardIDs})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.Events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"unicorn_app_backend/models"
+	"unicorn_app_backend/realtime"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,18 +21,12 @@ func NewTestHandler(db *sql.DB) *TestHandler {
 }
 
 func (h *TestHandler) CreateTest(c *gin.Context) {
-	// Get the user ID from the context
+	// Get the user and organization from the context
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
 	// Check if user is Admin
-	var isAdmin bool
-	err := h.db.QueryRow(`
-		SELECT EXISTS (
-			SELECT 1 FROM user_roles ur
-			JOIN roles r ON r.id = ur.role_id
-			WHERE ur.user_id = $1 AND r.role = 'Admin'
-		)
-	`, userID).Scan(&isAdmin)
+	isAdmin, err := hasGlobalRole(h.db, userID, orgID, "Admin")
 
 	if err != nil {
 		log.Printf("Error checking admin status: %v", err)
@@ -50,6 +46,26 @@ func (h *TestHandler) CreateTest(c *gin.Context) {
 		return
 	}
 
+	// Tests can only be attached to lessons of the same organization
+	if req.LessonID != 0 {
+		var lessonExists bool
+		err = h.db.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM lessons l
+				JOIN courses co ON co.id = l.course_id
+				WHERE l.id = $1 AND co.organization_id = $2
+			)
+		`, req.LessonID, orgID).Scan(&lessonExists)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify lesson"})
+			return
+		}
+		if !lessonExists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Lesson not found"})
+			return
+		}
+	}
+
 	// Start transaction
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -61,10 +77,10 @@ func (h *TestHandler) CreateTest(c *gin.Context) {
 	// Create test
 	var testID int
 	err = tx.QueryRow(`
-		INSERT INTO tests (lesson_id, title, reward_details, created_at)
-		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		INSERT INTO tests (lesson_id, title, reward_details, organization_id, created_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
 		RETURNING id
-	`, req.LessonID, req.Title, req.RewardDetails).Scan(&testID)
+	`, req.LessonID, req.Title, req.RewardDetails, orgID).Scan(&testID)
 
 	if err != nil {
 		log.Printf("Error creating test: %v", err)
@@ -117,6 +133,7 @@ func (h *TestHandler) CreateTest(c *gin.Context) {
 
 func (h *TestHandler) SubmitTestAttempt(c *gin.Context) {
 	userID := c.GetInt("userID")
+	orgID := c.GetInt("orgID")
 
 	var attempt models.TestAttempt
 	if err := c.ShouldBindJSON(&attempt); err != nil {
@@ -124,9 +141,24 @@ func (h *TestHandler) SubmitTestAttempt(c *gin.Context) {
 		return
 	}
 
+	// Check if the test exists in the organization
+	var testExists bool
+	err := h.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM tests WHERE id = $1 AND organization_id = $2)",
+		attempt.TestID, orgID,
+	).Scan(&testExists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify test"})
+		return
+	}
+	if !testExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Test not found"})
+		return
+	}
+
 	// Check if user has already attempted this test
 	var existingAttemptID int
-	err := h.db.QueryRow(
+	err = h.db.QueryRow(
 		"SELECT id FROM test_attempts WHERE test_id = $1 AND user_id = $2",
 		attempt.TestID, userID,
 	).Scan(&existingAttemptID)
@@ -178,16 +210,16 @@ func (h *TestHandler) SubmitTestAttempt(c *gin.Context) {
 	var rewardCatalogID int
 	var rewardDetails string
 	if attempt.Score >= 90 {
-		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Test Master'").Scan(&rewardCatalogID)
+		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Test Master' AND organization_id = $1", orgID).Scan(&rewardCatalogID)
 		rewardDetails = "Congratulations! You've achieved Test Master status!"
 	} else if attempt.Score >= 80 {
-		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Quick Learner'").Scan(&rewardCatalogID)
+		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Quick Learner' AND organization_id = $1", orgID).Scan(&rewardCatalogID)
 		rewardDetails = "Great job! You're a Quick Learner!"
 	} else if attempt.Score >= 70 {
-		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Good Progress'").Scan(&rewardCatalogID)
+		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Good Progress' AND organization_id = $1", orgID).Scan(&rewardCatalogID)
 		rewardDetails = "Well done! You're making Good Progress!"
 	} else if attempt.Score >= 20 {
-		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Test Completion'").Scan(&rewardCatalogID)
+		err = tx.QueryRow("SELECT id FROM rewards_catalog WHERE name = 'Test Completion' AND organization_id = $1", orgID).Scan(&rewardCatalogID)
 		rewardDetails = "Good work! You've completed the test!"
 	} else {
 		rewardDetails = "Keep practicing! Score 20% or higher to earn a reward."
@@ -235,9 +267,9 @@ func (h *TestHandler) GetUserRewards(c *gin.Context) {
 		FROM rewards r
 		JOIN test_attempts ta ON r.attempt_id = ta.id
 		JOIN tests t ON ta.test_id = t.id
-		WHERE ta.user_id = $1
+		WHERE ta.user_id = $1 AND t.organization_id = $2
 		ORDER BY ta.completed_at DESC
-	`, userID)
+	`, userID, c.GetInt("orgID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rewards"})
 		return
@@ -293,8 +325,8 @@ func (h *TestHandler) CreateReward(c *gin.Context) {
 		SELECT ta.user_id, t.title, ta.score 
 		FROM test_attempts ta
 		JOIN tests t ON ta.test_id = t.id
-		WHERE ta.id = $1`,
-		reward.AttemptID,
+		WHERE ta.id = $1 AND t.organization_id = $2`,
+		reward.AttemptID, c.GetInt("orgID"),
 	).Scan(&userID, &testTitle, &score)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -356,8 +388,13 @@ func (h *TestHandler) UpdateReward(c *gin.Context) {
 
 	// Update the reward
 	result, err := h.db.Exec(
-		"UPDATE rewards SET reward_details = $1 WHERE id = $2",
-		reward.RewardDetails, rewardID,
+		`UPDATE rewards SET reward_details = $1
+		WHERE id = $2 AND attempt_id IN (
+			SELECT ta.id FROM test_attempts ta
+			JOIN tests t ON t.id = ta.test_id
+			WHERE t.organization_id = $3
+		)`,
+		reward.RewardDetails, rewardID, c.GetInt("orgID"),
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reward"})
@@ -385,21 +422,29 @@ func (h *TestHandler) GetTests(c *gin.Context) {
 	// Optional query parameters for filtering
 	lessonID := c.Query("lesson_id")
 
+	page, ok := parsePageQuery(c)
+	if !ok {
+		return
+	}
+
 	query := `
 		SELECT t.id, t.lesson_id, t.title, t.reward_details, t.created_at,
 		       l.title as lesson_title
 		FROM tests t
 		LEFT JOIN lessons l ON t.lesson_id = l.id
-		WHERE 1=1
+		WHERE t.organization_id = $1
 	`
-	args := []interface{}{}
+	args := []interface{}{c.GetInt("orgID")}
 
 	if lessonID != "" {
-		query += " AND t.lesson_id = $1"
+		query += " AND t.lesson_id = $2"
 		args = append(args, lessonID)
 	}
 
-	query += " ORDER BY t.created_at DESC"
+	keyset, args := page.keyset("t.created_at", "t.id", false, args)
+	query += keyset + " ORDER BY t.created_at DESC, t.id DESC"
+	limit, args := page.limit(args)
+	query += limit
 
 	rows, err := h.db.Query(query, args...)
 	if err != nil {
@@ -408,7 +453,8 @@ func (h *TestHandler) GetTests(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var tests []gin.H
+	tests := make([]gin.H, 0)
+	var cursors []pageCursor
 	for rows.Next() {
 		var (
 			id            int
@@ -437,9 +483,11 @@ func (h *TestHandler) GetTests(c *gin.Context) {
 		}
 
 		tests = append(tests, test)
+		cursors = append(cursors, pageCursor{CreatedAt: createdAt.Time, ID: id})
 	}
 
-	c.JSON(http.StatusOK, tests)
+	tests, next := trimPage(tests, page.Limit, func(i int) pageCursor { return cursors[i] })
+	c.JSON(http.StatusOK, pageResponse(tests, next))
 }
 
 func (h *TestHandler) GetTestByID(c *gin.Context) {
@@ -473,8 +521,8 @@ func (h *TestHandler) GetTestByID(c *gin.Context) {
 		SELECT t.id, t.lesson_id, t.title, t.reward_details, t.created_at, l.title
 		FROM tests t
 		LEFT JOIN lessons l ON t.lesson_id = l.id
-		WHERE t.id = $1
-	`, testID).Scan(&test.ID, &test.LessonID, &test.Title, &test.RewardDetails, &createdAt, &test.LessonTitle)
+		WHERE t.id = $1 AND t.organization_id = $2
+	`, testID, c.GetInt("orgID")).Scan(&test.ID, &test.LessonID, &test.Title, &test.RewardDetails, &createdAt, &test.LessonTitle)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -564,8 +612,9 @@ func (h *TestHandler) GetRewardsCatalog(c *gin.Context) {
 	rows, err := h.db.Query(`
 		SELECT id, name, description, points, type, created_at
 		FROM rewards_catalog
+		WHERE organization_id = $1
 		ORDER BY points DESC, name ASC
-	`)
+	`, c.GetInt("orgID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rewards catalog"})
 		return
@@ -605,10 +654,10 @@ func (h *TestHandler) CreateRewardCatalog(c *gin.Context) {
 
 	var rewardID int
 	err := h.db.QueryRow(`
-		INSERT INTO rewards_catalog (name, description, points, type, created_at)
-		VALUES ($1, $2, $3, $4, CURRENT_DATE)
+		INSERT INTO rewards_catalog (name, description, points, type, organization_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_DATE)
 		RETURNING id`,
-		req.Name, req.Description, req.Points, req.Type,
+		req.Name, req.Description, req.Points, req.Type, c.GetInt("orgID"),
 	).Scan(&rewardID)
 
 	if err != nil {
@@ -643,8 +692,8 @@ func (h *TestHandler) UpdateRewardCatalog(c *gin.Context) {
 	result, err := h.db.Exec(`
 		UPDATE rewards_catalog
 		SET name = $1, description = $2, points = $3, type = $4
-		WHERE id = $5`,
-		req.Name, req.Description, req.Points, req.Type, rewardID,
+		WHERE id = $5 AND organization_id = $6`,
+		req.Name, req.Description, req.Points, req.Type, rewardID, c.GetInt("orgID"),
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reward"})
@@ -695,7 +744,7 @@ func (h *TestHandler) DeleteRewardCatalog(c *gin.Context) {
 		return
 	}
 
-	result, err := h.db.Exec("DELETE FROM rewards_catalog WHERE id = $1", rewardID)
+	result, err := h.db.Exec("DELETE FROM rewards_catalog WHERE id = $1 AND organization_id = $2", rewardID, c.GetInt("orgID"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reward"})
 		return
@@ -735,7 +784,10 @@ func (h *TestHandler) ActivateTestInChatboard(c *gin.Context) {
 
 	// Check if chatboard exists
 	var chatboardExists bool
-	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM chatboards WHERE id = $1)", req.ChatboardID).Scan(&chatboardExists)
+	err := h.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM chatboards WHERE id = $1 AND organization_id = $2)",
+		req.ChatboardID, c.GetInt("orgID"),
+	).Scan(&chatboardExists)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chatboard"})
 		return
@@ -747,7 +799,10 @@ func (h *TestHandler) ActivateTestInChatboard(c *gin.Context) {
 
 	// Check if test exists
 	var testExists bool
-	err = h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM tests WHERE id = $1)", req.TestID).Scan(&testExists)
+	err = h.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM tests WHERE id = $1 AND organization_id = $2)",
+		req.TestID, c.GetInt("orgID"),
+	).Scan(&testExists)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify test"})
 		return
@@ -772,6 +827,14 @@ func (h *TestHandler) ActivateTestInChatboard(c *gin.Context) {
 		return
 	}
 
+	if err := realtime.Publish(h.db, realtime.Event{
+		Type:        realtime.EventTestActivated,
+		ChatboardID: req.ChatboardID,
+		TestID:      req.TestID,
+	}); err != nil {
+		log.Printf("Error publishing test event: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Test activated in chatboard successfully",
 		"id":      id,
@@ -801,7 +864,8 @@ func (h *TestHandler) DeactivateTestInChatboard(c *gin.Context) {
 		UPDATE chatboard_tests 
 		SET is_active = false 
 		WHERE chatboard_id = $1 AND test_id = $2
-	`, req.ChatboardID, req.TestID)
+		AND test_id IN (SELECT id FROM tests WHERE organization_id = $3)
+	`, req.ChatboardID, req.TestID, c.GetInt("orgID"))
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate test in chatboard"})
@@ -818,6 +882,14 @@ func (h *TestHandler) DeactivateTestInChatboard(c *gin.Context) {
 		return
 	}
 
+	if err := realtime.Publish(h.db, realtime.Event{
+		Type:        realtime.EventTestDeactivated,
+		ChatboardID: req.ChatboardID,
+		TestID:      req.TestID,
+	}); err != nil {
+		log.Printf("Error publishing test event: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Test deactivated in chatboard successfully",
 	})
@@ -825,15 +897,34 @@ func (h *TestHandler) DeactivateTestInChatboard(c *gin.Context) {
 
 // GetChatboardTests gets all tests for a specific chatboard
 func (h *TestHandler) GetChatboardTests(c *gin.Context) {
-	chatboardID := c.Param("chatboard_id")
+	chatboardID, err := strconv.Atoi(c.Param("chatboard_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chatboard ID"})
+		return
+	}
+
+	// Test managers see every board's tests, members only those of their boards
+	userRole := c.GetString("userRole")
+	hasAccess := userRole == "Admin" || userRole == "Head Unicorn"
+	if !hasAccess {
+		hasAccess, err = canAccessChatboard(h.db, c.GetInt("userID"), c.GetInt("orgID"), chatboardID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chatboard"})
+		return
+	}
 
 	rows, err := h.db.Query(`
 		SELECT t.id, t.title, t.reward_details, t.created_at, ct.is_active
 		FROM tests t
 		JOIN chatboard_tests ct ON t.id = ct.test_id
-		WHERE ct.chatboard_id = $1
+		WHERE ct.chatboard_id = $1 AND t.organization_id = $2
 		ORDER BY t.created_at DESC
-	`, chatboardID)
+	`, chatboardID, c.GetInt("orgID"))
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chatboard tests"})
@@ -22,7 +22,7 @@ func NewUserHandler(db *sql.DB) *UserHandler {
 func (h *UserHandler) GetUserInfo(c *gin.Context) {
 	userID := c.GetInt("userID")
 
-	profile, err := h.getUserProfile(userID)
+	profile, err := h.getUserProfile(userID, c.GetInt("orgID"))
 	if err != nil {
 		log.Printf("Error getting user profile: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user info"})
@@ -43,19 +43,19 @@ func (h *UserHandler) GetUserInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, profile)
 }
 
-// getUserProfile fetches all related information for a user
-func (h *UserHandler) getUserProfile(userID int) (models.UserProfile, error) {
+// getUserProfile fetches all related information for a user within the organization
+func (h *UserHandler) getUserProfile(userID, orgID int) (models.UserProfile, error) {
 	profile := models.UserProfile{
 		Roles:     make([]string, 0),
 		Squads:    make([]models.UserSquad, 0),
 		Countries: make([]string, 0),
 	}
 
-	// Get username
-	var username sql.NullString
+	// Get username and picture
+	var username, avatarPreset, avatarImageKey sql.NullString
 	err := h.db.QueryRow(`
-		SELECT username FROM users WHERE id = $1
-	`, userID).Scan(&username)
+		SELECT username, avatar_preset, avatar_image_key FROM users WHERE id = $1
+	`, userID).Scan(&username, &avatarPreset, &avatarImageKey)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return profile, nil
@@ -65,14 +65,15 @@ func (h *UserHandler) getUserProfile(userID int) (models.UserProfile, error) {
 	if username.Valid {
 		profile.Username = username.String
 	}
+	profile.AvatarURL = avatarURL(userID, avatarPreset, avatarImageKey)
 
 	// Get global roles
 	roleRows, err := h.db.Query(`
 		SELECT DISTINCT r.role 
 		FROM user_roles ur
 		JOIN roles r ON r.id = ur.role_id
-		WHERE ur.user_id = $1
-	`, userID)
+		WHERE ur.user_id = $1 AND ur.organization_id = $2
+	`, userID, orgID)
 	if err != nil {
 		return profile, err
 	}
@@ -104,9 +105,9 @@ func (h *UserHandler) getUserProfile(userID int) (models.UserProfile, error) {
 		FROM user_squads us
 		JOIN squads s ON s.id = us.squad_id
 		LEFT JOIN squad_roles sr ON sr.squad_id = s.id AND sr.user_id = us.user_id
-		WHERE us.user_id = $1
+		WHERE us.user_id = $1 AND s.organization_id = $2
 		ORDER BY s.name
-	`, userID)
+	`, userID, orgID)
 	if err != nil {
 		return profile, err
 	}
@@ -127,8 +128,8 @@ func (h *UserHandler) getUserProfile(userID int) (models.UserProfile, error) {
 		SELECT c.name 
 		FROM user_countries uc
 		JOIN countries c ON c.id = uc.country_id
-		WHERE uc.user_id = $1
-	`, userID)
+		WHERE uc.user_id = $1 AND c.organization_id = $2
+	`, userID, orgID)
 	if err != nil {
 		return profile, err
 	}
@@ -156,9 +157,9 @@ func (h *UserHandler) GetSquads(c *gin.Context) {
 			us.status
 		FROM squads s
 		JOIN user_squads us ON s.id = us.squad_id
-		WHERE us.user_id = $1
+		WHERE us.user_id = $1 AND s.organization_id = $2
 		ORDER BY s.name`,
-		userID)
+		userID, c.GetInt("orgID"))
 	if err != nil {
 		log.Printf("Error fetching squads: %v", err)
 		c.JSON(http.StatusOK, []gin.H{}) // Return empty array instead of error
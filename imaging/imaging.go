@@ -0,0 +1,334 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// maxPixels bounds the images we decode, so a small file claiming huge
+// dimensions cannot exhaust memory
+const maxPixels = 50_000_000
+
+// Animated GIFs are decoded frame by frame, so their frames are bounded too:
+// by count, and by the pixels of all frames together
+const (
+	maxGIFFrames = 500
+	maxGIFPixels = 100_000_000
+)
+
+const (
+	originalJPEGQuality = 90
+	variantJPEGQuality  = 82
+)
+
+// ErrTooLarge is returned for images with more than maxPixels pixels, and
+// for GIFs over the frame limits
+var ErrTooLarge = errors.New("imaging: image dimensions too large")
+
+// Sizes are the variants made of every image, by the longest side they fit
+// in. Images are never scaled up.
+var Sizes = []struct {
+	Name    string
+	MaxSide int
+}{
+	{"thumbnail", 320},
+	{"medium", 1280},
+}
+
+// Variant is a resized copy of an image
+type Variant struct {
+	Name        string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Result is an uploaded image with its metadata removed, and its variants
+type Result struct {
+	// ContentType and Data are the cleaned original. Orientation from EXIF
+	// is applied to the pixels before the metadata is dropped.
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+	BlurHash    string
+	Variants    []Variant
+}
+
+// Process strips the metadata from an image of the given sniffed content
+// type and makes its variants
+func Process(data []byte, contentType string) (*Result, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imaging: reading image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	result := &Result{ContentType: contentType}
+	var img image.Image
+
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("imaging: decoding JPEG: %w", err)
+		}
+		img = applyOrientation(img, jpegOrientation(data))
+		// The encoder writes no metadata, so re-encoding drops EXIF, XMP
+		// and everything else
+		result.Data, err = encodeJPEG(img, originalJPEGQuality)
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("imaging: decoding PNG: %w", err)
+		}
+		result.Data, err = encodePNG(img)
+	case "image/gif":
+		// Re-encoding keeps the animation and drops comments and
+		// application extensions
+		if err = checkGIFFrames(data); err != nil {
+			return nil, err
+		}
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("imaging: decoding GIF: %w", err)
+		}
+		var frame image.Image
+		if len(g.Image) > 0 {
+			frame = g.Image[0]
+		}
+		img = firstFrame(frame, g.Config.Width, g.Config.Height)
+		var buf bytes.Buffer
+		err = gif.EncodeAll(&buf, g)
+		result.Data = buf.Bytes()
+	case "image/webp":
+		img, err = webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("imaging: decoding WebP: %w", err)
+		}
+		// There is no WebP encoder, so the metadata chunks are cut out of
+		// the container instead
+		result.Data, err = stripWebPMetadata(data)
+	default:
+		return nil, fmt.Errorf("imaging: unsupported content type %q", contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	result.Width, result.Height = bounds.Dx(), bounds.Dy()
+
+	for _, size := range Sizes {
+		variant, err := resize(img, size.MaxSide)
+		if err != nil {
+			return nil, err
+		}
+		variant.Name = size.Name
+		result.Variants = append(result.Variants, variant)
+	}
+
+	// The smallest variant is plenty for a blurred placeholder
+	small, _, err := image.Decode(bytes.NewReader(result.Variants[0].Data))
+	if err != nil {
+		return nil, err
+	}
+	result.BlurHash = blurHash(small, 4, 3)
+
+	return result, nil
+}
+
+// avatarSize is the width and height of profile pictures
+const avatarSize = 256
+
+// Avatar turns an uploaded image into a square profile picture, cropped to
+// its center, without metadata. Animated GIFs keep their first frame.
+func Avatar(data []byte, contentType string) (Variant, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Variant{}, fmt.Errorf("imaging: reading image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return Variant{}, ErrTooLarge
+	}
+
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = applyOrientation(img, jpegOrientation(data))
+		}
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		// Only the first frame is decoded
+		var frame image.Image
+		frame, err = gif.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = firstFrame(frame, cfg.Width, cfg.Height)
+		}
+	case "image/webp":
+		img, err = webp.Decode(bytes.NewReader(data))
+	default:
+		return Variant{}, fmt.Errorf("imaging: unsupported content type %q", contentType)
+	}
+	if err != nil {
+		return Variant{}, fmt.Errorf("imaging: decoding image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	square := image.Rect(0, 0, side, side).Add(image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	})
+
+	dst := image.NewNRGBA(image.Rect(0, 0, avatarSize, avatarSize))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, square, draw.Src, nil)
+
+	return encodeVariant(dst)
+}
+
+// resize scales the image to fit in a maxSide square
+func resize(img image.Image, maxSide int) (Variant, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			height = max(1, height*maxSide/width)
+			width = maxSide
+		} else {
+			width = max(1, width*maxSide/height)
+			height = maxSide
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return encodeVariant(dst)
+}
+
+// encodeVariant encodes a scaled image as JPEG, or PNG when it has
+// transparency
+func encodeVariant(img *image.NRGBA) (Variant, error) {
+	variant := Variant{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	var err error
+	if img.Opaque() {
+		variant.ContentType = "image/jpeg"
+		variant.Data, err = encodeJPEG(img, variantJPEGQuality)
+	} else {
+		variant.ContentType = "image/png"
+		variant.Data, err = encodePNG(img)
+	}
+	return variant, err
+}
+
+// firstFrame renders the first frame of a GIF, if any, on a canvas of the
+// GIF's size
+func firstFrame(frame image.Image, width, height int) image.Image {
+	canvas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if frame != nil {
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	}
+	return canvas
+}
+
+// checkGIFFrames walks the blocks of a GIF without decoding any pixels and
+// returns ErrTooLarge when it has more than maxGIFFrames frames or more than
+// maxGIFPixels pixels in all frames together.
+func checkGIFFrames(data []byte) error {
+	errMalformed := errors.New("imaging: reading GIF: malformed block structure")
+
+	// Header and logical screen descriptor
+	if len(data) < 13 {
+		return errMalformed
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks moves past a sequence of data sub-blocks
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	frames := 0
+	var pixels int64
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension: label, then sub-blocks
+			pos += 2
+			if !skipSubBlocks() {
+				return errMalformed
+			}
+		case 0x2C: // Image descriptor
+			if pos+10 > len(data) {
+				return errMalformed
+			}
+			width := int64(data[pos+5]) | int64(data[pos+6])<<8
+			height := int64(data[pos+7]) | int64(data[pos+8])<<8
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+
+			frames++
+			pixels += width * height
+			if frames > maxGIFFrames || pixels > maxGIFPixels {
+				return ErrTooLarge
+			}
+
+			// LZW minimum code size, then the image data sub-blocks
+			pos++
+			if !skipSubBlocks() {
+				return errMalformed
+			}
+		case 0x3B: // Trailer
+			return nil
+		default:
+			return errMalformed
+		}
+	}
+	return nil
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
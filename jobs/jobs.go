@@ -0,0 +1,43 @@
+// Package jobs runs periodic background work inside the server process.
+// Every instance runs every job, so jobs must be safe to run concurrently:
+// they claim their rows with a single UPDATE so each row is handled once.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is work repeated every Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs each job in its own goroutine, first right away and then every
+// interval, until ctx is cancelled. Failures are logged and retried on the
+// next tick.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, job)
+	}
+}
+
+func run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil {
+			log.Printf("Error running job %s: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+	"unicorn_app_backend/realtime"
+)
+
+// pinExpiryInterval is how often expired pins are swept, and so how late a
+// pin may come off after its expiry
+const pinExpiryInterval = time.Minute
+
+// ExpirePins unpins posts whose pin has expired
+func ExpirePins(db *sql.DB) Job {
+	return Job{
+		Name:     "expire-pins",
+		Interval: pinExpiryInterval,
+		Run: func(ctx context.Context) error {
+			rows, err := db.QueryContext(ctx, `
+                UPDATE posts
+                SET pinned = FALSE, pin_order = 0, pinned_at = NULL, pinned_by = NULL, pin_expires_at = NULL
+                WHERE pinned AND pin_expires_at <= NOW()
+                RETURNING id, chatboard_id
+            `)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			unpinned := false
+			var events []realtime.Event
+			for rows.Next() {
+				event := realtime.Event{Type: realtime.EventPostPinned, Pinned: &unpinned}
+				if err := rows.Scan(&event.PostID, &event.ChatboardID); err != nil {
+					return err
+				}
+				events = append(events, event)
+			}
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				if err := realtime.Publish(db, event); err != nil {
+					log.Printf("Error publishing pin event: %v", err)
+				}
+			}
+			return nil
+		},
+	}
+}
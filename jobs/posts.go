@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"time"
+)
+
+// scheduledPostInterval is how often due posts are published, and so how
+// late a scheduled post may appear after its publish_at
+const scheduledPostInterval = time.Minute
+
+// PublishScheduledPosts runs publish, which publishes the scheduled posts
+// that are due. Publishing goes through the same checks as posting, so the
+// caller passes it in rather than this package depending on the handlers.
+func PublishScheduledPosts(publish func(ctx context.Context) error) Job {
+	return Job{
+		Name:     "publish-scheduled-posts",
+		Interval: scheduledPostInterval,
+		Run:      publish,
+	}
+}
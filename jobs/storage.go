@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+	"unicorn_app_backend/storage"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// storageSweepInterval is how often queued objects are deleted from
+	// storage
+	storageSweepInterval = 5 * time.Minute
+	// storageSweepBatch is how many objects one transaction deletes
+	storageSweepBatch = 100
+	// removedPostRetention is how long the attachments of removed posts are
+	// kept so the posts can still be restored with them
+	removedPostRetention = "30 days"
+)
+
+// SweepStorage deletes the attachments of posts removed longer than the
+// retention period ago, and removes the stored objects of deleted
+// attachments from storage. Objects that fail to delete stay queued and are
+// retried on the next run.
+func SweepStorage(db *sql.DB, store storage.Store) Job {
+	return Job{
+		Name:     "sweep-storage",
+		Interval: storageSweepInterval,
+		Run: func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, `
+                DELETE FROM attachments a
+                USING posts p
+                WHERE p.id = a.post_id AND p.deleted_at < NOW() - $1::INTERVAL
+            `, removedPostRetention)
+			if err != nil {
+				return err
+			}
+
+			for {
+				deleted, err := deleteQueuedObjects(ctx, db, store)
+				if err != nil {
+					return err
+				}
+				if deleted < storageSweepBatch {
+					return nil
+				}
+			}
+		},
+	}
+}
+
+// deleteQueuedObjects deletes one batch of queued objects from storage and
+// returns how many it claimed. Rows are locked while their objects are
+// deleted so other instances skip them.
+func deleteQueuedObjects(ctx context.Context, db *sql.DB, store storage.Store) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT storage_key FROM storage_deletions
+        ORDER BY queued_at
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    `, storageSweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	removed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting stored object %s: %v", key, err)
+			continue
+		}
+		removed = append(removed, key)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM storage_deletions WHERE storage_key = ANY($1)", pq.Array(removed)); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	// A batch with failures is not retried right away
+	if len(removed) < len(keys) {
+		return 0, nil
+	}
+	return len(keys), nil
+}
@@ -11,7 +11,11 @@ import (
 	"syscall"
 	"time"
 	"unicorn_app_backend/db"
+	"unicorn_app_backend/handlers"
+	"unicorn_app_backend/jobs"
+	"unicorn_app_backend/realtime"
 	"unicorn_app_backend/routes"
+	"unicorn_app_backend/storage"
 
 	_ "github.com/lib/pq"
 
@@ -64,6 +68,16 @@ func main() {
 		log.Fatal("JWT_SECRET environment variable is required")
 	}
 
+	// Anonymous poll votes are keyed with their own secret, so that the
+	// token secret can't tie them back to their voters
+	pollVoterSecret := os.Getenv("POLL_VOTER_SECRET")
+	if pollVoterSecret == "" {
+		log.Fatal("POLL_VOTER_SECRET environment variable is required")
+	}
+	if pollVoterSecret == string(jwtSecret) {
+		log.Fatal("POLL_VOTER_SECRET must differ from JWT_SECRET")
+	}
+
 	// Connect to database
 	database, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -81,6 +95,31 @@ func main() {
 		log.Fatalf("Error initializing database schema: %v", err)
 	}
 
+	// Listen for chatboard events published by any instance
+	broker, err := realtime.NewBroker(dbURL)
+	if err != nil {
+		log.Fatalf("Error starting realtime broker: %v", err)
+	}
+	defer broker.Close()
+
+	// Storage for uploaded files, local disk unless STORAGE_BACKEND=s3
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Error initializing storage: %v", err)
+	}
+
+	// Background work such as expiring pins runs until shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	publishScheduledPosts := func(ctx context.Context) error {
+		return handlers.PublishScheduledPosts(ctx, database)
+	}
+	jobs.Start(jobsCtx,
+		jobs.ExpirePins(database),
+		jobs.PublishScheduledPosts(publishScheduledPosts),
+		jobs.SweepStorage(database, store),
+	)
+
 	// Seed initial data
 	//if err := db.SeedData(database); err != nil {
 	//	log.Printf("Warning: Error seeding initial data: %v", err)
@@ -97,6 +136,7 @@ func main() {
 		"Content-Length",
 		"Content-Type",
 		"Authorization",
+		"X-Organization-ID",
 	}
 	config.AllowMethods = []string{
 		"GET",
@@ -108,7 +148,7 @@ func main() {
 	r.Use(cors.New(config))
 
 	// Setup routes
-	routes.SetupRoutes(r, database, jwtSecret)
+	routes.SetupRoutes(r, database, jwtSecret, broker, store)
 
 	// Run server
 	srv := &http.Server{
@@ -127,6 +167,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -4,9 +4,11 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 	"unicorn_app_backend/models"
@@ -59,16 +61,34 @@ func AuthMiddleware(db *sql.DB, jwtSecret []byte) gin.HandlerFunc {
 			return
 		}
 
-		// Get user's role
+		orgID, isSuperAdmin, err := resolveOrganization(db, claims.UserID, c.GetHeader("X-Organization-ID"))
+		if err == errNotOrganizationMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			c.Abort()
+			return
+		} else if err != nil {
+			log.Printf("Error resolving organization: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve organization"})
+			c.Abort()
+			return
+		}
+
+		// Get user's role within the organization
 		var userRole string
-		err = db.QueryRow(`
-			SELECT r.role 
-			FROM user_roles ur
-			JOIN roles r ON r.id = ur.role_id
-			WHERE ur.user_id = $1
-			AND r.role IN ('Admin', 'Head Unicorn')
-			LIMIT 1
-		`, claims.UserID).Scan(&userRole)
+		if isSuperAdmin {
+			userRole = "Admin"
+		} else {
+			err = db.QueryRow(`
+				SELECT r.role 
+				FROM user_roles ur
+				JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = $1
+				AND ur.organization_id = $2
+				AND r.role IN ('Admin', 'Head Unicorn')
+				ORDER BY r.role = 'Admin' DESC
+				LIMIT 1
+			`, claims.UserID, orgID).Scan(&userRole)
+		}
 
 		if err != nil && err != sql.ErrNoRows {
 			log.Printf("Error getting user role: %v", err)
@@ -77,8 +97,10 @@ func AuthMiddleware(db *sql.DB, jwtSecret []byte) gin.HandlerFunc {
 			return
 		}
 
-		// Set both userID and role in context
+		// Set userID, organization and role in context
 		c.Set("userID", claims.UserID)
+		c.Set("orgID", orgID)
+		c.Set("isSuperAdmin", isSuperAdmin)
 		c.Set("userRole", userRole)
 		c.Set("token", tokenString)
 
@@ -87,6 +109,52 @@ func AuthMiddleware(db *sql.DB, jwtSecret []byte) gin.HandlerFunc {
 	}
 }
 
+var errNotOrganizationMember = errors.New("not a member of the organization")
+
+// resolveOrganization picks the organization a request acts in. The
+// X-Organization-ID header selects one explicitly; without it the user's
+// first membership is used. Super admins may act in any organization.
+func resolveOrganization(db *sql.DB, userID int, header string) (int, bool, error) {
+	var isSuperAdmin bool
+	if err := db.QueryRow("SELECT is_super_admin FROM users WHERE id = $1", userID).Scan(&isSuperAdmin); err != nil {
+		return 0, false, err
+	}
+
+	var orgID int
+	if header != "" {
+		requested, err := strconv.Atoi(header)
+		if err != nil {
+			return 0, isSuperAdmin, errNotOrganizationMember
+		}
+		err = db.QueryRow(`
+			SELECT o.id FROM organizations o
+			WHERE o.id = $1
+			AND ($3 OR EXISTS (
+				SELECT 1 FROM organization_members om
+				WHERE om.organization_id = o.id AND om.user_id = $2
+			))
+		`, requested, userID, isSuperAdmin).Scan(&orgID)
+		if err == sql.ErrNoRows {
+			return 0, isSuperAdmin, errNotOrganizationMember
+		}
+		return orgID, isSuperAdmin, err
+	}
+
+	err := db.QueryRow(`
+		SELECT organization_id FROM organization_members
+		WHERE user_id = $1
+		ORDER BY created_at, organization_id
+		LIMIT 1
+	`, userID).Scan(&orgID)
+	if err == sql.ErrNoRows && isSuperAdmin {
+		err = db.QueryRow("SELECT id FROM organizations WHERE slug = 'default'").Scan(&orgID)
+	}
+	if err == sql.ErrNoRows {
+		return 0, isSuperAdmin, errNotOrganizationMember
+	}
+	return orgID, isSuperAdmin, err
+}
+
 // TokenService handles token generation and validation
 type TokenService struct {
 	DB        *sql.DB
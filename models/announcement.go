@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type CreateAnnouncementRequest struct {
+	Title        string `json:"title" binding:"required"`
+	Content      string `json:"content" binding:"required"`
+	ChatboardIDs []int  `json:"chatboard_ids"`
+	SquadIDs     []int  `json:"squad_ids"`
+}
+
+type Announcement struct {
+	ID           int       `json:"id"`
+	AuthorID     *int      `json:"author_id"`
+	Author       string    `json:"author"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	ChatboardIDs []int     `json:"chatboard_ids"`
+	SquadIDs     []int     `json:"squad_ids"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// For recipients
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+
+	// For the author and leaders
+	RecipientCount    *int `json:"recipient_count,omitempty"`
+	AcknowledgedCount *int `json:"acknowledged_count,omitempty"`
+}
+
+type AnnouncementRecipient struct {
+	UserID         int        `json:"user_id"`
+	Username       string     `json:"username"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	RemindedAt     *time.Time `json:"reminded_at,omitempty"`
+}
+
+// AcknowledgementReport is who has and hasn't acknowledged an announcement
+type AcknowledgementReport struct {
+	AnnouncementID    int                     `json:"announcement_id"`
+	RecipientCount    int                     `json:"recipient_count"`
+	AcknowledgedCount int                     `json:"acknowledged_count"`
+	Acknowledged      []AnnouncementRecipient `json:"acknowledged"`
+	Pending           []AnnouncementRecipient `json:"pending"`
+}
+
+type RemindAnnouncementRequest struct {
+	UserIDs []int `json:"user_ids"` // Every pending recipient when empty
+}
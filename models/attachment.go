@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// AttachmentResponse describes a file attached to a post. URL is the API
+// path the file is downloaded from. Images also have their dimensions, a
+// blurhash placeholder and resized variants.
+type AttachmentResponse struct {
+	ID          int                          `json:"id"`
+	PostID      int                          `json:"post_id"`
+	Filename    string                       `json:"filename"`
+	ContentType string                       `json:"content_type"`
+	Size        int64                        `json:"size"`
+	URL         string                       `json:"url"`
+	Width       *int                         `json:"width,omitempty"`
+	Height      *int                         `json:"height,omitempty"`
+	BlurHash    *string                      `json:"blurhash,omitempty"`
+	Variants    map[string]AttachmentVariant `json:"variants,omitempty"`
+	CreatedAt   time.Time                    `json:"created_at"`
+}
+
+// AttachmentVariant is a resized copy of an image attachment
+type AttachmentVariant struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
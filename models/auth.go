@@ -38,6 +38,7 @@ type LoginResponse struct {
 
 type UserProfile struct {
 	Username  string      `json:"username"`
+	AvatarURL *string     `json:"avatar_url"`
 	Roles     []string    `json:"roles"`
 	Squads    []UserSquad `json:"squads"`
 	Countries []string    `json:"countries"`
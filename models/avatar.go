@@ -15,7 +15,7 @@ type SquadRequest struct {
 type SquadRole struct {
 	SquadID int    `json:"squad_id" binding:"required"`
 	RoleID  int    `json:"role_id" binding:"required"`
-	Status  string `json:"status" binding:"required"`
+	Status  string `json:"status"` // Ignored: new memberships always start as Pending
 }
 
 type AvatarResponse struct {
@@ -31,13 +31,27 @@ type UserSquad struct {
 
 type UserAvatarResponse struct {
 	Username  string      `json:"username"`
+	AvatarURL *string     `json:"avatar_url"`
 	Roles     []string    `json:"roles"`
 	Squads    []UserSquad `json:"squads"`
 	Countries []string    `json:"countries"`
 }
 
+// SetAvatarImageRequest picks a built-in unicorn avatar
+type SetAvatarImageRequest struct {
+	Preset string `json:"preset" binding:"required"`
+}
+
+// AvatarPreset is a built-in unicorn avatar
+type AvatarPreset struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
 type VerificationRequest struct {
 	UserID  int    `json:"user_id" binding:"required"`
 	SquadID int    `json:"squad_id" binding:"required"`
-	Status  string `json:"status" binding:"required,oneof=Pending Approved Rejected"`
+	Status  string `json:"status" binding:"required,oneof=Pending Approved Rejected Removed"`
+	Reason  string `json:"reason"`
 }
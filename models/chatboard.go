@@ -4,6 +4,7 @@ type CreateChatboardRequest struct {
 	Title       string          `json:"title" binding:"required"`
 	Description string          `json:"description" binding:"required"`
 	Access      ChatboardAccess `json:"access" binding:"required"`
+	Policy      *AccessRule     `json:"policy"` // Overrides the default "any of access" rule
 }
 
 type ChatboardAccess struct {
@@ -12,12 +13,45 @@ type ChatboardAccess struct {
 	CountryIDs []int `json:"country_ids,omitempty"` // Countries that can access
 }
 
+// AccessRule is a node of a chatboard access policy. Every condition set on a
+// node must hold: All and Any combine child rules with AND and OR, Not negates
+// a rule, and the ID lists match users belonging to any of the listed squads,
+// roles or countries. Squads only match Approved memberships unless
+// SquadStatus asks for another status; without SquadIDs it matches a
+// membership in any squad.
+//
+// Example, Helper Unicorns in Estonia:
+//
+//	{"all": [{"role_ids": [3]}, {"country_ids": [1]}]}
+type AccessRule struct {
+	All         []AccessRule `json:"all,omitempty"`
+	Any         []AccessRule `json:"any,omitempty"`
+	Not         *AccessRule  `json:"not,omitempty"`
+	SquadIDs    []int        `json:"squad_ids,omitempty"`
+	SquadStatus string       `json:"squad_status,omitempty"`
+	RoleIDs     []int        `json:"role_ids,omitempty"`
+	CountryIDs  []int        `json:"country_ids,omitempty"`
+}
+
+type UpdateChatboardRequest struct {
+	Title       *string          `json:"title"`
+	Description *string          `json:"description"`
+	Access      *ChatboardAccess `json:"access"` // Replaces all access lists when given
+	Policy      *AccessRule      `json:"policy"`
+	ClearPolicy bool             `json:"clear_policy"` // Falls back to the default rule
+}
+
 type ChatboardResponse struct {
 	ID          int                 `json:"id"`
 	Title       string              `json:"title"`
 	Description string              `json:"description"`
 	CreatedAt   string              `json:"created_at"`
+	CreatorID   *int                `json:"creator_id,omitempty"`
+	Archived    bool                `json:"archived"`
+	UnreadCount int                 `json:"unread_count"` // Posts by others since the user's read marker
+	SlowMode    int                 `json:"slow_mode_seconds"`
 	Access      ChatboardAccessInfo `json:"access"`
+	Policy      *AccessRule         `json:"policy,omitempty"`
 }
 
 type ChatboardAccessInfo struct {
@@ -27,12 +61,18 @@ type ChatboardAccessInfo struct {
 }
 
 type PendingUserResponse struct {
-	UserID    int    `json:"user_id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-	SquadID   int    `json:"squad_id"`
-	SquadName string `json:"squad_name"`
-	Status    string `json:"status"`
-	Role      string `json:"role"`
+	UserID      int                `json:"user_id"`
+	FirstName   string             `json:"first_name"`
+	LastName    string             `json:"last_name"`
+	Email       string             `json:"email"`
+	SquadID     int                `json:"squad_id"`
+	SquadName   string             `json:"squad_name"`
+	Status      string             `json:"status"`
+	Role        string             `json:"role"`
+	WouldUnlock []ChatboardSummary `json:"would_unlock"` // Chatboards approving the request would open
+}
+
+type ChatboardSummary struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
 }
@@ -3,16 +3,38 @@ package models
 import "time"
 
 type CreateCommentRequest struct {
-	PostID  int    `json:"post_id" binding:"required"`
+	PostID          int    `json:"post_id" binding:"required"`
+	ParentCommentID *int   `json:"parent_comment_id"` // Set to reply to a comment
+	Comment         string `json:"comment" binding:"required"`
+}
+
+type UpdateCommentRequest struct {
 	Comment string `json:"comment" binding:"required"`
 }
 
 type CommentResponse struct {
+	ID              int               `json:"id"`
+	PostID          int               `json:"post_id"`
+	ParentCommentID *int              `json:"parent_comment_id"`
+	Depth           int               `json:"depth"` // 0 for comments on the post itself
+	UserID          int               `json:"user_id"`
+	Author          string            `json:"author"`
+	AuthorAvatarURL *string           `json:"author_avatar_url"`
+	Comment         string            `json:"comment"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UserRole        string            `json:"user_role"` // Role of the commenter in the chatboard
+	ReplyCount      int               `json:"reply_count"`
+	Edited          bool              `json:"edited"`
+	EditedAt        *time.Time        `json:"edited_at,omitempty"`
+	Removed         bool              `json:"removed"`
+	Held            bool              `json:"held"` // Waiting for a moderator's approval
+	Reactions       []ReactionSummary `json:"reactions"`
+	Mentions        []Mention         `json:"mentions"`
+}
+
+type CommentRevisionResponse struct {
 	ID        int       `json:"id"`
-	PostID    int       `json:"post_id"`
-	UserID    int       `json:"user_id"`
-	Author    string    `json:"author"`
 	Comment   string    `json:"comment"`
-	CreatedAt time.Time `json:"created_at"`
-	UserRole  string    `json:"user_role"` // Role of the commenter in the chatboard
+	EditedBy  *int      `json:"edited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"` // When this version was replaced
 }
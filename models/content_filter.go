@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+type ContentFilterRule struct {
+	ID        int       `json:"id"`
+	Locale    string    `json:"locale"` // Empty for every locale
+	Kind      string    `json:"kind"`   // "word" or "regex"
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"` // "block" or "flag"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateContentFilterRuleRequest struct {
+	Locale  string `json:"locale"`
+	Kind    string `json:"kind" binding:"required"`
+	Pattern string `json:"pattern" binding:"required"`
+	Action  string `json:"action" binding:"required"`
+}
+
+type AllowedDomain struct {
+	ID        int       `json:"id"`
+	Domain    string    `json:"domain"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddAllowedDomainRequest struct {
+	Domain string `json:"domain" binding:"required"`
+}
+
+// FilterMatch is why the content filter held a post or comment
+type FilterMatch struct {
+	Code  string `json:"code"`
+	Match string `json:"match"`
+}
+
+// HeldContent is a post or comment waiting for a moderator's approval
+type HeldContent struct {
+	Type        string        `json:"type"` // "post" or "comment"
+	ID          int           `json:"id"`
+	PostID      int           `json:"post_id"`
+	ChatboardID int           `json:"chatboard_id"`
+	UserID      int           `json:"user_id"`
+	Author      string        `json:"author"`
+	Title       string        `json:"title"`
+	Text        string        `json:"text"`
+	Reasons     []FilterMatch `json:"reasons"`
+	CreatedAt   time.Time     `json:"created_at"`
+	HeldAt      time.Time     `json:"held_at"`
+}
+
+type ReviewHeldContentRequest struct {
+	Note string `json:"note"`
+}
@@ -1,15 +1,43 @@
 package models
 
 type Country struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ISOCode  string `json:"iso_code"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
 }
 
 type CreateCountryRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	ISOCode  string `json:"iso_code" binding:"required,len=2,alpha"` // ISO 3166-1 alpha-2
+	Timezone string `json:"timezone"`                                // IANA name, defaults to UTC
+	Locale   string `json:"locale"`                                  // Defaults to en
+}
+
+type UpdateCountryRequest struct {
+	Name     *string `json:"name"`
+	ISOCode  *string `json:"iso_code" binding:"omitempty,len=2,alpha"`
+	Timezone *string `json:"timezone"`
+	Locale   *string `json:"locale"`
 }
 
 type CountryResponse struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ISOCode  string `json:"iso_code"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
+}
+
+type AssignCountryAdminRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+type CountryAdminResponse struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	CreatedAt string `json:"created_at"`
 }
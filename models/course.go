@@ -5,16 +5,19 @@ import "time"
 type Course struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
+	CountryID *int   `json:"country_id"`
 	CreatedAt string `json:"created_at"`
 }
 
 type CreateCourseRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	CountryID *int   `json:"country_id"` // Required for country admins
 }
 
 type CourseResponse struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
+	CountryID *int   `json:"country_id"`
 	CreatedAt string `json:"created_at"`
 }
 
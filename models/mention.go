@@ -0,0 +1,11 @@
+package models
+
+// Mention is an @username in a post's content or a comment's text. Start
+// and Length are in UTF-16 code units, the way JavaScript, Swift and Kotlin
+// index strings, and cover the @ too.
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	Length   int    `json:"length"`
+}
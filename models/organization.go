@@ -0,0 +1,27 @@
+package models
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+	Slug string `json:"slug" binding:"required"`
+}
+
+type OrganizationResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AddOrganizationMemberRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
+type OrganizationMemberResponse struct {
+	UserID    int      `json:"user_id"`
+	Username  string   `json:"username"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Email     string   `json:"email"`
+	Roles     []string `json:"roles"`
+	JoinedAt  string   `json:"joined_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type CreatePollRequest struct {
+	Options        []string   `json:"options" binding:"required"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous"`
+	ClosesAt       *time.Time `json:"closes_at"`
+}
+
+type VotePollRequest struct {
+	OptionIDs []int `json:"option_ids" binding:"required"`
+}
+
+// Poll is a post's poll as the caller sees it. Vote counts are left out
+// until the caller has voted or the poll has closed.
+type Poll struct {
+	ID             int          `json:"id"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	Anonymous      bool         `json:"anonymous"`
+	ClosesAt       *time.Time   `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	Voted          bool         `json:"voted"`
+	ResultsVisible bool         `json:"results_visible"`
+	VoterCount     *int         `json:"voter_count,omitempty"`
+	Options        []PollOption `json:"options"`
+	MyOptionIDs    []int        `json:"my_option_ids,omitempty"`
+}
+
+type PollOption struct {
+	ID     int         `json:"id"`
+	Text   string      `json:"text"`
+	Votes  *int        `json:"votes,omitempty"`
+	Voters []PollVoter `json:"voters,omitempty"` // Only for polls that aren't anonymous
+}
+
+type PollVoter struct {
+	UserID   int     `json:"user_id"`
+	Username *string `json:"username"`
+}
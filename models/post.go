@@ -8,26 +8,48 @@ type CreatePostRequest struct {
 	Content     string `json:"content" binding:"required"`
 }
 
-type PostResponse struct {
-	ID           int       `json:"id"`
-	ChatboardID  int       `json:"chatboard_id"`
-	UserID       int       `json:"user_id"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	CreatedAt    time.Time `json:"created_at"`
-	Author       string    `json:"author"` // username of the post creator
-	CommentCount int       `json:"comment_count"`
-	UserRole     string    `json:"user_role"` // Add this field
-	Pinned       bool      `json:"pinned"`
+type UpdatePostRequest struct {
+	Title   *string `json:"title"`
+	Content *string `json:"content"`
 }
 
-type Post struct {
+type PostRevisionResponse struct {
 	ID        int       `json:"id"`
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
-	Pinned    bool      `json:"pinned"`
-	CreatedAt time.Time `json:"created_at"`
-	Author    Author    `json:"author"`
+	EditedBy  *int      `json:"edited_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"` // When this version was replaced
+}
+
+type PostResponse struct {
+	ID              int                  `json:"id"`
+	ChatboardID     int                  `json:"chatboard_id"`
+	UserID          int                  `json:"user_id"`
+	Title           string               `json:"title"`
+	Content         string               `json:"content"`
+	CreatedAt       time.Time            `json:"created_at"`
+	Author          string               `json:"author"` // username of the post creator
+	AuthorAvatarURL *string              `json:"author_avatar_url"`
+	CommentCount    int                  `json:"comment_count"`
+	UserRole        string               `json:"user_role"` // Add this field
+	Pinned          bool                 `json:"pinned"`
+	Reactions       []ReactionSummary    `json:"reactions"`
+	Attachments     []AttachmentResponse `json:"attachments"`
+	Mentions        []Mention            `json:"mentions"`
+}
+
+type Post struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Pinned    bool       `json:"pinned"`
+	CreatedAt time.Time  `json:"created_at"`
+	Author    Author     `json:"author"`
+	Mentions  []Mention  `json:"mentions"`
+	Held      bool       `json:"held"` // Waiting for a moderator's approval
+	Poll      *Poll      `json:"poll"`
+	Status    string     `json:"status"` // draft, scheduled or published
+	PublishAt *time.Time `json:"publish_at,omitempty"`
 }
 
 type Author struct {
@@ -35,3 +57,16 @@ type Author struct {
 	LastName  string `json:"last_name"`
 	Email     string `json:"email"`
 }
+
+type PinPostRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"` // Stays pinned until unpinned when null
+	Position  *int       `json:"position"`   // 1 is the top; new pins go last
+}
+
+type SchedulePostRequest struct {
+	PublishAt *time.Time `json:"publish_at" binding:"required"`
+}
+
+type ReorderPinsRequest struct {
+	PostIDs []int `json:"post_ids" binding:"required"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type ToggleReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// ReactionSummary is the number of reactions with one emoji on a post or
+// comment, and whether the caller is one of them
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+type CreateReportRequest struct {
+	TargetType string `json:"target_type" binding:"required"` // "post", "comment" or "user"
+	TargetID   int    `json:"target_id" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+	Details    string `json:"details"`
+}
+
+// Report is one member's report, as moderators see it
+type Report struct {
+	ID         int       `json:"id"`
+	ReporterID *int      `json:"reporter_id"`
+	Reporter   string    `json:"reporter"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ReportGroup is the open reports about one post, comment or user
+type ReportGroup struct {
+	TargetType  string         `json:"target_type"`
+	PostID      *int           `json:"post_id,omitempty"`
+	CommentID   *int           `json:"comment_id,omitempty"`
+	ChatboardID *int           `json:"chatboard_id,omitempty"`
+	UserID      int            `json:"user_id"`
+	Username    string         `json:"username"`
+	Title       string         `json:"title,omitempty"`
+	Preview     string         `json:"preview,omitempty"`
+	Removed     bool           `json:"removed"`
+	ReportCount int            `json:"report_count"`
+	Reasons     map[string]int `json:"reasons"`
+	FirstReport time.Time      `json:"first_reported_at"`
+	LastReport  time.Time      `json:"last_reported_at"`
+	Reports     []Report       `json:"reports"`
+}
+
+type ModerateReportRequest struct {
+	Action      string `json:"action" binding:"required"` // "dismiss", "hide", "warn" or "suspend"
+	Note        string `json:"note"`
+	SuspendDays int    `json:"suspend_days"`
+}
+
+// ModerationAction is an entry in the moderation log
+type ModerationAction struct {
+	ID             int        `json:"id"`
+	ModeratorID    *int       `json:"moderator_id"`
+	Moderator      string     `json:"moderator"`
+	Action         string     `json:"action"`
+	TargetType     string     `json:"target_type"`
+	PostID         *int       `json:"post_id,omitempty"`
+	CommentID      *int       `json:"comment_id,omitempty"`
+	ChatboardID    *int       `json:"chatboard_id,omitempty"`
+	UserID         *int       `json:"user_id"`
+	Note           string     `json:"note"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	ReportCount    int        `json:"report_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
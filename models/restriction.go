@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ChatboardRestriction is an active ban or mute of a member on a chatboard
+type ChatboardRestriction struct {
+	ID          int        `json:"id"`
+	ChatboardID int        `json:"chatboard_id"`
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username"`
+	Kind        string     `json:"kind"` // "ban" or "mute"
+	Reason      string     `json:"reason"`
+	ExpiresAt   *time.Time `json:"expires_at"` // Null when it lasts until lifted
+	CreatedBy   *int       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreateRestrictionRequest struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Reason string `json:"reason"`
+	Hours  int    `json:"hours"` // Lasts until lifted when zero
+}
+
+type LiftRestrictionRequest struct {
+	Note string `json:"note"`
+}
+
+type SetSlowModeRequest struct {
+	Seconds *int `json:"seconds" binding:"required"` // Zero turns slow mode off
+}
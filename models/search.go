@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SearchResult is a post or comment matching a search. Highlights are HTML
+// escaped text with the matching words wrapped in <mark></mark>.
+type SearchResult struct {
+	Type           string    `json:"type"` // "post" or "comment"
+	ID             int       `json:"id"`
+	PostID         int       `json:"post_id"`
+	ChatboardID    int       `json:"chatboard_id"`
+	PostTitle      string    `json:"post_title"`
+	TitleHighlight string    `json:"title_highlight,omitempty"`
+	Snippet        string    `json:"snippet"`
+	Author         string    `json:"author"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -1,15 +1,89 @@
 package models
 
 type Squad struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	CountryID *int   `json:"country_id"`
 }
 
 type CreateSquadRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	CountryID *int   `json:"country_id"`
+}
+
+type UpdateSquadRequest struct {
+	Name         *string `json:"name"`
+	CountryID    *int    `json:"country_id"`
+	ClearCountry bool    `json:"clear_country"` // Takes the squad out of its country
 }
 
 type SquadResponse struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	CountryID   *int   `json:"country_id"`
+	CountryName string `json:"country_name,omitempty"`
+	Archived    bool   `json:"archived"`
+}
+
+type SquadDetailResponse struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name"`
+	CountryID    *int           `json:"country_id"`
+	CountryName  string         `json:"country_name,omitempty"`
+	Archived     bool           `json:"archived"`
+	ArchivedAt   string         `json:"archived_at,omitempty"`
+	MemberCount  int            `json:"member_count"`
+	MemberCounts map[string]int `json:"member_counts"` // Members per membership status
+	Leaders      []SquadMember  `json:"leaders"`
+}
+
+type SquadMember struct {
+	UserID        int      `json:"user_id"`
+	Username      string   `json:"username"`
+	FirstName     string   `json:"first_name"`
+	LastName      string   `json:"last_name"`
+	Status        string   `json:"status"`
+	Roles         []string `json:"roles"`
+	RequestedRole *string  `json:"requested_role,omitempty"` // Granted on approval
+	JoinedAt      string   `json:"joined_at"`
+}
+
+type UpdateSquadMemberRoleRequest struct {
+	RoleID int `json:"role_id" binding:"required"`
+}
+
+// Squad membership statuses. Transitions between them are enforced by the
+// squad membership workflow in the handlers package.
+const (
+	SquadStatusPending  = "Pending"
+	SquadStatusApproved = "Approved"
+	SquadStatusRejected = "Rejected"
+	SquadStatusRemoved  = "Removed"
+)
+
+type JoinSquadRequest struct {
+	RoleID int    `json:"role_id"` // Optional squad role the applicant asks for, granted on approval
+	Reason string `json:"reason"`
+}
+
+type SquadDecisionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type SquadMembershipResponse struct {
+	UserID    int    `json:"user_id"`
+	SquadID   int    `json:"squad_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	DecidedBy *int   `json:"decided_by,omitempty"`
+	DecidedAt string `json:"decided_at,omitempty"`
+}
+
+type SquadStatusHistoryEntry struct {
+	ID         int    `json:"id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason,omitempty"`
+	ChangedBy  int    `json:"changed_by"`
+	CreatedAt  string `json:"created_at"`
 }
@@ -0,0 +1,179 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres NOTIFY channel chatboard events are sent on. Every
+// instance listens on it, so an event published by one instance reaches the
+// subscribers of all of them.
+const Channel = "chatboard_events"
+
+// Event types pushed to subscribers
+const (
+	EventPostCreated      = "post.created"
+	EventPostUpdated      = "post.updated"
+	EventPostRemoved      = "post.removed"
+	EventPostRestored     = "post.restored"
+	EventCommentCreated   = "comment.created"
+	EventCommentUpdated   = "comment.updated"
+	EventCommentRemoved   = "comment.removed"
+	EventCommentRestored  = "comment.restored"
+	EventPostPinned       = "post.pinned"
+	EventPinsReordered    = "pins.reordered"
+	EventReactionsChanged = "reactions.changed"
+	EventPollVoted        = "poll.voted"
+	EventPollClosed       = "poll.closed"
+	EventTestActivated    = "test.activated"
+	EventTestDeactivated  = "test.deactivated"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before events are dropped for it
+const subscriberBuffer = 32
+
+// Event is a change on a chatboard. Events only carry IDs, clients fetch the
+// details through the regular endpoints. NOTIFY payloads are limited to 8000
+// bytes, so content must never be added here.
+type Event struct {
+	Type        string `json:"type"`
+	ChatboardID int    `json:"chatboard_id"`
+	PostID      int    `json:"post_id,omitempty"`
+	CommentID   int    `json:"comment_id,omitempty"`
+	TestID      int    `json:"test_id,omitempty"`
+	Pinned      *bool  `json:"pinned,omitempty"`
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Publish sends the event to every instance. Publishing inside a transaction
+// delivers the event only when the transaction commits.
+func Publish(db execer, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("SELECT pg_notify($1, $2)", Channel, string(payload))
+	return err
+}
+
+// Subscription receives the events of a set of chatboards
+type Subscription struct {
+	Events     <-chan Event
+	events     chan Event
+	chatboards map[int]bool
+}
+
+// Broker listens for chatboard events and fans them out to the subscriptions
+// of this instance.
+type Broker struct {
+	listener *pq.Listener
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewBroker connects a listener to the database and starts dispatching events
+func NewBroker(dsn string) (*Broker, error) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Realtime listener error: %v", err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	b := &Broker{
+		listener:      listener,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+	go b.run()
+
+	return b, nil
+}
+
+func (b *Broker) run() {
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification means the connection was re-established
+			// and events may have been missed
+			if n == nil {
+				continue
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+				log.Printf("Error decoding realtime event: %v", err)
+				continue
+			}
+			b.dispatch(event)
+		case <-time.After(90 * time.Second):
+			go b.listener.Ping()
+		}
+	}
+}
+
+func (b *Broker) dispatch(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscriptions {
+		if !sub.chatboards[event.ChatboardID] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			log.Printf("Dropping realtime event %s for a slow subscriber", event.Type)
+		}
+	}
+}
+
+// Subscribe starts delivering the events of the chatboards
+func (b *Broker) Subscribe(chatboardIDs []int) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{
+		Events:     events,
+		events:     events,
+		chatboards: make(map[int]bool, len(chatboardIDs)),
+	}
+	for _, id := range chatboardIDs {
+		sub.chatboards[id] = true
+	}
+
+	b.mu.Lock()
+	b.subscriptions[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe stops delivering events and closes the subscription's channel
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscriptions[sub]; ok {
+		delete(b.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// Close stops listening for events
+func (b *Broker) Close() error {
+	return b.listener.Close()
+}
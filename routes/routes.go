@@ -5,30 +5,44 @@ import (
 
 	"unicorn_app_backend/handlers"
 	"unicorn_app_backend/middleware"
+	"unicorn_app_backend/realtime"
+	"unicorn_app_backend/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes configures all the routes for the application
-func SetupRoutes(r *gin.Engine, db *sql.DB, jwtSecret []byte) {
+func SetupRoutes(r *gin.Engine, db *sql.DB, jwtSecret []byte, broker *realtime.Broker, store storage.Store) {
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, jwtSecret)
 	countryHandler := handlers.NewCountryHandler(db)
 	roleHandler := handlers.NewRoleHandler(db)
 	squadHandler := handlers.NewSquadHandler(db)
-	avatarHandler := handlers.NewAvatarHandler(db)
+	avatarHandler := handlers.NewAvatarHandler(db, store)
 	chatboardHandler := handlers.NewChatboardHandler(db)
 	postHandler := handlers.NewPostHandler(db)
 	commentHandler := handlers.NewCommentHandler(db)
+	reactionHandler := handlers.NewReactionHandler(db)
+	pollHandler := handlers.NewPollHandler(db)
+	attachmentHandler := handlers.NewAttachmentHandler(db, store)
+	searchHandler := handlers.NewSearchHandler(db)
+	moderationHandler := handlers.NewModerationHandler(db)
+	contentFilterHandler := handlers.NewContentFilterHandler(db)
+	restrictionHandler := handlers.NewRestrictionHandler(db)
+	announcementHandler := handlers.NewAnnouncementHandler(db)
 	courseHandler := handlers.NewCourseHandler(db)
 	lessonHandler := handlers.NewLessonHandler(db)
 	attendanceHandler := handlers.NewAttendanceHandler(db)
 	testHandler := handlers.NewTestHandler(db)
 	healthHandler := handlers.NewHealthHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(db)
+	organizationHandler := handlers.NewOrganizationHandler(db)
 	userHandler := handlers.NewUserHandler(db)
+	streamHandler := handlers.NewStreamHandler(db, broker)
 
 	// Public routes
 	r.GET("/health", healthHandler.HealthCheck)
+	r.GET("/avatars/presets/:id", avatarHandler.GetAvatarPresetImage)
 
 	// Auth routes (public)
 	r.POST("/register", authHandler.Register)
@@ -42,12 +56,28 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, jwtSecret []byte) {
 		// Auth routes that require authentication
 		protected.POST("/logout", authHandler.Logout)
 
+		// Organization routes
+		protected.POST("/organizations", organizationHandler.CreateOrganization)
+		protected.GET("/organizations", organizationHandler.GetOrganizations)
+		protected.GET("/organizations/:id/members", organizationHandler.GetOrganizationMembers)
+		protected.POST("/organizations/:id/members", organizationHandler.AddOrganizationMember)
+		protected.DELETE("/organizations/:id/members/:user_id", organizationHandler.RemoveOrganizationMember)
+
 		//Avatar route
 		protected.POST("/avatar", avatarHandler.CreateUserAvatar)
+		protected.GET("/avatars/presets", avatarHandler.GetAvatarPresets)
+		protected.PUT("/me/avatar-image", avatarHandler.SetAvatarImage)
+		protected.DELETE("/me/avatar-image", avatarHandler.DeleteAvatarImage)
+		protected.GET("/users/:id/avatar-image", avatarHandler.GetUserAvatarImage)
 
 		//Country routes
 		protected.POST("/countries", countryHandler.CreateCountry)
 		protected.GET("/countries", countryHandler.GetCountries)
+		protected.PATCH("/countries/:id", countryHandler.UpdateCountry)
+		protected.DELETE("/countries/:id", countryHandler.DeleteCountry)
+		protected.GET("/countries/:id/admins", countryHandler.GetCountryAdmins)
+		protected.POST("/countries/:id/admins", countryHandler.AssignCountryAdmin)
+		protected.DELETE("/countries/:id/admins/:user_id", countryHandler.RemoveCountryAdmin)
 
 		//Role routes
 		protected.POST("/roles", roleHandler.CreateRole)
@@ -57,21 +87,106 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, jwtSecret []byte) {
 		// Squad routes
 		protected.POST("/squads", squadHandler.CreateSquad)
 		protected.GET("/squads", squadHandler.GetSquads)
+		protected.GET("/squads/:id", squadHandler.GetSquadByID)
+		protected.PATCH("/squads/:id", squadHandler.UpdateSquad)
+		protected.DELETE("/squads/:id", squadHandler.DeleteSquad)
+		protected.POST("/squads/:id/archive", squadHandler.ArchiveSquad)
+		protected.POST("/squads/:id/unarchive", squadHandler.UnarchiveSquad)
+		protected.GET("/squads/:id/members", squadHandler.GetSquadMembers)
+		protected.POST("/squads/:id/join", squadHandler.JoinSquad)
+		protected.POST("/squads/:id/members/:user_id/approve", squadHandler.ApproveSquadMember)
+		protected.POST("/squads/:id/members/:user_id/reject", squadHandler.RejectSquadMember)
+		protected.DELETE("/squads/:id/members/:user_id", squadHandler.RemoveSquadMember)
+		protected.GET("/squads/:id/members/:user_id/history", squadHandler.GetSquadMemberHistory)
+		protected.PUT("/squads/:id/members/:user_id/role", squadHandler.UpdateSquadMemberRole)
 
 		// Chatboard routes
 		protected.POST("/chatboards", chatboardHandler.CreateChatboard)
 		protected.GET("/chatboards", chatboardHandler.GetChatboards)
 		protected.GET("/chatboards/:id", chatboardHandler.GetChatboardByID)
+		protected.PATCH("/chatboards/:id", chatboardHandler.UpdateChatboard)
+		protected.DELETE("/chatboards/:id", chatboardHandler.DeleteChatboard)
+		protected.POST("/chatboards/:id/archive", chatboardHandler.ArchiveChatboard)
+		protected.POST("/chatboards/:id/unarchive", chatboardHandler.UnarchiveChatboard)
 		protected.GET("/chatboards/:id/pending-users", chatboardHandler.GetPendingUsers)
+		protected.POST("/chatboards/:id/read", chatboardHandler.MarkChatboardRead)
+		protected.GET("/chatboards/:id/restrictions", restrictionHandler.GetRestrictions)
+		protected.POST("/chatboards/:id/bans", restrictionHandler.BanUser)
+		protected.DELETE("/chatboards/:id/bans/:user_id", restrictionHandler.UnbanUser)
+		protected.POST("/chatboards/:id/mutes", restrictionHandler.MuteUser)
+		protected.DELETE("/chatboards/:id/mutes/:user_id", restrictionHandler.UnmuteUser)
+		protected.PUT("/chatboards/:id/slow-mode", restrictionHandler.SetSlowMode)
+		protected.PUT("/chatboards/:id/pins", postHandler.ReorderPins)
+
+		// Real-time chatboard events (Server-Sent Events)
+		protected.GET("/stream", streamHandler.StreamChatboards)
 
 		// Post routes
 		protected.POST("/posts", postHandler.CreatePost)
 		protected.GET("/posts", postHandler.GetPosts)
+		protected.GET("/posts/drafts", postHandler.GetDrafts)
+		protected.POST("/posts/:id/publish", postHandler.PublishPost)
+		protected.PUT("/posts/:id/schedule", postHandler.SchedulePost)
+		protected.DELETE("/posts/:id/schedule", postHandler.UnschedulePost)
 		protected.POST("/posts/:id/toggle-pin", postHandler.TogglePin)
+		protected.POST("/posts/:id/pin", postHandler.PinPost)
+		protected.POST("/posts/:id/unpin", postHandler.UnpinPost)
+		protected.POST("/posts/:id/read", postHandler.MarkPostRead)
+		protected.PATCH("/posts/:id", postHandler.UpdatePost)
+		protected.DELETE("/posts/:id", postHandler.DeletePost)
+		protected.POST("/posts/:id/restore", postHandler.RestorePost)
+		protected.GET("/posts/:id/revisions", postHandler.GetPostRevisions)
+		protected.POST("/posts/:id/reactions", reactionHandler.TogglePostReaction)
+		protected.POST("/posts/:id/vote", pollHandler.Vote)
+		protected.POST("/posts/:id/poll/close", pollHandler.ClosePoll)
+		protected.POST("/posts/:id/attachments", attachmentHandler.UploadAttachment)
+
+		// Attachment routes
+		protected.GET("/attachments/:id", attachmentHandler.DownloadAttachment)
+		protected.DELETE("/attachments/:id", attachmentHandler.DeleteAttachment)
 
 		// Comment routes
 		protected.POST("/comments", commentHandler.CreateComment)
 		protected.GET("/comments", commentHandler.GetComments)
+		protected.PATCH("/comments/:id", commentHandler.UpdateComment)
+		protected.DELETE("/comments/:id", commentHandler.DeleteComment)
+		protected.POST("/comments/:id/restore", commentHandler.RestoreComment)
+		protected.GET("/comments/:id/revisions", commentHandler.GetCommentRevisions)
+		protected.POST("/comments/:id/reactions", reactionHandler.ToggleCommentReaction)
+
+		// Reaction routes
+		protected.GET("/reactions", reactionHandler.GetReactionEmoji)
+
+		// Announcement routes
+		protected.POST("/announcements", announcementHandler.CreateAnnouncement)
+		protected.GET("/announcements", announcementHandler.GetAnnouncements)
+		protected.GET("/announcements/sent", announcementHandler.GetSentAnnouncements)
+		protected.GET("/announcements/:id", announcementHandler.GetAnnouncement)
+		protected.POST("/announcements/:id/acknowledge", announcementHandler.AcknowledgeAnnouncement)
+		protected.GET("/announcements/:id/acknowledgements", announcementHandler.GetAcknowledgements)
+		protected.POST("/announcements/:id/remind", announcementHandler.RemindAnnouncement)
+
+		// Search routes
+		protected.GET("/search", searchHandler.Search)
+
+		// Moderation routes
+		protected.POST("/reports", moderationHandler.CreateReport)
+		protected.GET("/reports", moderationHandler.GetReports)
+		protected.POST("/reports/:id/resolve", moderationHandler.ResolveReport)
+		protected.GET("/moderation/actions", moderationHandler.GetModerationActions)
+		protected.GET("/moderation/held", moderationHandler.GetHeldContent)
+		protected.POST("/posts/:id/approve", moderationHandler.ApprovePost)
+		protected.POST("/posts/:id/reject", moderationHandler.RejectPost)
+		protected.POST("/comments/:id/approve", moderationHandler.ApproveComment)
+		protected.POST("/comments/:id/reject", moderationHandler.RejectComment)
+
+		// Content filter routes
+		protected.GET("/content-filter/rules", contentFilterHandler.GetContentFilterRules)
+		protected.POST("/content-filter/rules", contentFilterHandler.CreateContentFilterRule)
+		protected.DELETE("/content-filter/rules/:id", contentFilterHandler.DeleteContentFilterRule)
+		protected.GET("/content-filter/domains", contentFilterHandler.GetAllowedDomains)
+		protected.POST("/content-filter/domains", contentFilterHandler.AddAllowedDomain)
+		protected.DELETE("/content-filter/domains/:id", contentFilterHandler.DeleteAllowedDomain)
 
 		// Course routes
 		protected.POST("/courses", courseHandler.CreateCourse)
@@ -109,8 +224,14 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, jwtSecret []byte) {
 
 		// User info route
 		protected.GET("/userinfo", userHandler.GetUserInfo)
+		protected.GET("/me/squads", userHandler.GetSquads)
 
 		// Verification route
 		protected.POST("/verification", avatarHandler.VerifyUserSquad)
+
+		// Notification routes
+		protected.GET("/notifications", notificationHandler.GetNotifications)
+		protected.POST("/notifications/read", notificationHandler.MarkAllNotificationsRead)
+		protected.POST("/notifications/:id/read", notificationHandler.MarkNotificationRead)
 	}
 }